@@ -6,11 +6,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/fileutils"
 	"github.com/unionj-cloud/go-doudou/stringutils"
 	"go/ast"
 	"go/format"
 	"golang.org/x/tools/imports"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -33,7 +33,7 @@ func FixImport(src []byte, file string) {
 	}); err != nil {
 		panic(err)
 	}
-	_ = ioutil.WriteFile(file, res, os.ModePerm)
+	_ = fileutils.WriteFile(file, res, os.ModePerm)
 }
 
 // GetMethodMeta get method name then new MethodMeta struct from *ast.FuncDecl
@@ -309,13 +309,13 @@ func Visit(files *[]string) filepath.WalkFunc {
 // GetMod get module name from go.mod file
 func GetMod() string {
 	var (
-		f         *os.File
+		f         fileutils.File
 		err       error
 		firstLine string
 	)
 	dir, _ := os.Getwd()
 	mod := filepath.Join(dir, "go.mod")
-	if f, err = os.Open(mod); err != nil {
+	if f, err = fileutils.Open(mod); err != nil {
 		panic(err)
 	}
 	reader := bufio.NewReader(f)
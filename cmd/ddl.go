@@ -32,7 +32,7 @@ var ddlCmd = &cobra.Command{
 		if dir, err = pathutils.FixPath(dir, "domain"); err != nil {
 			logrus.Panicln(err)
 		}
-		d := ddl.Ddl{dir, reverse, dao, pre, df, conf}
+		d := ddl.Ddl{dir, reverse, dao, pre, df, conf, dryRun}
 		d.Exec()
 	},
 }
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/unionj-cloud/go-doudou/svc"
+)
+
+var docformat string
+var docoutput string
+
+// docCmd renders static api documentation from openapi 3.0 spec json file
+var docCmd = &cobra.Command{
+	Use:   "doc",
+	Short: "render static html or markdown api documentation from openapi 3.0 spec json file",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		s := svc.NewSvc("")
+		s.DocPath = docfile
+		s.GenStaticDoc(docformat, docoutput)
+	},
+}
+
+func init() {
+	svcCmd.AddCommand(docCmd)
+
+	docCmd.Flags().StringVarP(&docfile, "file", "f", "", `openapi 3.0 spec json file path or download link, default is *_openapi3.json file in current directory`)
+	docCmd.Flags().StringVarP(&docformat, "format", "", "markdown", `documentation format, html or markdown`)
+	docCmd.Flags().StringVarP(&docoutput, "output", "o", "", `output file path, default is the json file path with .html or .md extension, or the last element of url path in current directory for download link`)
+}
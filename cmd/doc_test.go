@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDocCmd(t *testing.T) {
+	defer os.Remove("testsvc.html")
+	// go-doudou svc doc --file testdata/testsvc/testsvc_openapi3.json --format html -o testsvc.html
+	_, _, err := ExecuteCommandC(rootCmd, []string{"svc", "doc", "--file", "testdata/testsvc/testsvc_openapi3.json", "--format", "html", "-o", "testsvc.html"}...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile("testsvc.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "<title>Testsvc</title>") {
+		t.Error("title not found in html documentation")
+	}
+}
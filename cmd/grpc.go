@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/unionj-cloud/go-doudou/svc"
+)
+
+// grpcCmd generates grpc transport of the service
+var grpcCmd = &cobra.Command{
+	Use:   "grpc",
+	Short: "generate .proto file and grpc server adapter calling service implementation",
+	Long: `generate .proto file from svc.go and vo package, go code of messages and grpc service by protoc if it is
+found in PATH with protoc-gen-go and protoc-gen-go-grpc plugins, and grpc server adapter into transport/grpcsrv`,
+	Run: func(cmd *cobra.Command, args []string) {
+		s := svc.NewSvc("")
+		if err := s.Grpc(); err != nil {
+			logrus.Fatalln(err)
+		}
+	},
+}
+
+func init() {
+	svcCmd.AddCommand(grpcCmd)
+}
@@ -9,6 +9,12 @@ import (
 var handler bool
 var client string
 var doc bool
+var postman bool
+var genMock bool
+var genTest bool
+var force bool
+var jsonrpc bool
+var plugins []string
 var jsonattrcase string
 var routePatternStrategy int
 
@@ -23,6 +29,12 @@ var httpCmd = &cobra.Command{
 			Client:               client,
 			Omitempty:            omitempty,
 			Doc:                  doc,
+			Postman:              postman,
+			GenMock:              genMock,
+			GenTest:              genTest,
+			Force:                force,
+			Jsonrpc:              jsonrpc,
+			Plugins:              plugins,
 			Jsonattrcase:         jsonattrcase,
 			Env:                  baseURLEnv,
 			RoutePatternStrategy: routePatternStrategy,
@@ -39,6 +51,12 @@ func init() {
 	httpCmd.Flags().BoolVarP(&omitempty, "omitempty", "o", false, `if true, ",omitempty" will be appended to json tag of fields in every generated anonymous struct in handlers`)
 	httpCmd.Flags().StringVarP(&jsonattrcase, "case", "", "lowerCamel", `apply to json tag of fields in every generated anonymous struct in handlers. optional values: lowerCamel, snake`)
 	httpCmd.Flags().BoolVarP(&doc, "doc", "", false, `whether generate openapi 3.0 json document or not`)
+	httpCmd.Flags().BoolVarP(&postman, "postman", "", false, `whether generate postman v2.1 collection and .http file with sample requests or not`)
+	httpCmd.Flags().BoolVarP(&genMock, "mock", "", false, `whether generate mock implementation of service interface into mock package for unit tests or not`)
+	httpCmd.Flags().BoolVarP(&genTest, "test", "", false, `whether generate httptest based table-driven tests for http handlers into transport/httpsrv/handler_test.go or not`)
+	httpCmd.Flags().BoolVarP(&force, "force", "", false, `if true, handlerimpl.go, clientproxy.go and handler_test.go will be regenerated from scratch instead of only appending missing methods and regenerating methods with changed signatures. svcimpl.go is never overwritten`)
+	httpCmd.Flags().BoolVarP(&jsonrpc, "jsonrpc", "", false, `whether generate json-rpc 2.0 handler dispatching <Svc>.<Method> to service implementation into transport/jsonrpc and golang json-rpc client into client/jsonrpcclient.go or not. The handler is mounted on single endpoint /jsonrpc by srv.AddRoute(jsonrpc.Routes(svc)...) in cmd/main.go`)
+	httpCmd.Flags().StringSliceVarP(&plugins, "plugin", "", nil, `name of plugin to run after built-in code generators, can be repeated. Plugin named foo is executable go-doudou-gen-foo in PATH, it receives parsed service interface, vo structs and openapi 3.0 description as json through stdin, and returns files to write as json through stdout`)
 	httpCmd.Flags().StringVarP(&baseURLEnv, "env", "e", "", `base url environment variable name`)
 	httpCmd.Flags().IntVarP(&routePatternStrategy, "routePattern", "r", 0, "route pattern generate strategy. 0 means splitting each methods of service interface by slash / after converting to snake case. 1 means no splitting, only lowercase. recommend default value.")
 }
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/unionj-cloud/go-doudou/svc"
+)
+
+var register bool
+
+// mockCmd starts a mock server from openapi 3.0 spec json file
+var mockCmd = &cobra.Command{
+	Use:   "mock",
+	Short: "start a mock server from openapi 3.0 spec json file",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		s := svc.NewSvc("")
+		s.DocPath = docfile
+		s.Mock(register)
+	},
+}
+
+func init() {
+	svcCmd.AddCommand(mockCmd)
+
+	mockCmd.Flags().StringVarP(&docfile, "file", "f", "", `openapi 3.0 spec json file path or download link`)
+	mockCmd.Flags().BoolVarP(&register, "register", "", false, `whether register the mock server into memberlist cluster or not`)
+}
@@ -3,8 +3,14 @@ package cmd
 import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
+	"os"
 )
 
+var dryRun bool
+var templateDir string
+
 // rootCmd is the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Version: "v0.9.4",
@@ -18,6 +24,18 @@ it provides some useful components and middleware for constructing microservice
 load balancing and so on. it just begins, more features will come out soon.`,
 	Run: func(cmd *cobra.Command, args []string) {
 	},
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		fileutils.SetDryRun(dryRun)
+		templateutils.SetDir(templateDir)
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		if !dryRun {
+			return
+		}
+		if err := fileutils.PrintDiff(os.Stdout); err != nil {
+			logrus.Panicln(err)
+		}
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -31,4 +49,7 @@ func init() {
 	customFormatter.TimestampFormat = "2006-01-02 15:04:05"
 	customFormatter.FullTimestamp = true
 	logrus.SetFormatter(customFormatter)
+
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "If true, print unified diff of files to be generated and ddl statements to be executed without changing anything.")
+	rootCmd.PersistentFlags().StringVar(&templateDir, "templates", templateutils.DefaultDir, "Directory where templates overriding default templates of code generators are looked up.")
 }
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/unionj-cloud/go-doudou/templateutils"
+)
+
+var exportDir string
+
+// templateCmd manages templates used by code generators
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "manage templates used by code generators",
+	Long: `default templates can be overridden by files with the same relative path in template directory, 
+which is .go-doudou/templates in current directory by default and can be changed by --templates flag`,
+}
+
+// exportCmd dumps default templates
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "export default templates with documentation of data passed to them",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := templateutils.Export(exportDir); err != nil {
+			logrus.Panicln(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVarP(&exportDir, "dir", "d", templateutils.DefaultDir, `directory to export default templates into`)
+}
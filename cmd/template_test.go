@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"github.com/unionj-cloud/go-doudou/pathutils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplateExportCmd(t *testing.T) {
+	dir := pathutils.Abs("testtemplateexport")
+	defer os.RemoveAll(dir)
+	// go-doudou template export --dir testtemplateexport
+	_, _, err := ExecuteCommandC(rootCmd, []string{"template", "export", "--dir", dir}...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"README.md", "svc/main.go.tmpl", "svc/svcimpl_methods.go.tmpl", "ddl/dao.go.tmpl", "client/client.go.tmpl", "doc/doc.html.tmpl"} {
+		if _, err = os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -80,7 +80,7 @@ func NewTestsvc(opts ...ddhttp.DdClientOption) *TestsvcClient {
 	}
 
 	svcClient.client.OnBeforeRequest(func(_ *resty.Client, request *resty.Request) error {
-		request.URL = svcClient.provider.SelectServer() + request.URL
+		request.URL = ddhttp.SelectServer(request.Context(), svcClient.provider) + request.URL
 		return nil
 	})
 
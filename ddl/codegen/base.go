@@ -2,6 +2,8 @@ package codegen
 
 import (
 	log "github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"os"
 	"path/filepath"
 	"text/template"
@@ -30,13 +32,17 @@ type Base interface {
 }
 `
 
+func init() {
+	templateutils.Register("ddl/base.go.tmpl", basetmpl, `No data.`)
+}
+
 // GenBaseGo generates Base interface code
 // Base interface wraps some common CRUD operations for convenient use
 func GenBaseGo(domainpath string, folder ...string) error {
 	var (
 		err     error
 		daopath string
-		f       *os.File
+		f       fileutils.File
 		tpl     *template.Template
 		df      string
 	)
@@ -45,12 +51,12 @@ func GenBaseGo(domainpath string, folder ...string) error {
 		df = folder[0]
 	}
 	daopath = filepath.Join(filepath.Dir(domainpath), df)
-	_ = os.MkdirAll(daopath, os.ModePerm)
+	_ = fileutils.MkdirAll(daopath, os.ModePerm)
 	basefile := filepath.Join(daopath, "base.go")
-	if _, err = os.Stat(basefile); os.IsNotExist(err) {
-		f, _ = os.Create(basefile)
+	if _, err = fileutils.Stat(basefile); os.IsNotExist(err) {
+		f, _ = fileutils.Create(basefile)
 		defer f.Close()
-		tpl, _ = template.New("base.go.tmpl").Parse(basetmpl)
+		tpl, _ = template.New("base.go.tmpl").Parse(templateutils.Lookup("ddl/base.go.tmpl"))
 		_ = tpl.Execute(f, nil)
 	} else {
 		log.Warnf("file %s already exists", basefile)
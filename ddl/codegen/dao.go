@@ -3,6 +3,8 @@ package codegen
 import (
 	log "github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/ddl/table"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"os"
 	"path/filepath"
 	"strings"
@@ -15,12 +17,16 @@ type {{.DomainName}}Dao interface {
 	Base
 }`
 
+func init() {
+	templateutils.Register("ddl/dao.go.tmpl", daotmpl, `DomainName string: name of domain struct`)
+}
+
 // GenDaoGo generates dao layer interface code
 func GenDaoGo(domainpath string, t table.Table, folder ...string) error {
 	var (
 		err     error
 		daopath string
-		f       *os.File
+		f       fileutils.File
 		tpl     *template.Template
 		df      string
 	)
@@ -29,13 +35,13 @@ func GenDaoGo(domainpath string, t table.Table, folder ...string) error {
 		df = folder[0]
 	}
 	daopath = filepath.Join(filepath.Dir(domainpath), df)
-	_ = os.MkdirAll(daopath, os.ModePerm)
+	_ = fileutils.MkdirAll(daopath, os.ModePerm)
 	daofile := filepath.Join(daopath, strings.ToLower(t.Meta.Name)+"dao.go")
-	if _, err = os.Stat(daofile); os.IsNotExist(err) {
-		f, _ = os.Create(daofile)
+	if _, err = fileutils.Stat(daofile); os.IsNotExist(err) {
+		f, _ = fileutils.Create(daofile)
 		defer f.Close()
 
-		tpl, _ = template.New("dao.go.tmpl").Parse(daotmpl)
+		tpl, _ = template.New("dao.go.tmpl").Parse(templateutils.Lookup("ddl/dao.go.tmpl"))
 		_ = tpl.Execute(f, struct {
 			DomainName string
 		}{
@@ -5,6 +5,8 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/astutils"
 	"github.com/unionj-cloud/go-doudou/ddl/table"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"os"
 	"path/filepath"
 	"strings"
@@ -359,13 +361,22 @@ func (receiver {{.DomainName}}DaoImpl) PageMany(ctx context.Context, page query.
 	return pageRet, nil
 }`
 
+func init() {
+	templateutils.Register("ddl/daoimpl.go.tmpl", daoimpltmpl, `DomainPackage string: import path of domain package
+DomainName string: name of domain struct
+TableName string: name of table
+PkField astutils.FieldMeta: field of primary key in domain struct
+PkCol table.Column: column of primary key with fields Table, Name, Type, Default, Pk, Nullable, Unsigned, Autoincrement, Extra, Meta of astutils.FieldMeta, AutoSet, Indexes and Fk
+Functions: ToLower, ToSnake`)
+}
+
 // GenDaoImplGo generates dao layer implementation code
 func GenDaoImplGo(domainpath string, t table.Table, folder ...string) error {
 	var (
 		err      error
 		dpkg     string
 		daopath  string
-		f        *os.File
+		f        fileutils.File
 		funcMap  map[string]interface{}
 		tpl      *template.Template
 		pkColumn table.Column
@@ -376,18 +387,18 @@ func GenDaoImplGo(domainpath string, t table.Table, folder ...string) error {
 		df = folder[0]
 	}
 	daopath = filepath.Join(filepath.Dir(domainpath), df)
-	_ = os.MkdirAll(daopath, os.ModePerm)
+	_ = fileutils.MkdirAll(daopath, os.ModePerm)
 
 	daofile := filepath.Join(daopath, strings.ToLower(t.Meta.Name)+"daoimpl.go")
-	if _, err = os.Stat(daofile); os.IsNotExist(err) {
-		f, _ = os.Create(daofile)
+	if _, err = fileutils.Stat(daofile); os.IsNotExist(err) {
+		f, _ = fileutils.Create(daofile)
 		defer f.Close()
 
 		dpkg = astutils.GetImportPath(domainpath)
 		funcMap = make(map[string]interface{})
 		funcMap["ToLower"] = strings.ToLower
 		funcMap["ToSnake"] = strcase.ToSnake
-		tpl, _ = template.New("daoimpl.go.tmpl").Funcs(funcMap).Parse(daoimpltmpl)
+		tpl, _ = template.New("daoimpl.go.tmpl").Funcs(funcMap).Parse(templateutils.Lookup("ddl/daoimpl.go.tmpl"))
 		for _, column := range t.Columns {
 			if column.Pk {
 				pkColumn = column
@@ -6,6 +6,8 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/astutils"
 	"github.com/unionj-cloud/go-doudou/ddl/table"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"os"
 	"path/filepath"
 	"strings"
@@ -103,12 +105,23 @@ SET
     {{` + "`" + `{{` + "`" + `}}Eval "NoneZeroSet" . | TrimSuffix ","{{` + "`" + `}}` + "`" + `}}
 {{` + "`" + `{{` + "`" + `}}end{{` + "`" + `}}` + "`" + `}}`
 
+func init() {
+	templateutils.Register("ddl/daosql.tmpl", daosqltmpl, `Schema string: database schema from DB_SCHEMA environment variable
+TableName string: name of table
+DomainName string: name of domain struct
+InsertColumns []table.Column: columns not set by database automatically
+UpdateColumns []table.Column: columns not set by database automatically except primary key
+Pk table.Column: column of primary key
+Functions: ToSnake
+Output is wrapped as a go string variable in daosql.go file, and parsed by templateutils.BlockMysql at runtime`)
+}
+
 // GenDaoSQL generates sql statements used by dao layer
 func GenDaoSQL(domainpath string, t table.Table, folder ...string) error {
 	var (
 		err      error
 		daopath  string
-		f        *os.File
+		f        fileutils.File
 		funcMap  map[string]interface{}
 		tpl      *template.Template
 		iColumns []table.Column
@@ -121,15 +134,15 @@ func GenDaoSQL(domainpath string, t table.Table, folder ...string) error {
 		df = folder[0]
 	}
 	daopath = filepath.Join(filepath.Dir(domainpath), df)
-	_ = os.MkdirAll(daopath, os.ModePerm)
+	_ = fileutils.MkdirAll(daopath, os.ModePerm)
 	daofile := filepath.Join(daopath, strings.ToLower(t.Meta.Name)+"daosql.go")
-	if _, err = os.Stat(daofile); os.IsNotExist(err) {
-		f, _ = os.Create(daofile)
+	if _, err = fileutils.Stat(daofile); os.IsNotExist(err) {
+		f, _ = fileutils.Create(daofile)
 		defer f.Close()
 
 		funcMap = make(map[string]interface{})
 		funcMap["ToSnake"] = strcase.ToSnake
-		tpl, _ = template.New("daosql.tmpl").Funcs(funcMap).Parse(daosqltmpl)
+		tpl, _ = template.New("daosql.tmpl").Funcs(funcMap).Parse(templateutils.Lookup("ddl/daosql.tmpl"))
 
 		for _, co := range t.Columns {
 			if !co.AutoSet {
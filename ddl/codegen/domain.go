@@ -3,6 +3,7 @@ package codegen
 import (
 	log "github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/astutils"
+	"github.com/unionj-cloud/go-doudou/fileutils"
 	"github.com/unionj-cloud/go-doudou/templateutils"
 	"os"
 	"path/filepath"
@@ -24,19 +25,23 @@ type {{.Name}} struct {
 {{- end }}
 }`
 
+func init() {
+	templateutils.Register("ddl/domain.go.tmpl", domaintmpl, `Data is astutils.StructMeta converted from table with fields Name, Fields of []astutils.FieldMeta, Comments, Methods and IsExport`)
+}
+
 // GenDomainGo generates structs code in domain pkg from database tables
 func GenDomainGo(dpath string, domain astutils.StructMeta) error {
 	var (
 		err error
-		f   *os.File
+		f   fileutils.File
 	)
-	_ = os.MkdirAll(dpath, os.ModePerm)
+	_ = fileutils.MkdirAll(dpath, os.ModePerm)
 	dfile := filepath.Join(dpath, strings.ToLower(domain.Name)+".go")
-	if _, err = os.Stat(dfile); os.IsNotExist(err) {
-		f, _ = os.Create(dfile)
+	if _, err = fileutils.Stat(dfile); os.IsNotExist(err) {
+		f, _ = fileutils.Create(dfile)
 		defer f.Close()
 		var source string
-		source, _ = templateutils.String("domain.go.tmpl", domaintmpl, domain)
+		source, _ = templateutils.String("domain.go.tmpl", templateutils.Lookup("ddl/domain.go.tmpl"), domain)
 		astutils.FixImport([]byte(source), dfile)
 	} else {
 		log.Warnf("file %s already exists", dfile)
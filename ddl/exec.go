@@ -16,6 +16,7 @@ import (
 	"github.com/unionj-cloud/go-doudou/ddl/codegen"
 	"github.com/unionj-cloud/go-doudou/ddl/config"
 	"github.com/unionj-cloud/go-doudou/ddl/table"
+	"github.com/unionj-cloud/go-doudou/fileutils"
 )
 
 // Ddl is for ddl command
@@ -26,6 +27,7 @@ type Ddl struct {
 	Pre     string
 	Df      string
 	Conf    config.DbConfig
+	DryRun  bool
 }
 
 // Exec executes the logic for ddl command
@@ -59,14 +61,19 @@ func (d Ddl) Exec() {
 	var tables []table.Table
 	timeoutCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
-	_ = os.MkdirAll(d.Dir, os.ModePerm)
+	_ = fileutils.MkdirAll(d.Dir, os.ModePerm)
 	if !d.Reverse {
-		tables = table.Struct2Table(timeoutCtx, d.Dir, d.Pre, existTables, db, d.Conf.Schema)
+		ctx := timeoutCtx
+		if d.DryRun {
+			logrus.Warnln("dry run: ddl statements below are not executed")
+			ctx = table.WithDryRun(ctx)
+		}
+		tables = table.Struct2Table(ctx, d.Dir, d.Pre, existTables, db, d.Conf.Schema)
 	} else {
 		tables = table.Table2struct(timeoutCtx, d.Pre, d.Conf.Schema, existTables, db)
 		for _, item := range tables {
 			dfile := filepath.Join(d.Dir, strings.ToLower(item.Meta.Name)+".go")
-			if _, err = os.Stat(dfile); os.IsNotExist(err) {
+			if _, err = fileutils.Stat(dfile); os.IsNotExist(err) {
 				if err = codegen.GenDomainGo(d.Dir, item.Meta); err != nil {
 					panic(fmt.Sprintf("%+v", err))
 				}
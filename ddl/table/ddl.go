@@ -2,6 +2,8 @@ package table
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	mapset "github.com/deckarep/golang-set"
 	"github.com/iancoleman/strcase"
@@ -361,11 +363,36 @@ func dbColumn2Column(item DbColumn, colIdxMap map[string][]IndexItem, t string,
 	return col
 }
 
+type dryRunKey struct{}
+
+// WithDryRun returns a copy of ctx with which Struct2Table prints ddl statements but doesn't execute them
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+func isDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+// dryRunQuerier queries by the wrapped Querier but skips statements passed to ExecContext
+type dryRunQuerier struct {
+	wrapper.Querier
+}
+
+// ExecContext does nothing because statements have been printed already
+func (q dryRunQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+// Struct2Table updates tables from structs defined in go files of dir, if ctx is returned by WithDryRun,
+// ddl statements are only printed
 func Struct2Table(ctx context.Context, dir, pre string, existTables []string, db *sqlx.DB, schema string) (tables []Table) {
 	var (
 		files []string
 		err   error
 		tx    *sqlx.Tx
+		q     wrapper.Querier
 		root  *ast.File
 	)
 	if err = filepath.Walk(dir, astutils.Visit(&files)); err != nil {
@@ -396,11 +423,15 @@ func Struct2Table(ctx context.Context, dir, pre string, existTables []string, db
 			panic(fmt.Sprintf("%+v", err))
 		}
 	}()
+	q = tx
+	if isDryRun(ctx) {
+		q = dryRunQuerier{tx}
+	}
 
 	for _, t := range tables {
 		if sliceutils.StringContains(existTables, t.Name) {
 			var columns []DbColumn
-			if err = tx.SelectContext(ctx, &columns, fmt.Sprintf("desc %s", t.Name)); err != nil {
+			if err = q.SelectContext(ctx, &columns, fmt.Sprintf("desc %s", t.Name)); err != nil {
 				panic(fmt.Sprintf("%+v", err))
 			}
 			var existColumnNames []interface{}
@@ -411,29 +442,33 @@ func Struct2Table(ctx context.Context, dir, pre string, existTables []string, db
 
 			for _, col := range t.Columns {
 				if existColSet.Contains(col.Name) {
-					if err = ChangeColumn(ctx, tx, col); err != nil {
+					if err = ChangeColumn(ctx, q, col); err != nil {
 						panic(fmt.Sprintf("%+v", err))
 					}
 				} else {
-					if err = AddColumn(ctx, tx, col); err != nil {
+					if err = AddColumn(ctx, q, col); err != nil {
 						panic(fmt.Sprintf("%+v", err))
 					}
 				}
 			}
-			fks := foreignKeys(ctx, tx, schema, t.Name)
-			updateIndexFromStruct(ctx, tx, t, fks)
-			updateFkFromStruct(ctx, tx, t, fks)
+			fks := foreignKeys(ctx, q, schema, t.Name)
+			updateIndexFromStruct(ctx, q, t, fks)
+			updateFkFromStruct(ctx, q, t, fks)
 		} else {
-			if err = CreateTable(ctx, tx, t); err != nil {
+			if err = CreateTable(ctx, q, t); err != nil {
 				panic(fmt.Sprintf("%+v", err))
 			}
 		}
 	}
+	if isDryRun(ctx) {
+		_ = tx.Rollback()
+		return
+	}
 	_ = tx.Commit()
 	return
 }
 
-func updateFkFromStruct(ctx context.Context, tx *sqlx.Tx, t Table, fks []ForeignKey) {
+func updateFkFromStruct(ctx context.Context, tx wrapper.Querier, t Table, fks []ForeignKey) {
 	fkMap := make(map[string]ForeignKey)
 	for _, fk := range fks {
 		fkMap[fk.Constraint] = fk
@@ -470,7 +505,7 @@ func updateFkFromStruct(ctx context.Context, tx *sqlx.Tx, t Table, fks []Foreign
 	}
 }
 
-func updateIndexFromStruct(ctx context.Context, tx *sqlx.Tx, t Table, fks []ForeignKey) {
+func updateIndexFromStruct(ctx context.Context, tx wrapper.Querier, t Table, fks []ForeignKey) {
 	var dbIndexes []DbIndex
 	if err := tx.SelectContext(ctx, &dbIndexes, fmt.Sprintf("SHOW INDEXES FROM %s", t.Name)); err != nil {
 		panic(fmt.Sprintf("%+v", err))
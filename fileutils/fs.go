@@ -0,0 +1,267 @@
+package fileutils
+
+import (
+	"bytes"
+	"github.com/pmezard/go-difflib/difflib"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Functions in this file wrap functions of os and ioutil packages used by code generators. By default, they work on
+// disk. In dry-run mode enabled by SetDryRun, writes go to an in-memory overlay of disk instead, reads see the overlay,
+// and PrintDiff prints what would change as unified diff.
+
+type memEntry struct {
+	data    []byte
+	removed bool
+}
+
+type memFS struct {
+	lock  sync.RWMutex
+	files map[string]*memEntry
+	dirs  map[string]bool
+}
+
+func newMemFS() *memFS {
+	return &memFS{
+		files: make(map[string]*memEntry),
+		dirs:  make(map[string]bool),
+	}
+}
+
+var (
+	dryRun  bool
+	overlay = newMemFS()
+)
+
+// SetDryRun enables or disables dry-run mode, the in-memory overlay is discarded
+func SetDryRun(enabled bool) {
+	dryRun = enabled
+	overlay = newMemFS()
+}
+
+// IsDryRun reports whether dry-run mode is enabled
+func IsDryRun() bool {
+	return dryRun
+}
+
+func (fs *memFS) entry(name string) (*memEntry, bool) {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+	e, ok := fs.files[filepath.Clean(name)]
+	return e, ok
+}
+
+func (fs *memFS) write(name string, data []byte, appending bool) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	name = filepath.Clean(name)
+	e, ok := fs.files[name]
+	if !ok || !appending {
+		e = &memEntry{}
+		fs.files[name] = e
+	}
+	e.removed = false
+	e.data = append(e.data, data...)
+}
+
+// File is implemented by *os.File and files in the in-memory overlay
+type File interface {
+	io.Reader
+	io.Writer
+	io.Closer
+}
+
+type memFile struct {
+	name   string
+	reader *bytes.Reader
+}
+
+// Read implements io.Reader, files returned by Create have nothing to read like os.Create
+func (f memFile) Read(p []byte) (int, error) {
+	if f.reader == nil {
+		return 0, io.EOF
+	}
+	return f.reader.Read(p)
+}
+
+// Write implements io.Writer, files returned by Open are read only like os.Open
+func (f memFile) Write(p []byte) (int, error) {
+	if f.reader != nil {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: os.ErrPermission}
+	}
+	overlay.write(f.name, p, true)
+	return len(p), nil
+}
+
+// Close implements io.Closer
+func (f memFile) Close() error {
+	return nil
+}
+
+type memFileInfo struct {
+	name  string
+	size  int64
+	isDir bool
+}
+
+func (fi memFileInfo) Name() string { return fi.name }
+
+func (fi memFileInfo) Size() int64 { return fi.size }
+
+func (fi memFileInfo) Mode() os.FileMode {
+	if fi.isDir {
+		return os.ModeDir | os.ModePerm
+	}
+	return os.ModePerm
+}
+
+func (fi memFileInfo) ModTime() time.Time { return time.Time{} }
+
+func (fi memFileInfo) IsDir() bool { return fi.isDir }
+
+func (fi memFileInfo) Sys() interface{} { return nil }
+
+func notExist(op, name string) error {
+	return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+}
+
+// Create creates or truncates the named file like os.Create
+func Create(name string) (File, error) {
+	if !dryRun {
+		return os.Create(name)
+	}
+	overlay.write(name, nil, false)
+	return memFile{name: name}, nil
+}
+
+// WriteFile writes data to the named file like ioutil.WriteFile
+func WriteFile(name string, data []byte, perm os.FileMode) error {
+	if !dryRun {
+		return ioutil.WriteFile(name, data, perm)
+	}
+	overlay.write(name, data, false)
+	return nil
+}
+
+// ReadFile reads the named file like ioutil.ReadFile
+func ReadFile(name string) ([]byte, error) {
+	if e, ok := overlay.entry(name); dryRun && ok {
+		if e.removed {
+			return nil, notExist("open", name)
+		}
+		return append([]byte{}, e.data...), nil
+	}
+	return ioutil.ReadFile(name)
+}
+
+// Open opens the named file for reading like os.Open
+func Open(name string) (File, error) {
+	if !dryRun {
+		return os.Open(name)
+	}
+	data, err := ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return memFile{name: name, reader: bytes.NewReader(data)}, nil
+}
+
+// Stat returns os.FileInfo of the named file like os.Stat
+func Stat(name string) (os.FileInfo, error) {
+	if dryRun {
+		if e, ok := overlay.entry(name); ok {
+			if e.removed {
+				return nil, notExist("stat", name)
+			}
+			return memFileInfo{name: filepath.Base(name), size: int64(len(e.data))}, nil
+		}
+		overlay.lock.RLock()
+		isDir := overlay.dirs[filepath.Clean(name)]
+		overlay.lock.RUnlock()
+		if isDir {
+			return memFileInfo{name: filepath.Base(name), isDir: true}, nil
+		}
+	}
+	return os.Stat(name)
+}
+
+// MkdirAll creates a directory along with any necessary parents like os.MkdirAll
+func MkdirAll(path string, perm os.FileMode) error {
+	if !dryRun {
+		return os.MkdirAll(path, perm)
+	}
+	overlay.lock.Lock()
+	defer overlay.lock.Unlock()
+	for dir := filepath.Clean(path); !overlay.dirs[dir]; dir = filepath.Dir(dir) {
+		overlay.dirs[dir] = true
+	}
+	return nil
+}
+
+// Remove removes the named file like os.Remove
+func Remove(name string) error {
+	if !dryRun {
+		return os.Remove(name)
+	}
+	if _, err := Stat(name); err != nil {
+		return err
+	}
+	overlay.lock.Lock()
+	defer overlay.lock.Unlock()
+	overlay.files[filepath.Clean(name)] = &memEntry{removed: true}
+	return nil
+}
+
+// PrintDiff prints changes made in dry-run mode to w as unified diff, files are sorted by name
+func PrintDiff(w io.Writer) error {
+	overlay.lock.RLock()
+	defer overlay.lock.RUnlock()
+	var names []string
+	for name := range overlay.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		e := overlay.files[name]
+		fromFile, toFile := name, name
+		original, err := ioutil.ReadFile(name)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return err
+			}
+			fromFile = os.DevNull
+		}
+		if e.removed {
+			if fromFile == os.DevNull {
+				continue
+			}
+			toFile = os.DevNull
+		}
+		if fromFile != os.DevNull && toFile != os.DevNull && bytes.Equal(original, e.data) {
+			continue
+		}
+		var a, b []string
+		if fromFile != os.DevNull {
+			a = difflib.SplitLines(string(original))
+		}
+		if toFile != os.DevNull {
+			b = difflib.SplitLines(string(e.data))
+		}
+		if err = difflib.WriteUnifiedDiff(w, difflib.UnifiedDiff{
+			A:        a,
+			B:        b,
+			FromFile: fromFile,
+			ToFile:   toFile,
+			Context:  3,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
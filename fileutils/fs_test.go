@@ -0,0 +1,83 @@
+package fileutils
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/pathutils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	dir := pathutils.Abs("testdryrun")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	existing := filepath.Join(dir, "existing.txt")
+	if err := ioutil.WriteFile(existing, []byte("a\nb\n"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	removed := filepath.Join(dir, "removed.txt")
+	if err := ioutil.WriteFile(removed, []byte("c\n"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	SetDryRun(true)
+	defer SetDryRun(false)
+	assert.True(t, IsDryRun())
+
+	subdir := filepath.Join(dir, "sub")
+	assert.NoError(t, MkdirAll(subdir, os.ModePerm))
+	fi, err := Stat(subdir)
+	assert.NoError(t, err)
+	assert.True(t, fi.IsDir())
+
+	created := filepath.Join(subdir, "created.txt")
+	f, err := Create(created)
+	assert.NoError(t, err)
+	_, _ = f.Write([]byte("hello\n"))
+	_ = f.Close()
+	data, err := ReadFile(created)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", string(data))
+
+	assert.NoError(t, WriteFile(existing, []byte("a\nB\n"), os.ModePerm))
+	assert.NoError(t, Remove(removed))
+	_, err = Stat(removed)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = os.Stat(subdir)
+	assert.True(t, os.IsNotExist(err))
+	data, _ = ioutil.ReadFile(existing)
+	assert.Equal(t, "a\nb\n", string(data))
+	_, err = os.Stat(removed)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, PrintDiff(&buf))
+	diff := buf.String()
+	assert.Contains(t, diff, "--- "+existing+"\n+++ "+existing+"\n")
+	assert.Contains(t, diff, "-b\n+B\n")
+	assert.Contains(t, diff, "--- /dev/null\n+++ "+created+"\n")
+	assert.Contains(t, diff, "+hello\n")
+	assert.Contains(t, diff, "--- "+removed+"\n+++ /dev/null\n")
+	assert.Contains(t, diff, "-c\n")
+}
+
+func TestDryRunDisabled(t *testing.T) {
+	dir := pathutils.Abs("testdryrundisabled")
+	defer os.RemoveAll(dir)
+	assert.False(t, IsDryRun())
+	assert.NoError(t, MkdirAll(dir, os.ModePerm))
+	file := filepath.Join(dir, "file.txt")
+	assert.NoError(t, WriteFile(file, []byte("hello"), os.ModePerm))
+	data, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+	var buf bytes.Buffer
+	assert.NoError(t, PrintDiff(&buf))
+	assert.Empty(t, buf.String())
+}
@@ -1,18 +1,49 @@
 package loadbalance
 
 import (
+	"hash/fnv"
 	"math"
 	"math/rand"
+	"sort"
 )
 
+// Subset returns subset of backends for client with clientId by deterministic subsetting algorithm.
+// It doesn't modify backends and is safe for concurrent use.
 func Subset(backends []string, clientId int, subsetSize int) []string {
 	subsetCount := int(math.Ceil(float64(len(backends)) / float64(subsetSize)))
 	round := int64(clientId / subsetCount)
-	rand.Seed(round)
-	rand.Shuffle(len(backends), func(i, j int) {
-		backends[i], backends[j] = backends[j], backends[i]
+	shuffled := append([]string{}, backends...)
+	rand.New(rand.NewSource(round)).Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
 	})
 	subsetId := clientId % subsetCount
 	start := subsetId * subsetSize
-	return backends[start : start+subsetSize]
+	return shuffled[start : start+subsetSize]
+}
+
+func rendezvousScore(client, backend string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(client))
+	h.Write([]byte{0})
+	h.Write([]byte(backend))
+	return h.Sum64()
+}
+
+// RendezvousSubset returns subsetSize backends with the highest rendezvous hashing scores for client.
+// The subset is stable for the same client, and when backends join or leave, only the joined or left ones
+// may move in or out of the subset. It doesn't modify backends and is safe for concurrent use.
+func RendezvousSubset(backends []string, client string, subsetSize int) []string {
+	if subsetSize <= 0 || subsetSize >= len(backends) {
+		return append([]string{}, backends...)
+	}
+	scores := make(map[string]uint64, len(backends))
+	sorted := make([]string, len(backends))
+	for i, backend := range backends {
+		scores[backend] = rendezvousScore(client, backend)
+		sorted[i] = backend
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return scores[sorted[i]] > scores[sorted[j]]
+	})
+	return sorted[:subsetSize]
 }
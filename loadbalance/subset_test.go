@@ -1,6 +1,7 @@
 package loadbalance
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 )
@@ -113,3 +114,70 @@ func TestSubset(t *testing.T) {
 		})
 	}
 }
+
+func TestSubsetNotModifyBackends(t *testing.T) {
+	backends := []string{"192.168.0.0", "192.168.0.1", "192.168.0.2", "192.168.0.3"}
+	Subset(backends, 1, 2)
+	if !reflect.DeepEqual(backends, []string{"192.168.0.0", "192.168.0.1", "192.168.0.2", "192.168.0.3"}) {
+		t.Errorf("Subset() modified backends: %v", backends)
+	}
+}
+
+func TestRendezvousSubset(t *testing.T) {
+	var backends []string
+	for i := 0; i < 20; i++ {
+		backends = append(backends, fmt.Sprintf("node-%d", i))
+	}
+	got := RendezvousSubset(backends, "client-a", 5)
+	if len(got) != 5 {
+		t.Fatalf("RendezvousSubset() returned %d backends, want 5", len(got))
+	}
+	if again := RendezvousSubset(backends, "client-a", 5); !reflect.DeepEqual(got, again) {
+		t.Errorf("RendezvousSubset() = %v, want stable subset %v", again, got)
+	}
+	if other := RendezvousSubset(backends, "client-b", 5); reflect.DeepEqual(got, other) {
+		t.Errorf("RendezvousSubset() returned the same subset %v for different clients", got)
+	}
+
+	// remove a backend out of the subset, subset should not change
+	var rest []string
+	for _, backend := range backends {
+		if backend != notIn(backends, got) {
+			rest = append(rest, backend)
+		}
+	}
+	if after := RendezvousSubset(rest, "client-a", 5); !reflect.DeepEqual(got, after) {
+		t.Errorf("RendezvousSubset() = %v, want %v", after, got)
+	}
+
+	// remove a backend in the subset, only it should be replaced
+	rest = nil
+	for _, backend := range backends {
+		if backend != got[0] {
+			rest = append(rest, backend)
+		}
+	}
+	after := RendezvousSubset(rest, "client-a", 5)
+	if !reflect.DeepEqual(got[1:], after[:4]) {
+		t.Errorf("RendezvousSubset() = %v, want %v kept", after, got[1:])
+	}
+
+	if all := RendezvousSubset(backends[:3], "client-a", 5); len(all) != 3 {
+		t.Errorf("RendezvousSubset() returned %d backends, want 3", len(all))
+	}
+}
+
+func notIn(backends, subset []string) string {
+	for _, backend := range backends {
+		found := false
+		for _, item := range subset {
+			if item == backend {
+				found = true
+			}
+		}
+		if !found {
+			return backend
+		}
+	}
+	return ""
+}
@@ -2,20 +2,20 @@ package client
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
-	"github.com/go-resty/resty/v2"
 	"github.com/iancoleman/strcase"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/astutils"
+	"github.com/unionj-cloud/go-doudou/fileutils"
 	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
 	"github.com/unionj-cloud/go-doudou/sliceutils"
 	"github.com/unionj-cloud/go-doudou/stringutils"
-	"io/ioutil"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"text/template"
 )
@@ -62,6 +62,9 @@ import (
 type {{.Meta.Name}}Client struct {
 	provider registry.IServiceProvider
 	client   *resty.Client
+	{{- if or .Auth.Bearer .Auth.APIKey .Auth.Basic }}
+	credentials
+	{{- end }}
 }
 
 func (receiver *{{.Meta.Name}}Client) SetProvider(provider registry.IServiceProvider) {
@@ -71,7 +74,6 @@ func (receiver *{{.Meta.Name}}Client) SetProvider(provider registry.IServiceProv
 func (receiver *{{.Meta.Name}}Client) SetClient(client *resty.Client) {
 	receiver.client = client
 }
-
 {{- range $m := .Meta.Methods }}
 	{{- range $i, $c := $m.Comments }}
 	{{- if eq $i 0}}
@@ -92,6 +94,33 @@ func (receiver *{{.Meta.Name}}Client) SetClient(client *resty.Client) {
 		_req := receiver.client.R()
 		_req.SetContext(ctx)
 
+		{{- range $s := index $.Security $m.Name }}
+			{{- if eq $s.Kind "bearer" }}
+				if stringutils.IsNotEmpty(receiver.bearerToken) {
+					_req.SetAuthToken(receiver.bearerToken)
+				}
+			{{- else if eq $s.Kind "basic" }}
+				if stringutils.IsNotEmpty(receiver.username) {
+					_req.SetBasicAuth(receiver.username, receiver.password)
+				}
+			{{- else if eq $s.Kind "header" }}
+				if stringutils.IsNotEmpty(receiver.apiKey) {
+					_req.SetHeader("{{$s.Name}}", receiver.apiKey)
+				}
+			{{- else if eq $s.Kind "query" }}
+				if stringutils.IsNotEmpty(receiver.apiKey) {
+					_req.SetQueryParam("{{$s.Name}}", receiver.apiKey)
+				}
+			{{- else if eq $s.Kind "cookie" }}
+				if stringutils.IsNotEmpty(receiver.apiKey) {
+					_req.SetCookie(&http.Cookie{
+						Name:  "{{$s.Name}}",
+						Value: receiver.apiKey,
+					})
+				}
+			{{- end }}
+		{{- end }}
+
 		{{- if $m.QueryParams }}
 			_queryParams, _ := _querystring.Values({{$m.QueryParams.Name}})
 			_req.SetQueryParamsFromValues(_queryParams)
@@ -203,7 +232,7 @@ func New{{.Meta.Name}}(opts ...ddhttp.DdClientOption) *{{.Meta.Name}}Client {
 	}
 
 	svcClient.client.OnBeforeRequest(func(_ *resty.Client, request *resty.Request) error {
-		request.URL = svcClient.provider.SelectServer() + request.URL
+		request.URL = ddhttp.SelectServer(request.Context(), svcClient.provider) + request.URL
 		return nil
 	})
 
@@ -223,6 +252,88 @@ func New{{.Meta.Name}}(opts ...ddhttp.DdClientOption) *{{.Meta.Name}}Client {
 }
 `
 
+var authtmpl = `package {{.Pkg}}
+
+import (
+	ddhttp "github.com/unionj-cloud/go-doudou/svc/http"
+)
+
+// credentials are sent to apis requiring authentication, they are shared by clients in this package
+type credentials struct {
+	{{- if .Auth.Bearer }}
+	bearerToken string
+	{{- end }}
+	{{- if .Auth.APIKey }}
+	apiKey string
+	{{- end }}
+	{{- if .Auth.Basic }}
+	username string
+	password string
+	{{- end }}
+}
+
+func (c *credentials) getCredentials() *credentials {
+	return c
+}
+
+// credentialsOf returns credentials of client, nil if no api of client requires authentication
+func credentialsOf(client ddhttp.DdClient) *credentials {
+	if c, ok := client.(interface{ getCredentials() *credentials }); ok {
+		return c.getCredentials()
+	}
+	return nil
+}
+{{- if .Auth.Bearer }}
+
+// WithBearerToken sets token sent as Authorization: Bearer header to apis requiring bearer token
+func WithBearerToken(token string) ddhttp.DdClientOption {
+	return func(client ddhttp.DdClient) {
+		if c := credentialsOf(client); c != nil {
+			c.bearerToken = token
+		}
+	}
+}
+{{- end }}
+{{- if .Auth.APIKey }}
+
+// WithAPIKey sets key sent to apis requiring api key
+func WithAPIKey(key string) ddhttp.DdClientOption {
+	return func(client ddhttp.DdClient) {
+		if c := credentialsOf(client); c != nil {
+			c.apiKey = key
+		}
+	}
+}
+{{- end }}
+{{- if .Auth.Basic }}
+
+// WithBasicAuth sets username and password sent to apis requiring http basic authentication
+func WithBasicAuth(username, password string) ddhttp.DdClientOption {
+	return func(client ddhttp.DdClient) {
+		if c := credentialsOf(client); c != nil {
+			c.username = username
+			c.password = password
+		}
+	}
+}
+{{- end }}
+`
+
+func init() {
+	templateutils.Register("client/vo.go.tmpl", votmpl, `Schemas map[string]v3.Schema: schemas in components of OpenAPI 3.0 json document
+Omit bool: whether to add omitempty to json tags
+Pkg string: package name
+Functions: toCamel, toGoType, toComment, stringContains`)
+	templateutils.Register("client/client.go.tmpl", httptmpl, `Meta astutils.InterfaceMeta: apis converted from paths of OpenAPI 3.0 json document
+Env string: environment variable name of base url
+Pkg string: package name
+Security map[string][]securityItem: credentials required by each method, securityItem has fields Kind (bearer, basic, header, query or cookie) and Name
+Auth authKinds: whether Bearer, APIKey or Basic credentials are required by any api, client embeds credentials declared in auth.go if so
+Functions: toCamel, contains, restyMethod, toUpper`)
+	templateutils.Register("client/auth.go.tmpl", authtmpl, `Pkg string: package name
+Auth authKinds: whether Bearer, APIKey or Basic credentials are required by any api of all clients in the package`)
+}
+
 func toMethod(endpoint string) string {
 	endpoint = strings.ReplaceAll(strings.ReplaceAll(endpoint, "{", ""), "}", "")
 	endpoint = strings.ReplaceAll(strings.Trim(endpoint, "/"), "/", "_")
@@ -255,21 +366,22 @@ func restyMethod(method string) string {
 	return strings.Title(strings.ToLower(httpMethod(method)))
 }
 
-func genGoHTTP(paths map[string]v3.Path, svcname, dir, env, pkg string) {
-	_ = os.MkdirAll(dir, os.ModePerm)
+// genGoHTTP generates client of svcname, returns kinds of credentials required by its apis
+func genGoHTTP(paths map[string]v3.Path, svcname, dir, env, pkg string) authKinds {
+	_ = fileutils.MkdirAll(dir, os.ModePerm)
 	output := filepath.Join(dir, svcname+"client.go")
-	fi, err := os.Stat(output)
+	fi, err := fileutils.Stat(output)
 	if err != nil && !os.IsNotExist(err) {
 		panic(err)
 	}
 	if fi != nil {
 		logrus.Warningln("file " + svcname + "client.go will be overwrited")
 	}
-	var f *os.File
-	if f, err = os.Create(output); err != nil {
+	var f fileutils.File
+	if f, err = fileutils.Create(output); err != nil {
 		panic(err)
 	}
-	defer func(f *os.File) {
+	defer func(f fileutils.File) {
 		_ = f.Close()
 	}(f)
 
@@ -278,19 +390,143 @@ func genGoHTTP(paths map[string]v3.Path, svcname, dir, env, pkg string) {
 	funcMap["contains"] = strings.Contains
 	funcMap["restyMethod"] = restyMethod
 	funcMap["toUpper"] = strings.ToUpper
-	tpl, _ := template.New("http.go.tmpl").Funcs(funcMap).Parse(httptmpl)
+	tpl, _ := template.New("http.go.tmpl").Funcs(funcMap).Parse(templateutils.Lookup("client/client.go.tmpl"))
+	methodSecurity, auth := securityOf(paths)
 	var sqlBuf bytes.Buffer
 	_ = tpl.Execute(&sqlBuf, struct {
-		Meta astutils.InterfaceMeta
-		Env  string
-		Pkg  string
+		Meta     astutils.InterfaceMeta
+		Env      string
+		Pkg      string
+		Security map[string][]securityItem
+		Auth     authKinds
 	}{
-		Meta: api2Interface(paths, svcname),
-		Env:  env,
-		Pkg:  pkg,
+		Meta:     api2Interface(paths, svcname),
+		Env:      env,
+		Pkg:      pkg,
+		Security: methodSecurity,
+		Auth:     auth,
 	})
 	source := strings.TrimSpace(sqlBuf.String())
 	astutils.FixImport([]byte(source), output)
+	return auth
+}
+
+// genGoAuth generates credentials and options setting them shared by all clients in package,
+// so that they are declared only once even if apis of several clients require authentication
+func genGoAuth(dir, pkg string, auth authKinds) {
+	output := filepath.Join(dir, "auth.go")
+	fi, err := fileutils.Stat(output)
+	if err != nil && !os.IsNotExist(err) {
+		panic(err)
+	}
+	if fi != nil {
+		logrus.Warningln("file auth.go will be overwrited")
+	}
+	tpl, _ := template.New("auth.go.tmpl").Parse(templateutils.Lookup("client/auth.go.tmpl"))
+	var buf bytes.Buffer
+	_ = tpl.Execute(&buf, struct {
+		Pkg  string
+		Auth authKinds
+	}{
+		Pkg:  pkg,
+		Auth: auth,
+	})
+	astutils.FixImport(bytes.TrimSpace(buf.Bytes()), output)
+}
+
+// or returns kinds of credentials required by either a or b
+func (a authKinds) or(b authKinds) authKinds {
+	return authKinds{
+		Bearer: a.Bearer || b.Bearer,
+		APIKey: a.APIKey || b.APIKey,
+		Basic:  a.Basic || b.Basic,
+	}
+}
+
+// securityItem represents how to inject a credential into request
+type securityItem struct {
+	// Kind is one of bearer, basic, header, query and cookie
+	Kind string
+	// Name is name of header, query parameter or cookie for api key
+	Name string
+}
+
+// authKinds represents which kinds of credentials are required by apis
+type authKinds struct {
+	Bearer bool
+	APIKey bool
+	Basic  bool
+}
+
+// securityOf resolves security requirements of each operation in paths keyed by generated method name.
+// Operation level security overrides global security. Both oauth2 and openIdConnect schemes are treated as bearer token.
+func securityOf(paths map[string]v3.Path) (map[string][]securityItem, authKinds) {
+	var auth authKinds
+	ret := make(map[string][]securityItem)
+	for endpoint, path := range paths {
+		operations := map[string]*v3.Operation{
+			"Get":    path.Get,
+			"Post":   path.Post,
+			"Put":    path.Put,
+			"Delete": path.Delete,
+		}
+		for httpMethod, operation := range operations {
+			if operation == nil {
+				continue
+			}
+			requirements := security
+			if operation.Security != nil {
+				requirements = operation.Security
+			}
+			var items []securityItem
+			for _, requirement := range requirements {
+				for name := range requirement {
+					scheme, exists := securitySchemes[name]
+					if !exists {
+						logrus.Warnf("security scheme %s not found in api %s %s\n", name, httpMethod, endpoint)
+						continue
+					}
+					var item securityItem
+					switch scheme.Type {
+					case v3.HTTPS:
+						if strings.ToLower(scheme.Scheme) == "basic" {
+							item.Kind = "basic"
+							auth.Basic = true
+						} else {
+							item.Kind = "bearer"
+							auth.Bearer = true
+						}
+					case v3.APIKeyS:
+						item.Kind = string(scheme.In)
+						item.Name = scheme.Name
+						auth.APIKey = true
+					default:
+						item.Kind = "bearer"
+						auth.Bearer = true
+					}
+					if !containsSecurityItem(items, item) {
+						items = append(items, item)
+					}
+				}
+			}
+			sort.SliceStable(items, func(i, j int) bool {
+				return items[i].Kind+items[i].Name < items[j].Kind+items[j].Name
+			})
+			if len(items) > 0 {
+				ret[httpMethod+toMethod(endpoint)] = items
+			}
+		}
+	}
+	return ret, auth
+}
+
+func containsSecurityItem(items []securityItem, item securityItem) bool {
+	for _, v := range items {
+		if v == item {
+			return true
+		}
+	}
+	return false
 }
 
 func api2Interface(paths map[string]v3.Path, svcname string) astutils.InterfaceMeta {
@@ -700,7 +936,7 @@ func toCamel(str string) string {
 }
 
 func genGoVo(schemas map[string]v3.Schema, output, pkg string) {
-	if err := os.MkdirAll(filepath.Dir(output), os.ModePerm); err != nil {
+	if err := fileutils.MkdirAll(filepath.Dir(output), os.ModePerm); err != nil {
 		panic(err)
 	}
 	funcMap := make(map[string]interface{})
@@ -708,7 +944,7 @@ func genGoVo(schemas map[string]v3.Schema, output, pkg string) {
 	funcMap["toGoType"] = toGoType
 	funcMap["toComment"] = toComment
 	funcMap["stringContains"] = sliceutils.StringContains
-	tpl, _ := template.New("vo.go.tmpl").Funcs(funcMap).Parse(votmpl)
+	tpl, _ := template.New("vo.go.tmpl").Funcs(funcMap).Parse(templateutils.Lookup("client/vo.go.tmpl"))
 	var sqlBuf bytes.Buffer
 	_ = tpl.Execute(&sqlBuf, struct {
 		Schemas map[string]v3.Schema
@@ -727,26 +963,30 @@ var schemas map[string]v3.Schema
 var requestBodies map[string]v3.RequestBody
 var responses map[string]v3.Response
 var omitempty bool
+var securitySchemes map[string]v3.SecurityScheme
+var security []v3.Security
 
 // GenGoClient generate go http client code from OpenAPI3.0 json document
 func GenGoClient(dir string, file string, omit bool, env, pkg string) {
 	var (
 		err       error
-		f         *os.File
+		f         fileutils.File
 		clientDir string
 		fi        os.FileInfo
 		api       v3.API
 		vofile    string
 	)
 	clientDir = filepath.Join(dir, pkg)
-	if err = os.MkdirAll(clientDir, os.ModePerm); err != nil {
+	if err = fileutils.MkdirAll(clientDir, os.ModePerm); err != nil {
 		panic(err)
 	}
 
-	api = loadAPI(file)
+	api = v3.LoadAPI(file)
 	schemas = api.Components.Schemas
 	requestBodies = api.Components.RequestBodies
 	responses = api.Components.Responses
+	securitySchemes = api.Components.SecuritySchemes
+	security = api.Security
 	omitempty = omit
 	svcmap := make(map[string]map[string]v3.Path)
 	for endpoint, path := range api.Paths {
@@ -759,59 +999,25 @@ func GenGoClient(dir string, file string, omit bool, env, pkg string) {
 		}
 	}
 
+	var auth authKinds
 	for svcname, paths := range svcmap {
-		genGoHTTP(paths, svcname, clientDir, env, pkg)
+		auth = auth.or(genGoHTTP(paths, svcname, clientDir, env, pkg))
+	}
+	if auth != (authKinds{}) {
+		genGoAuth(clientDir, pkg, auth)
 	}
 
 	vofile = filepath.Join(clientDir, "vo.go")
-	fi, err = os.Stat(vofile)
+	fi, err = fileutils.Stat(vofile)
 	if err != nil && !os.IsNotExist(err) {
 		panic(err)
 	}
 	if fi != nil {
 		logrus.Warningln("file vo.go will be overwrited")
 	}
-	if f, err = os.Create(vofile); err != nil {
+	if f, err = fileutils.Create(vofile); err != nil {
 		panic(err)
 	}
 	defer f.Close()
 	genGoVo(api.Components.Schemas, vofile, pkg)
 }
-
-func loadAPI(file string) v3.API {
-	var (
-		docfile *os.File
-		err     error
-		docraw  []byte
-		api     v3.API
-	)
-	if strings.HasPrefix(file, "http") {
-		link := file
-		client := resty.New()
-		client.SetRedirectPolicy(resty.FlexibleRedirectPolicy(15))
-		root, _ := os.Getwd()
-		client.SetOutputDirectory(root)
-		filename := ".openapi3"
-		_, err := client.R().
-			SetOutput(filename).
-			Get(link)
-		if err != nil {
-			panic(err)
-		}
-		file = filepath.Join(root, filename)
-		defer os.Remove(file)
-	}
-	if docfile, err = os.Open(file); err != nil {
-		panic(err)
-	}
-	defer func(docfile *os.File) {
-		_ = docfile.Close()
-	}(docfile)
-	if docraw, err = ioutil.ReadAll(docfile); err != nil {
-		panic(err)
-	}
-	if err = json.Unmarshal(docraw, &api); err != nil {
-		panic(err)
-	}
-	return api
-}
@@ -6,7 +6,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
 	"github.com/unionj-cloud/go-doudou/pathutils"
+	"io/ioutil"
 	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
 	"strings"
@@ -15,26 +17,26 @@ import (
 
 func Test_genGoVo(t *testing.T) {
 	testdir := pathutils.Abs("../testdata")
-	api := loadAPI(path.Join(testdir, "petstore3.json"))
+	api := v3.LoadAPI(path.Join(testdir, "petstore3.json"))
 	genGoVo(api.Components.Schemas, filepath.Join(testdir, "test", "vo.go"), "test")
 }
 
 func Test_genGoVo_clean(t *testing.T) {
 	testdir := pathutils.Abs("../testdata")
-	api := loadAPI(path.Join(testdir, "test5.json"))
+	api := v3.LoadAPI(path.Join(testdir, "test5.json"))
 	genGoVo(api.Components.Schemas, filepath.Join(testdir, "test", "vo.go"), "test")
 }
 
 func Test_genGoVo_Omit(t *testing.T) {
 	testdir := pathutils.Abs("../testdata")
-	api := loadAPI(path.Join(testdir, "petstore3.json"))
+	api := v3.LoadAPI(path.Join(testdir, "petstore3.json"))
 	omitempty = true
 	genGoVo(api.Components.Schemas, filepath.Join(testdir, "test", "vo.go"), "test")
 }
 
 func Test_genGoHttp(t *testing.T) {
 	testdir := pathutils.Abs("../testdata")
-	api := loadAPI(path.Join(testdir, "petstore3.json"))
+	api := v3.LoadAPI(path.Join(testdir, "petstore3.json"))
 	schemas = api.Components.Schemas
 	requestBodies = api.Components.RequestBodies
 	svcmap := make(map[string]map[string]v3.Path)
@@ -55,7 +57,7 @@ func Test_genGoHttp(t *testing.T) {
 
 func Test_genGoHttp1(t *testing.T) {
 	testdir := pathutils.Abs("../testdata")
-	api := loadAPI(path.Join(testdir, "test1.json"))
+	api := v3.LoadAPI(path.Join(testdir, "test1.json"))
 	schemas = api.Components.Schemas
 	requestBodies = api.Components.RequestBodies
 	svcmap := make(map[string]map[string]v3.Path)
@@ -76,7 +78,7 @@ func Test_genGoHttp1(t *testing.T) {
 
 func Test_genGoHttp2(t *testing.T) {
 	testdir := pathutils.Abs("../testdata")
-	api := loadAPI(path.Join(testdir, "test2.json"))
+	api := v3.LoadAPI(path.Join(testdir, "test2.json"))
 	schemas = api.Components.Schemas
 	requestBodies = api.Components.RequestBodies
 	svcmap := make(map[string]map[string]v3.Path)
@@ -97,7 +99,7 @@ func Test_genGoHttp2(t *testing.T) {
 
 func Test_genGoHttp3(t *testing.T) {
 	testdir := pathutils.Abs("../testdata")
-	api := loadAPI(path.Join(testdir, "test3.json"))
+	api := v3.LoadAPI(path.Join(testdir, "test3.json"))
 	schemas = api.Components.Schemas
 	requestBodies = api.Components.RequestBodies
 	svcmap := make(map[string]map[string]v3.Path)
@@ -117,7 +119,7 @@ func Test_genGoHttp3(t *testing.T) {
 }
 
 func Test_genGoHttp4(t *testing.T) {
-	api := loadAPI("https://petstore3.swagger.io/api/v3/openapi.json")
+	api := v3.LoadAPI("https://petstore3.swagger.io/api/v3/openapi.json")
 	schemas = api.Components.Schemas
 	requestBodies = api.Components.RequestBodies
 	svcmap := make(map[string]map[string]v3.Path)
@@ -138,7 +140,7 @@ func Test_genGoHttp4(t *testing.T) {
 
 func Test_genGoHttp5(t *testing.T) {
 	testdir := pathutils.Abs("../testdata")
-	api := loadAPI(path.Join(testdir, "test5.json"))
+	api := v3.LoadAPI(path.Join(testdir, "test5.json"))
 	schemas = api.Components.Schemas
 	requestBodies = api.Components.RequestBodies
 	svcmap := make(map[string]map[string]v3.Path)
@@ -159,19 +161,19 @@ func Test_genGoHttp5(t *testing.T) {
 
 func Test_loadApiPanic(t *testing.T) {
 	assert.Panics(t, func() {
-		loadAPI("notexists.json")
+		v3.LoadAPI("notexists.json")
 	})
 }
 
 func Test_loadApiJsonUnmarshalPanic(t *testing.T) {
 	assert.Panics(t, func() {
-		loadAPI("../testdata/test4.json")
+		v3.LoadAPI("../testdata/test4.json")
 	})
 }
 
 func Test_genGoHttp_Omit(t *testing.T) {
 	testdir := pathutils.Abs("../testdata")
-	api := loadAPI(path.Join(testdir, "petstore3.json"))
+	api := v3.LoadAPI(path.Join(testdir, "petstore3.json"))
 	omitempty = true
 	schemas = api.Components.Schemas
 	requestBodies = api.Components.RequestBodies
@@ -600,3 +602,102 @@ func Test_operation2Method(t *testing.T) {
 		})
 	}
 }
+
+func TestGenGoClient_Security(t *testing.T) {
+	dir := "../testdata/testsecurityclient"
+	defer func(path string) {
+		_ = os.RemoveAll(path)
+	}(dir)
+	GenGoClient(dir, "../testdata/petstore3.json", true, "", "client")
+	content, err := ioutil.ReadFile(filepath.Join(dir, "client", "auth.go"))
+	assert.NoError(t, err)
+	source := string(content)
+	assert.Contains(t, source, "func WithBearerToken(token string) ddhttp.DdClientOption")
+	assert.Contains(t, source, "func WithAPIKey(key string) ddhttp.DdClientOption")
+	assert.NotContains(t, source, "func WithBasicAuth")
+	for _, file := range []string{"petclient.go", "storeclient.go"} {
+		content, err = ioutil.ReadFile(filepath.Join(dir, "client", file))
+		assert.NoError(t, err)
+		source = string(content)
+		assert.NotContains(t, source, "func With")
+		assert.Contains(t, source, "\tcredentials\n")
+	}
+	assert.Contains(t, source, `_req.SetHeader("api_key", receiver.apiKey)`)
+	content, err = ioutil.ReadFile(filepath.Join(dir, "client", "userclient.go"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), "credentials")
+}
+
+func TestGenGoClient_Build(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	dir, err := ioutil.TempDir("", "testbuildclient")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	GenGoClient(dir, "../testdata/petstore3.json", true, "", "client")
+	// generated clients use go-doudou of this repository
+	gomod := fmt.Sprintf(`module testbuildclient
+
+go 1.17
+
+require (
+	github.com/google/go-querystring v1.2.0
+	github.com/unionj-cloud/go-doudou v0.0.0
+)
+
+replace github.com/unionj-cloud/go-doudou => %s
+`, pathutils.Abs("../../../.."))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), os.ModePerm))
+	cmd := exec.Command("go", "build", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOSUMDB=off")
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(output))
+}
+
+func Test_securityOf(t *testing.T) {
+	securitySchemes = map[string]v3.SecurityScheme{
+		"basicAuth": {
+			Type:   v3.HTTPS,
+			Scheme: "basic",
+		},
+		"apiKeyAuth": {
+			Type: v3.APIKeyS,
+			In:   v3.InQuery,
+			Name: "token",
+		},
+	}
+	security = []v3.Security{{"basicAuth": []string{}}}
+	defer func() {
+		securitySchemes = nil
+		security = nil
+	}()
+	methodSecurity, auth := securityOf(map[string]v3.Path{
+		"/user": {
+			Get: &v3.Operation{},
+			Post: &v3.Operation{
+				Security: []v3.Security{{"apiKeyAuth": []string{}}},
+			},
+			Delete: &v3.Operation{
+				Security: []v3.Security{},
+			},
+		},
+	})
+	assert.Equal(t, map[string][]securityItem{
+		"GetUser": {
+			{
+				Kind: "basic",
+			},
+		},
+		"PostUser": {
+			{
+				Kind: "query",
+				Name: "token",
+			},
+		},
+	}, methodSecurity)
+	assert.Equal(t, authKinds{APIKey: true, Basic: true}, auth)
+}
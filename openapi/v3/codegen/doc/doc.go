@@ -0,0 +1,438 @@
+package doc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+	"github.com/unionj-cloud/go-doudou/openapi/v3/mock"
+	"github.com/unionj-cloud/go-doudou/sliceutils"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Format represents output format of static documentation
+type Format string
+
+const (
+	// HTML self-contained html page
+	HTML Format = "html"
+	// Markdown markdown file
+	Markdown Format = "markdown"
+)
+
+type paramView struct {
+	Name        string
+	In          string
+	Type        string
+	Required    bool
+	Description string
+}
+
+type contentView struct {
+	ContentType string
+	Type        string
+	Example     string
+}
+
+type responseView struct {
+	Status      string
+	Description string
+	Contents    []contentView
+}
+
+type operationView struct {
+	Anchor      string
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Deprecated  bool
+	Security    []string
+	Parameters  []paramView
+	RequestBody []contentView
+	Responses   []responseView
+}
+
+type propertyView struct {
+	Name        string
+	Type        string
+	Required    bool
+	Description string
+}
+
+type schemaView struct {
+	Anchor      string
+	Name        string
+	Description string
+	Properties  []propertyView
+	Example     string
+}
+
+type securitySchemeView struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+type docView struct {
+	Title           string
+	Description     string
+	Version         string
+	SecuritySchemes []securitySchemeView
+	Operations      []operationView
+	Schemas         []schemaView
+}
+
+type builder struct {
+	schemas       map[string]v3.Schema
+	requestBodies map[string]v3.RequestBody
+	responses     map[string]v3.Response
+}
+
+var anchorReg = regexp.MustCompile(`[^a-z0-9]+`)
+
+func anchorOf(s string) string {
+	return strings.Trim(anchorReg.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// typeOf returns human-readable type name of schema
+func typeOf(schema *v3.Schema) string {
+	if schema == nil {
+		return ""
+	}
+	if stringutils.IsNotEmpty(schema.Ref) {
+		return strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+	}
+	switch {
+	case len(schema.AllOf) > 0:
+		return joinTypes(schema.AllOf, " & ")
+	case len(schema.OneOf) > 0:
+		return joinTypes(schema.OneOf, " | ")
+	case len(schema.AnyOf) > 0:
+		return joinTypes(schema.AnyOf, " | ")
+	}
+	switch schema.Type {
+	case v3.ArrayT:
+		return "[]" + typeOf(schema.Items)
+	case v3.ObjectT, "":
+		if ap, ok := schema.AdditionalProperties.(*v3.Schema); ok {
+			return "map[string]" + typeOf(ap)
+		}
+		if stringutils.IsNotEmpty(schema.Title) {
+			return schema.Title
+		}
+		return string(v3.ObjectT)
+	default:
+		if stringutils.IsNotEmpty(string(schema.Format)) {
+			return fmt.Sprintf("%s(%s)", schema.Type, schema.Format)
+		}
+		return string(schema.Type)
+	}
+}
+
+func joinTypes(schemas []*v3.Schema, sep string) string {
+	var types []string
+	for _, item := range schemas {
+		types = append(types, typeOf(item))
+	}
+	return strings.Join(types, sep)
+}
+
+func (b builder) example(explicit interface{}, schema *v3.Schema) string {
+	value := explicit
+	if value == nil {
+		value = mock.ValueOf(schema, b.schemas)
+	}
+	if value == nil {
+		return ""
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func (b builder) contents(content *v3.Content) []contentView {
+	if content == nil {
+		return nil
+	}
+	var ret []contentView
+	rv := reflect.ValueOf(content).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		mt, ok := rv.Field(i).Interface().(*v3.MediaType)
+		if !ok || mt == nil {
+			continue
+		}
+		view := contentView{
+			ContentType: strings.Split(rt.Field(i).Tag.Get("json"), ",")[0],
+			Type:        typeOf(mt.Schema),
+		}
+		if mt.Schema == nil || (mt.Schema.Type == v3.StringT && mt.Schema.Format == v3.BinaryF) {
+			ret = append(ret, view)
+			continue
+		}
+		view.Example = b.example(mt.Example, mt.Schema)
+		ret = append(ret, view)
+	}
+	return ret
+}
+
+func (b builder) responseViews(responses *v3.Responses) []responseView {
+	if responses == nil {
+		return nil
+	}
+	var ret []responseView
+	rv := reflect.ValueOf(responses).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		resp, ok := rv.Field(i).Interface().(*v3.Response)
+		if !ok || resp == nil {
+			continue
+		}
+		if stringutils.IsNotEmpty(resp.Ref) {
+			ref, exists := b.responses[strings.TrimPrefix(resp.Ref, "#/components/responses/")]
+			if !exists {
+				continue
+			}
+			resp = &ref
+		}
+		ret = append(ret, responseView{
+			Status:      strings.Split(rt.Field(i).Tag.Get("json"), ",")[0],
+			Description: resp.Description,
+			Contents:    b.contents(resp.Content),
+		})
+	}
+	return ret
+}
+
+func securityNames(requirements []v3.Security) []string {
+	var ret []string
+	for _, requirement := range requirements {
+		var names []string
+		for name := range requirement {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			ret = append(ret, "anonymous")
+			continue
+		}
+		ret = append(ret, strings.Join(names, " + "))
+	}
+	return ret
+}
+
+func (b builder) operationView(key string, op *v3.Operation, global []v3.Security) operationView {
+	splits := strings.SplitN(key, " ", 2)
+	view := operationView{
+		Anchor:      anchorOf(key),
+		Method:      splits[0],
+		Path:        splits[1],
+		Summary:     op.Summary,
+		Description: op.Description,
+		Deprecated:  op.Deprecated,
+		Responses:   b.responseViews(op.Responses),
+	}
+	requirements := global
+	if op.Security != nil {
+		requirements = op.Security
+	}
+	view.Security = securityNames(requirements)
+	for _, item := range op.Parameters {
+		view.Parameters = append(view.Parameters, paramView{
+			Name:        item.Name,
+			In:          string(item.In),
+			Type:        typeOf(item.Schema),
+			Required:    item.Required || item.In == v3.InPath,
+			Description: item.Description,
+		})
+	}
+	body := op.RequestBody
+	if body != nil && stringutils.IsNotEmpty(body.Ref) {
+		if ref, exists := b.requestBodies[strings.TrimPrefix(body.Ref, "#/components/requestBodies/")]; exists {
+			body = &ref
+		} else {
+			body = nil
+		}
+	}
+	if body != nil {
+		view.RequestBody = b.contents(body.Content)
+	}
+	return view
+}
+
+func (b builder) schemaView(name string, schema v3.Schema) schemaView {
+	view := schemaView{
+		Anchor:      "schema-" + anchorOf(name),
+		Name:        name,
+		Description: schema.Description,
+		Example:     b.example(schema.Example, &schema),
+	}
+	var props []string
+	for k := range schema.Properties {
+		props = append(props, k)
+	}
+	sort.Strings(props)
+	for _, k := range props {
+		view.Properties = append(view.Properties, propertyView{
+			Name:        k,
+			Type:        typeOf(schema.Properties[k]),
+			Required:    sliceutils.StringContains(schema.Required, k),
+			Description: schema.Properties[k].Description,
+		})
+	}
+	return view
+}
+
+func newDocView(api v3.API) docView {
+	var b builder
+	var view docView
+	if api.Components != nil {
+		b.schemas = api.Components.Schemas
+		b.requestBodies = api.Components.RequestBodies
+		b.responses = api.Components.Responses
+	}
+	if api.Info != nil {
+		view.Title = api.Info.Title
+		view.Description = api.Info.Description
+		view.Version = api.Info.Version
+	}
+	ops := v3.Operations(api)
+	var keys []string
+	for key := range ops {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		pi := keys[i][strings.Index(keys[i], " ")+1:]
+		pj := keys[j][strings.Index(keys[j], " ")+1:]
+		if pi != pj {
+			return pi < pj
+		}
+		return keys[i] < keys[j]
+	})
+	for _, key := range keys {
+		view.Operations = append(view.Operations, b.operationView(key, ops[key], api.Security))
+	}
+	var names []string
+	for name := range b.schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		view.Schemas = append(view.Schemas, b.schemaView(name, b.schemas[name]))
+	}
+	if api.Components != nil {
+		var schemeNames []string
+		for name := range api.Components.SecuritySchemes {
+			schemeNames = append(schemeNames, name)
+		}
+		sort.Strings(schemeNames)
+		for _, name := range schemeNames {
+			scheme := api.Components.SecuritySchemes[name]
+			typ := string(scheme.Type)
+			switch scheme.Type {
+			case v3.HTTPS:
+				typ = fmt.Sprintf("http %s", scheme.Scheme)
+			case v3.APIKeyS:
+				typ = fmt.Sprintf("apiKey in %s: %s", scheme.In, scheme.Name)
+			}
+			view.SecuritySchemes = append(view.SecuritySchemes, securitySchemeView{
+				Name:        name,
+				Type:        typ,
+				Description: scheme.Description,
+			})
+		}
+	}
+	return view
+}
+
+func init() {
+	templateutils.Register("doc/doc.md.tmpl", markdownTmpl, `Title, Description, Version string: info of OpenAPI 3.0 json document
+SecuritySchemes []securitySchemeView: Name, Type and Description of security schemes
+Operations []operationView: Anchor, Method, Path, Summary, Description string, Deprecated bool, Security []string, Parameters []paramView, RequestBody []contentView, Responses []responseView
+paramView: Name, In, Type, Description string, Required bool
+contentView: ContentType, Type, Example string
+responseView: Status, Description string, Contents []contentView
+Schemas []schemaView: Anchor, Name, Description, Example string, Properties []propertyView (Name, Type, Description string, Required bool)
+Functions: cell, toUpper`)
+	templateutils.Register("doc/doc.html.tmpl", htmlTmpl, `Title, Description, Version string: info of OpenAPI 3.0 json document
+SecuritySchemes []securitySchemeView: Name, Type and Description of security schemes
+Operations []operationView: Anchor, Method, Path, Summary, Description string, Deprecated bool, Security []string, Parameters []paramView, RequestBody []contentView, Responses []responseView
+paramView: Name, In, Type, Description string, Required bool
+contentView: ContentType, Type, Example string
+responseView: Status, Description string, Contents []contentView
+Schemas []schemaView: Anchor, Name, Description, Example string, Properties []propertyView (Name, Type, Description string, Required bool)
+Functions: toLower, lines
+Parsed by html/template package`)
+}
+
+// cell escapes text for using in markdown table cell
+func cell(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(strings.TrimSpace(s), "|", `\|`), "\n", "<br>")
+}
+
+// Render renders api as static documentation in format
+func Render(api v3.API, format Format) ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		err error
+	)
+	view := newDocView(api)
+	switch format {
+	case Markdown:
+		funcMap := template.FuncMap{
+			"cell":    cell,
+			"toUpper": strings.ToUpper,
+		}
+		var tpl *template.Template
+		if tpl, err = template.New("doc.md.tmpl").Funcs(funcMap).Parse(templateutils.Lookup("doc/doc.md.tmpl")); err != nil {
+			return nil, err
+		}
+		err = tpl.Execute(&buf, view)
+	case HTML:
+		funcMap := htmltemplate.FuncMap{
+			"toLower": strings.ToLower,
+			"lines": func(s string) []string {
+				return strings.Split(strings.TrimSpace(s), "\n")
+			},
+		}
+		var tpl *htmltemplate.Template
+		if tpl, err = htmltemplate.New("doc.html.tmpl").Funcs(funcMap).Parse(templateutils.Lookup("doc/doc.html.tmpl")); err != nil {
+			return nil, err
+		}
+		err = tpl.Execute(&buf, view)
+	default:
+		return nil, fmt.Errorf("not support format %s, only html and markdown are supported", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GenDoc renders OpenAPI3.0 json document file as static documentation, and writes it to output.
+func GenDoc(file string, format Format, output string) {
+	api := v3.LoadAPI(file)
+	data, err := Render(api, format)
+	if err != nil {
+		panic(err)
+	}
+	if err = fileutils.MkdirAll(filepath.Dir(output), os.ModePerm); err != nil {
+		panic(err)
+	}
+	if err = fileutils.WriteFile(output, data, os.ModePerm); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,56 @@
+package doc
+
+import (
+	"github.com/stretchr/testify/assert"
+	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+	"github.com/unionj-cloud/go-doudou/pathutils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func loadPetstore() v3.API {
+	return v3.LoadAPI(filepath.Join(pathutils.Abs("../testdata"), "petstore3.json"))
+}
+
+func TestRender_Markdown(t *testing.T) {
+	data, err := Render(loadPetstore(), Markdown)
+	assert.NoError(t, err)
+	md := string(data)
+	assert.Contains(t, md, "# Swagger Petstore - OpenAPI 3.0")
+	assert.Contains(t, md, `<a name="get-pet-petid"></a>`)
+	assert.Contains(t, md, "### GET /pet/{petId}")
+	assert.Contains(t, md, "| petId | path | integer(int64) | true | ID of pet to return |")
+	assert.Contains(t, md, "| 404 | Pet not found |  |  |")
+	assert.Contains(t, md, "**Security:** api_key or petstore_auth")
+	assert.Contains(t, md, "| api_key | apiKey in header: api_key |  |")
+	assert.Contains(t, md, `"name": "doggie"`)
+}
+
+func TestRender_HTML(t *testing.T) {
+	data, err := Render(loadPetstore(), HTML)
+	assert.NoError(t, err)
+	html := string(data)
+	assert.Contains(t, html, "<title>Swagger Petstore - OpenAPI 3.0</title>")
+	assert.Contains(t, html, `<section id="get-pet-petid">`)
+	assert.Contains(t, html, `<section id="schema-pet">`)
+	assert.Contains(t, html, "&#34;name&#34;: &#34;doggie&#34;")
+}
+
+func TestRender_UnknownFormat(t *testing.T) {
+	_, err := Render(loadPetstore(), Format("pdf"))
+	assert.Error(t, err)
+}
+
+func TestGenDoc(t *testing.T) {
+	dir, err := ioutil.TempDir("", "doc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "petstore.md")
+	assert.NotPanics(t, func() {
+		GenDoc(filepath.Join(pathutils.Abs("../testdata"), "petstore3.json"), Markdown, output)
+	})
+	_, err = os.Stat(output)
+	assert.NoError(t, err)
+}
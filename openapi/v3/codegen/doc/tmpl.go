@@ -0,0 +1,286 @@
+package doc
+
+var markdownTmpl = `# {{.Title}}
+{{- if .Description }}
+
+{{.Description}}
+{{- end }}
+{{- if .Version }}
+
+Version: {{.Version}}
+{{- end }}
+
+## Contents
+
+- [APIs](#apis)
+{{- range .Operations }}
+  - [{{.Method}} {{.Path}}](#{{.Anchor}})
+{{- end }}
+{{- if .Schemas }}
+- [Schemas](#schemas)
+{{- range .Schemas }}
+  - [{{.Name}}](#{{.Anchor}})
+{{- end }}
+{{- end }}
+{{- if .SecuritySchemes }}
+
+## Security Schemes
+
+| Name | Type | Description |
+| --- | --- | --- |
+{{- range .SecuritySchemes }}
+| {{.Name}} | {{cell .Type}} | {{cell .Description}} |
+{{- end }}
+{{- end }}
+
+## APIs
+{{- range .Operations }}
+
+<a name="{{.Anchor}}"></a>
+### {{.Method}} {{.Path}}
+{{- if .Deprecated }}
+
+> **Deprecated**
+{{- end }}
+{{- if .Summary }}
+
+{{.Summary}}
+{{- end }}
+{{- if .Description }}
+
+{{.Description}}
+{{- end }}
+{{- if .Security }}
+
+**Security:** {{range $i, $s := .Security}}{{if $i}} or {{end}}{{$s}}{{end}}
+{{- end }}
+{{- if .Parameters }}
+
+**Parameters**
+
+| Name | In | Type | Required | Description |
+| --- | --- | --- | --- | --- |
+{{- range .Parameters }}
+| {{.Name}} | {{.In}} | {{cell .Type}} | {{.Required}} | {{cell .Description}} |
+{{- end }}
+{{- end }}
+{{- if .RequestBody }}
+
+**Request Body**
+
+| Content-Type | Type |
+| --- | --- |
+{{- range .RequestBody }}
+| {{.ContentType}} | {{cell .Type}} |
+{{- end }}
+{{- range .RequestBody }}
+{{- if .Example }}
+
+{{.ContentType}} example:
+
+~~~json
+{{.Example}}
+~~~
+{{- end }}
+{{- end }}
+{{- end }}
+{{- if .Responses }}
+
+**Responses**
+
+| Status | Description | Content-Type | Type |
+| --- | --- | --- | --- |
+{{- range $r := .Responses }}
+{{- if $r.Contents }}
+{{- range $r.Contents }}
+| {{$r.Status}} | {{cell $r.Description}} | {{.ContentType}} | {{cell .Type}} |
+{{- end }}
+{{- else }}
+| {{$r.Status}} | {{cell $r.Description}} |  |  |
+{{- end }}
+{{- end }}
+{{- range $r := .Responses }}
+{{- range $r.Contents }}
+{{- if .Example }}
+
+{{$r.Status}} {{.ContentType}} example:
+
+~~~json
+{{.Example}}
+~~~
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- if .Schemas }}
+
+## Schemas
+{{- range .Schemas }}
+
+<a name="{{.Anchor}}"></a>
+### {{.Name}}
+{{- if .Description }}
+
+{{.Description}}
+{{- end }}
+{{- if .Properties }}
+
+| Property | Type | Required | Description |
+| --- | --- | --- | --- |
+{{- range .Properties }}
+| {{.Name}} | {{cell .Type}} | {{.Required}} | {{cell .Description}} |
+{{- end }}
+{{- end }}
+{{- if .Example }}
+
+Example:
+
+~~~json
+{{.Example}}
+~~~
+{{- end }}
+{{- end }}
+{{- end }}
+`
+
+var htmlTmpl = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+  body { margin: 0; font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; color: #24292f; }
+  nav { position: fixed; top: 0; bottom: 0; left: 0; width: 280px; overflow-y: auto; padding: 16px; box-sizing: border-box; background: #f6f8fa; border-right: 1px solid #d0d7de; font-size: 14px; }
+  nav ul { list-style: none; padding-left: 0; }
+  nav li { margin: 4px 0; word-break: break-all; }
+  nav a { color: #24292f; text-decoration: none; }
+  main { margin-left: 280px; padding: 16px 32px; max-width: 1100px; }
+  section { border-bottom: 1px solid #d0d7de; padding-bottom: 16px; }
+  table { border-collapse: collapse; margin: 8px 0; }
+  th, td { border: 1px solid #d0d7de; padding: 4px 10px; text-align: left; vertical-align: top; }
+  pre { background: #f6f8fa; padding: 12px; overflow-x: auto; }
+  .method { display: inline-block; min-width: 56px; padding: 2px 6px; border-radius: 4px; color: #fff; font-size: 12px; font-weight: bold; text-align: center; }
+  .get { background: #1f883d; }
+  .post { background: #0969da; }
+  .put { background: #9a6700; }
+  .delete { background: #cf222e; }
+  .deprecated { color: #cf222e; font-weight: bold; }
+</style>
+</head>
+<body>
+<nav>
+  <h3>{{.Title}}</h3>
+  <strong>APIs</strong>
+  <ul>
+    {{- range .Operations }}
+    <li><a href="#{{.Anchor}}"><span class="method {{toLower .Method}}">{{.Method}}</span> {{.Path}}</a></li>
+    {{- end }}
+  </ul>
+  {{- if .Schemas }}
+  <strong>Schemas</strong>
+  <ul>
+    {{- range .Schemas }}
+    <li><a href="#{{.Anchor}}">{{.Name}}</a></li>
+    {{- end }}
+  </ul>
+  {{- end }}
+</nav>
+<main>
+  <h1>{{.Title}}</h1>
+  {{- if .Description }}
+  {{- range lines .Description }}
+  <p>{{.}}</p>
+  {{- end }}
+  {{- end }}
+  {{- if .Version }}
+  <p>Version: {{.Version}}</p>
+  {{- end }}
+  {{- if .SecuritySchemes }}
+  <h2>Security Schemes</h2>
+  <table>
+    <tr><th>Name</th><th>Type</th><th>Description</th></tr>
+    {{- range .SecuritySchemes }}
+    <tr><td>{{.Name}}</td><td>{{.Type}}</td><td>{{.Description}}</td></tr>
+    {{- end }}
+  </table>
+  {{- end }}
+  <h2>APIs</h2>
+  {{- range .Operations }}
+  <section id="{{.Anchor}}">
+    <h3><span class="method {{toLower .Method}}">{{.Method}}</span> {{.Path}}</h3>
+    {{- if .Deprecated }}
+    <p class="deprecated">Deprecated</p>
+    {{- end }}
+    {{- if .Summary }}
+    <p>{{.Summary}}</p>
+    {{- end }}
+    {{- if .Description }}
+    {{- range lines .Description }}
+    <p>{{.}}</p>
+    {{- end }}
+    {{- end }}
+    {{- if .Security }}
+    <p><strong>Security:</strong> {{range $i, $s := .Security}}{{if $i}} or {{end}}<code>{{$s}}</code>{{end}}</p>
+    {{- end }}
+    {{- if .Parameters }}
+    <h4>Parameters</h4>
+    <table>
+      <tr><th>Name</th><th>In</th><th>Type</th><th>Required</th><th>Description</th></tr>
+      {{- range .Parameters }}
+      <tr><td>{{.Name}}</td><td>{{.In}}</td><td><code>{{.Type}}</code></td><td>{{.Required}}</td><td>{{.Description}}</td></tr>
+      {{- end }}
+    </table>
+    {{- end }}
+    {{- if .RequestBody }}
+    <h4>Request Body</h4>
+    {{- range .RequestBody }}
+    <p><code>{{.ContentType}}</code> <code>{{.Type}}</code></p>
+    {{- if .Example }}
+    <pre>{{.Example}}</pre>
+    {{- end }}
+    {{- end }}
+    {{- end }}
+    {{- if .Responses }}
+    <h4>Responses</h4>
+    {{- range $r := .Responses }}
+    <p><strong>{{$r.Status}}</strong> {{$r.Description}}</p>
+    {{- range $r.Contents }}
+    <p><code>{{.ContentType}}</code> <code>{{.Type}}</code></p>
+    {{- if .Example }}
+    <pre>{{.Example}}</pre>
+    {{- end }}
+    {{- end }}
+    {{- end }}
+    {{- end }}
+  </section>
+  {{- end }}
+  {{- if .Schemas }}
+  <h2>Schemas</h2>
+  {{- range .Schemas }}
+  <section id="{{.Anchor}}">
+    <h3>{{.Name}}</h3>
+    {{- if .Description }}
+    {{- range lines .Description }}
+    <p>{{.}}</p>
+    {{- end }}
+    {{- end }}
+    {{- if .Properties }}
+    <table>
+      <tr><th>Property</th><th>Type</th><th>Required</th><th>Description</th></tr>
+      {{- range .Properties }}
+      <tr><td>{{.Name}}</td><td><code>{{.Type}}</code></td><td>{{.Required}}</td><td>{{.Description}}</td></tr>
+      {{- end }}
+    </table>
+    {{- end }}
+    {{- if .Example }}
+    <pre>{{.Example}}</pre>
+    {{- end }}
+  </section>
+  {{- end }}
+  {{- end }}
+</main>
+</body>
+</html>
+`
@@ -69,7 +69,7 @@ func NewCustomer(opts ...ddhttp.DdClientOption) *CustomerClient {
 	}
 
 	svcClient.client.OnBeforeRequest(func(_ *resty.Client, request *resty.Request) error {
-		request.URL = svcClient.provider.SelectServer() + request.URL
+		request.URL = ddhttp.SelectServer(request.Context(), svcClient.provider) + request.URL
 		return nil
 	})
 
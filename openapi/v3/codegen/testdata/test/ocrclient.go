@@ -147,7 +147,7 @@ func NewOcr(opts ...ddhttp.DdClientOption) *OcrClient {
 	}
 
 	svcClient.client.OnBeforeRequest(func(_ *resty.Client, request *resty.Request) error {
-		request.URL = svcClient.provider.SelectServer() + request.URL
+		request.URL = ddhttp.SelectServer(request.Context(), svcClient.provider) + request.URL
 		return nil
 	})
 
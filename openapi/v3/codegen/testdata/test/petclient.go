@@ -215,7 +215,7 @@ func NewPet(opts ...ddhttp.DdClientOption) *PetClient {
 	}
 
 	svcClient.client.OnBeforeRequest(func(_ *resty.Client, request *resty.Request) error {
-		request.URL = svcClient.provider.SelectServer() + request.URL
+		request.URL = ddhttp.SelectServer(request.Context(), svcClient.provider) + request.URL
 		return nil
 	})
 
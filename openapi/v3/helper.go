@@ -2,10 +2,15 @@ package v3
 
 import (
 	"encoding/json"
+	"github.com/go-resty/resty/v2"
 	"github.com/unionj-cloud/go-doudou/astutils"
 	"github.com/unionj-cloud/go-doudou/copier"
 	"github.com/unionj-cloud/go-doudou/sliceutils"
 	"github.com/unionj-cloud/go-doudou/stringutils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
 	"unicode"
@@ -143,3 +148,63 @@ func IsBuiltin(field astutils.FieldMeta) bool {
 	}
 	return sliceutils.Contains(simples, pschema) || (pschema.Type == ArrayT && sliceutils.Contains(simples, pschema.Items))
 }
+
+// LoadAPI loads OpenAPI3.0 json document from local file path or download link
+func LoadAPI(file string) API {
+	var (
+		docfile *os.File
+		err     error
+		docraw  []byte
+		api     API
+	)
+	if strings.HasPrefix(file, "http") {
+		link := file
+		client := resty.New()
+		client.SetRedirectPolicy(resty.FlexibleRedirectPolicy(15))
+		root, _ := os.Getwd()
+		client.SetOutputDirectory(root)
+		filename := ".openapi3"
+		_, err := client.R().
+			SetOutput(filename).
+			Get(link)
+		if err != nil {
+			panic(err)
+		}
+		file = filepath.Join(root, filename)
+		defer os.Remove(file)
+	}
+	if docfile, err = os.Open(file); err != nil {
+		panic(err)
+	}
+	defer func(docfile *os.File) {
+		_ = docfile.Close()
+	}(docfile)
+	if docraw, err = ioutil.ReadAll(docfile); err != nil {
+		panic(err)
+	}
+	if err = json.Unmarshal(docraw, &api); err != nil {
+		panic(err)
+	}
+	return api
+}
+
+// Operations returns all operations from api keyed by http method and endpoint such as "GET /users/{id}"
+func Operations(api API) map[string]*Operation {
+	ops := make(map[string]*Operation)
+	for endpoint, path := range api.Paths {
+		pv := reflect.ValueOf(path)
+		for _, method := range []string{"Get", "Post", "Put", "Delete", "Patch", "Head", "Options", "Trace"} {
+			op, ok := pv.FieldByName(method).Interface().(*Operation)
+			if !ok || op == nil {
+				continue
+			}
+			if len(path.Parameters) > 0 {
+				merged := *op
+				merged.Parameters = append(append([]Parameter{}, path.Parameters...), op.Parameters...)
+				op = &merged
+			}
+			ops[strings.ToUpper(method)+" "+endpoint] = op
+		}
+	}
+	return ops
+}
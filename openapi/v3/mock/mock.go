@@ -0,0 +1,202 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/iancoleman/strcase"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/cast"
+	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/http/model"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxDepth limits recursion when synthesizing data from self-referencing schemas
+const maxDepth = 5
+
+// ValueOf returns example value of schema. If there is no example, default or enum value,
+// data will be synthesized from type and format of schema.
+// $ref will be resolved from schemas.
+func ValueOf(schema *v3.Schema, schemas map[string]v3.Schema) interface{} {
+	return valueOf(schema, schemas, 0)
+}
+
+func valueOf(schema *v3.Schema, schemas map[string]v3.Schema, depth int) interface{} {
+	if schema == nil || depth > maxDepth {
+		return nil
+	}
+	if stringutils.IsNotEmpty(schema.Ref) {
+		ref, exists := schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+		if !exists {
+			return nil
+		}
+		return valueOf(&ref, schemas, depth+1)
+	}
+	if schema.Example != nil {
+		return schema.Example
+	}
+	if schema.Default != nil {
+		return schema.Default
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+	if len(schema.AllOf) > 0 {
+		merged := make(map[string]interface{})
+		for _, item := range schema.AllOf {
+			if obj, ok := valueOf(item, schemas, depth+1).(map[string]interface{}); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	if len(schema.OneOf) > 0 {
+		return valueOf(schema.OneOf[0], schemas, depth+1)
+	}
+	if len(schema.AnyOf) > 0 {
+		return valueOf(schema.AnyOf[0], schemas, depth+1)
+	}
+	switch schema.Type {
+	case v3.IntegerT:
+		if schema.Minimum != nil {
+			return cast.ToInt64(schema.Minimum)
+		}
+		return 0
+	case v3.NumberT:
+		if schema.Minimum != nil {
+			return cast.ToFloat64(schema.Minimum)
+		}
+		return 0.0
+	case v3.BooleanT:
+		return true
+	case v3.StringT:
+		return stringOf(schema)
+	case v3.ArrayT:
+		if item := valueOf(schema.Items, schemas, depth+1); item != nil {
+			return []interface{}{item}
+		}
+		return []interface{}{}
+	default:
+		obj := make(map[string]interface{})
+		for k, v := range schema.Properties {
+			obj[k] = valueOf(v, schemas, depth+1)
+		}
+		if ap, ok := schema.AdditionalProperties.(*v3.Schema); ok && len(obj) == 0 {
+			obj["key"] = valueOf(ap, schemas, depth+1)
+		}
+		return obj
+	}
+}
+
+func stringOf(schema *v3.Schema) string {
+	switch schema.Format {
+	case v3.DateTimeF:
+		return time.Now().Format(time.RFC3339)
+	case v3.BinaryF:
+		return ""
+	}
+	s := "string"
+	if stringutils.IsNotEmpty(schema.Title) {
+		s = schema.Title
+	}
+	for len([]rune(s)) < schema.MinLength {
+		s += s
+	}
+	if schema.MaxLength > 0 && len([]rune(s)) > schema.MaxLength {
+		s = string([]rune(s)[:schema.MaxLength])
+	}
+	return s
+}
+
+// ServiceName returns service name of the mock server normalized from title of api the same way as generated services,
+// e.g. "Swagger Petstore - OpenAPI 3.0" becomes "swaggerpetstoreopenapi30", so it is valid in environment variables and routes
+func ServiceName(api v3.API) string {
+	if api.Info == nil {
+		return ""
+	}
+	return strings.ToLower(strcase.ToCamel(api.Info.Title))
+}
+
+// Routes returns routes serving every path in api for DefaultHttpSrv.
+// Each route validates parameters and request body, then responds with example or synthesized data.
+func Routes(api v3.API) []model.Route {
+	var schemas map[string]v3.Schema
+	if api.Components != nil {
+		schemas = api.Components.Schemas
+	}
+	validator := v3.NewValidator(api)
+	ops := v3.Operations(api)
+	keys := make([]string, 0, len(ops))
+	for key := range ops {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var routes []model.Route
+	for _, key := range keys {
+		op := ops[key]
+		splits := strings.SplitN(key, " ", 2)
+		name := op.OperationID
+		if stringutils.IsEmpty(name) {
+			name = key
+		}
+		routes = append(routes, model.Route{
+			Name:        name,
+			Method:      splits[0],
+			Pattern:     splits[1],
+			HandlerFunc: handler(validator, op, schemas),
+		})
+	}
+	return routes
+}
+
+func handler(validator *v3.Validator, op *v3.Operation, schemas map[string]v3.Schema) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		errs := validator.ValidateParams(op, mux.Vars(r), r.URL.Query(), r.Header)
+		errs = append(errs, validator.ValidateRequestBody(op, r)...)
+		if len(errs) > 0 {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(struct {
+				Errors v3.ValidationErrors `json:"errors"`
+			}{
+				Errors: errs,
+			})
+			return
+		}
+		resp := validator.Response200(op)
+		if resp == nil || resp.Content == nil {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		content := resp.Content
+		switch {
+		case content.JSON != nil:
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			var data interface{}
+			if content.JSON.Example != nil {
+				data = content.JSON.Example
+			} else {
+				data = ValueOf(content.JSON.Schema, schemas)
+			}
+			if err := json.NewEncoder(w).Encode(data); err != nil {
+				logrus.Errorln(err)
+			}
+		case content.Stream != nil:
+			w.Header().Set("Content-Disposition", "attachment; filename=mock.txt")
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write([]byte("mock file content"))
+		case content.TextPlain != nil:
+			w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+			_, _ = w.Write([]byte(fmt.Sprint(ValueOf(content.TextPlain.Schema, schemas))))
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package mock
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+	"github.com/unionj-cloud/go-doudou/pathutils"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newRouter(t *testing.T) *mux.Router {
+	api := v3.LoadAPI(filepath.Join(pathutils.Abs("../codegen/testdata"), "petstore3.json"))
+	router := mux.NewRouter()
+	routes := Routes(api)
+	if len(routes) == 0 {
+		t.Fatal("no route found")
+	}
+	for _, item := range routes {
+		router.Methods(item.Method).Path(item.Pattern).Name(item.Name).Handler(item.HandlerFunc)
+	}
+	return router
+}
+
+func TestRoutes(t *testing.T) {
+	router := newRouter(t)
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{
+			name:   "path variable",
+			method: http.MethodGet,
+			target: "/pet/10",
+			want:   http.StatusOK,
+		},
+		{
+			name:   "invalid path variable",
+			method: http.MethodGet,
+			target: "/pet/abc",
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:   "invalid enum query parameter",
+			method: http.MethodGet,
+			target: "/pet/findByStatus?status=unknown",
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:   "valid json body",
+			method: http.MethodPost,
+			target: "/pet",
+			body:   `{"name":"doggie","photoUrls":["http://localhost/1.png"]}`,
+			want:   http.StatusOK,
+		},
+		{
+			name:   "missing required property",
+			method: http.MethodPost,
+			target: "/pet",
+			body:   `{"name":"doggie"}`,
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:   "wrong property type",
+			method: http.MethodPost,
+			target: "/pet",
+			body:   `{"id":"10","name":"doggie","photoUrls":[]}`,
+			want:   http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestRoutes_Example(t *testing.T) {
+	router := newRouter(t)
+	req := httptest.NewRequest(http.MethodGet, "/pet/10", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	var pet map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &pet))
+	assert.Equal(t, "doggie", pet["name"])
+	assert.Equal(t, "available", pet["status"])
+}
+
+func TestValueOf(t *testing.T) {
+	schemas := map[string]v3.Schema{
+		"Node": {
+			Type: v3.ObjectT,
+			Properties: map[string]*v3.Schema{
+				"name": v3.String,
+				"next": {
+					Ref: "#/components/schemas/Node",
+				},
+			},
+		},
+	}
+	assert.NotPanics(t, func() {
+		ValueOf(&v3.Schema{Ref: "#/components/schemas/Node"}, schemas)
+	})
+	assert.Equal(t, "string", ValueOf(v3.String, nil))
+	assert.Equal(t, []interface{}{0}, ValueOf(&v3.Schema{Type: v3.ArrayT, Items: v3.Int}, nil))
+}
+
+func TestRoutes_Methods(t *testing.T) {
+	op := func(id string) *v3.Operation {
+		return &v3.Operation{
+			OperationID: id,
+			Responses: &v3.Responses{
+				Resp200: &v3.Response{
+					Content: &v3.Content{
+						JSON: &v3.MediaType{Schema: v3.String},
+					},
+				},
+			},
+		}
+	}
+	api := v3.API{
+		Paths: map[string]v3.Path{
+			"/user": {
+				Get:     op("GetUser"),
+				Post:    op("PostUser"),
+				Put:     op("PutUser"),
+				Delete:  op("DeleteUser"),
+				Patch:   op("PatchUser"),
+				Head:    op("HeadUser"),
+				Options: op("OptionsUser"),
+				Trace:   op("TraceUser"),
+			},
+		},
+	}
+	var got []string
+	for _, route := range Routes(api) {
+		got = append(got, route.Method+" "+route.Name)
+	}
+	assert.Equal(t, []string{
+		"DELETE DeleteUser",
+		"GET GetUser",
+		"HEAD HeadUser",
+		"OPTIONS OptionsUser",
+		"PATCH PatchUser",
+		"POST PostUser",
+		"PUT PutUser",
+		"TRACE TraceUser",
+	}, got)
+}
+
+func TestServiceName(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"usersvc", "usersvc"},
+		{"Swagger Petstore - OpenAPI 3.0", "swaggerpetstoreopenapi30"},
+		{"user's svc (v2)!", "userssvcv2"},
+		{"pet-store.api", "petstoreapi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			assert.Equal(t, tt.want, ServiceName(v3.API{Info: &v3.Info{Title: tt.title}}))
+		})
+	}
+	assert.Equal(t, "", ServiceName(v3.API{}))
+}
@@ -150,10 +150,8 @@ type Callback struct {
 	// TODO
 }
 
-// Security not implemented yet
-type Security struct {
-	// TODO
-}
+// Security https://spec.openapis.org/oas/v3.0.3#security-requirement-object
+type Security map[string][]string
 
 // Operation https://spec.openapis.org/oas/v3.0.3#operation-object
 type Operation struct {
@@ -173,17 +171,58 @@ type Operation struct {
 
 // Path https://spec.openapis.org/oas/v3.0.3#path-item-object
 type Path struct {
-	Get    *Operation `json:"get,omitempty"`
-	Post   *Operation `json:"post,omitempty"`
-	Put    *Operation `json:"put,omitempty"`
-	Delete *Operation `json:"delete,omitempty"`
+	Get     *Operation `json:"get,omitempty"`
+	Post    *Operation `json:"post,omitempty"`
+	Put     *Operation `json:"put,omitempty"`
+	Delete  *Operation `json:"delete,omitempty"`
+	Patch   *Operation `json:"patch,omitempty"`
+	Head    *Operation `json:"head,omitempty"`
+	Options *Operation `json:"options,omitempty"`
+	Trace   *Operation `json:"trace,omitempty"`
 	// TODO
 	Parameters []Parameter `json:"parameters,omitempty"`
 }
 
+// SecuritySchemeType represents type of security scheme
+type SecuritySchemeType string
+
+const (
+	// APIKeyS apiKey
+	APIKeyS SecuritySchemeType = "apiKey"
+	// HTTPS http
+	HTTPS SecuritySchemeType = "http"
+	// OAuth2S oauth2
+	OAuth2S SecuritySchemeType = "oauth2"
+	// OpenIDConnectS openIdConnect
+	OpenIDConnectS SecuritySchemeType = "openIdConnect"
+)
+
+// OAuthFlow https://spec.openapis.org/oas/v3.0.3#oauth-flow-object
+type OAuthFlow struct {
+	AuthorizationURL string            `json:"authorizationUrl,omitempty"`
+	TokenURL         string            `json:"tokenUrl,omitempty"`
+	RefreshURL       string            `json:"refreshUrl,omitempty"`
+	Scopes           map[string]string `json:"scopes"`
+}
+
+// OAuthFlows https://spec.openapis.org/oas/v3.0.3#oauth-flows-object
+type OAuthFlows struct {
+	Implicit          *OAuthFlow `json:"implicit,omitempty"`
+	Password          *OAuthFlow `json:"password,omitempty"`
+	ClientCredentials *OAuthFlow `json:"clientCredentials,omitempty"`
+	AuthorizationCode *OAuthFlow `json:"authorizationCode,omitempty"`
+}
+
 // SecurityScheme https://spec.openapis.org/oas/v3.0.3#security-scheme-object
 type SecurityScheme struct {
-	// TODO
+	Type             SecuritySchemeType `json:"type,omitempty"`
+	Description      string             `json:"description,omitempty"`
+	Name             string             `json:"name,omitempty"`
+	In               In                 `json:"in,omitempty"`
+	Scheme           string             `json:"scheme,omitempty"`
+	BearerFormat     string             `json:"bearerFormat,omitempty"`
+	Flows            *OAuthFlows        `json:"flows,omitempty"`
+	OpenIDConnectURL string             `json:"openIdConnectUrl,omitempty"`
 }
 
 // Discriminator https://spec.openapis.org/oas/v3.0.3#discriminator-object
@@ -233,8 +272,7 @@ type Components struct {
 	// TODO
 	Examples map[string]Example `json:"examples,omitempty"`
 	// TODO
-	Headers map[string]Header `json:"headers,omitempty"`
-	// TODO
+	Headers         map[string]Header         `json:"headers,omitempty"`
 	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
 	// TODO
 	Links map[string]Link `json:"links,omitempty"`
@@ -250,6 +288,7 @@ type API struct {
 	Tags         []Tag           `json:"tags,omitempty"`
 	Paths        map[string]Path `json:"paths,omitempty"`
 	Components   *Components     `json:"components,omitempty"`
+	Security     []Security      `json:"security,omitempty"`
 	ExternalDocs *ExternalDocs   `json:"externalDocs,omitempty"`
 }
 
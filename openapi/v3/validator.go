@@ -0,0 +1,451 @@
+package v3
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/unionj-cloud/cast"
+	"github.com/unionj-cloud/go-doudou/sliceutils"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationError describes one violation of OpenAPI3.0 schema
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error implements error interface
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors wraps all violations found in one validation
+type ValidationErrors []ValidationError
+
+// Error implements error interface
+func (e ValidationErrors) Error() string {
+	var msgs []string
+	for _, item := range e {
+		msgs = append(msgs, item.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validator validates parameters, request bodies and responses against schemas from an OpenAPI3.0 document
+type Validator struct {
+	schemas       map[string]Schema
+	requestBodies map[string]RequestBody
+	responses     map[string]Response
+}
+
+// NewValidator creates a Validator instance from api
+func NewValidator(api API) *Validator {
+	v := &Validator{}
+	if api.Components != nil {
+		v.schemas = api.Components.Schemas
+		v.requestBodies = api.Components.RequestBodies
+		v.responses = api.Components.Responses
+	}
+	return v
+}
+
+// Resolve returns the schema which schema.Ref points to, or schema itself if Ref is empty
+func (v *Validator) Resolve(schema *Schema) *Schema {
+	for schema != nil && stringutils.IsNotEmpty(schema.Ref) {
+		ref, exists := v.schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+		if !exists {
+			return nil
+		}
+		schema = &ref
+	}
+	return schema
+}
+
+// RequestBody returns the request body of op with $ref resolved
+func (v *Validator) RequestBody(op *Operation) *RequestBody {
+	body := op.RequestBody
+	if body != nil && stringutils.IsNotEmpty(body.Ref) {
+		if ref, exists := v.requestBodies[strings.TrimPrefix(body.Ref, "#/components/requestBodies/")]; exists {
+			return &ref
+		}
+		return nil
+	}
+	return body
+}
+
+// Response200 returns the 200 response of op with $ref resolved
+func (v *Validator) Response200(op *Operation) *Response {
+	if op.Responses == nil || op.Responses.Resp200 == nil {
+		return nil
+	}
+	resp := op.Responses.Resp200
+	if stringutils.IsNotEmpty(resp.Ref) {
+		if ref, exists := v.responses[strings.TrimPrefix(resp.Ref, "#/components/responses/")]; exists {
+			return &ref
+		}
+		return nil
+	}
+	return resp
+}
+
+// ValidateValue checks value decoded from json by a decoder with UseNumber enabled against schema.
+// Nil value is always valid as go-doudou encodes nil slices, maps and pointers as null.
+func (v *Validator) ValidateValue(field string, schema *Schema, value interface{}) ValidationErrors {
+	schema = v.Resolve(schema)
+	if schema == nil || value == nil {
+		return nil
+	}
+	var errs ValidationErrors
+	for _, item := range schema.AllOf {
+		errs = append(errs, v.ValidateValue(field, item, value)...)
+	}
+	if len(schema.OneOf) > 0 && !v.matchAny(field, schema.OneOf, value) {
+		errs = append(errs, ValidationError{field, "does not match any schema in oneOf"})
+	}
+	if len(schema.AnyOf) > 0 && !v.matchAny(field, schema.AnyOf, value) {
+		errs = append(errs, ValidationError{field, "does not match any schema in anyOf"})
+	}
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		errs = append(errs, ValidationError{field, fmt.Sprintf("must be one of %v", schema.Enum)})
+	}
+	typ := schema.Type
+	if stringutils.IsEmpty(string(typ)) && len(schema.Properties) > 0 {
+		typ = ObjectT
+	}
+	switch typ {
+	case IntegerT, NumberT:
+		errs = append(errs, validateNumber(field, schema, typ, value)...)
+	case StringT:
+		errs = append(errs, validateString(field, schema, value)...)
+	case BooleanT:
+		if _, ok := value.(bool); !ok {
+			errs = append(errs, ValidationError{field, "must be a boolean"})
+		}
+	case ArrayT:
+		items, ok := value.([]interface{})
+		if !ok {
+			errs = append(errs, ValidationError{field, "must be an array"})
+			break
+		}
+		for i, item := range items {
+			errs = append(errs, v.ValidateValue(fmt.Sprintf("%s[%d]", field, i), schema.Items, item)...)
+		}
+	case ObjectT:
+		errs = append(errs, v.validateObject(field, schema, value)...)
+	}
+	return errs
+}
+
+func (v *Validator) matchAny(field string, schemas []*Schema, value interface{}) bool {
+	for _, item := range schemas {
+		if len(v.ValidateValue(field, item, value)) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Validator) validateObject(field string, schema *Schema, value interface{}) ValidationErrors {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return ValidationErrors{{field, "must be an object"}}
+	}
+	var errs ValidationErrors
+	for _, key := range schema.Required {
+		if _, exists := obj[key]; !exists {
+			errs = append(errs, ValidationError{join(field, key), "is required"})
+		}
+	}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if prop, exists := schema.Properties[key]; exists {
+			errs = append(errs, v.ValidateValue(join(field, key), prop, obj[key])...)
+			continue
+		}
+		if ap, ok := schema.AdditionalProperties.(*Schema); ok {
+			errs = append(errs, v.ValidateValue(join(field, key), ap, obj[key])...)
+		}
+	}
+	return errs
+}
+
+func join(field, key string) string {
+	if stringutils.IsEmpty(field) {
+		return key
+	}
+	return field + "." + key
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, item := range enum {
+		if fmt.Sprint(item) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func toFloat64(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int, int64, int32:
+		return cast.ToFloat64(n), true
+	}
+	return 0, false
+}
+
+func validateNumber(field string, schema *Schema, typ Type, value interface{}) ValidationErrors {
+	f, ok := toFloat64(value)
+	if !ok {
+		return ValidationErrors{{field, fmt.Sprintf("must be %s", typ)}}
+	}
+	if typ == IntegerT && f != float64(int64(f)) {
+		return ValidationErrors{{field, "must be integer"}}
+	}
+	var errs ValidationErrors
+	if schema.Minimum != nil {
+		if min, err := cast.ToFloat64E(schema.Minimum); err == nil {
+			exclusive, _ := schema.ExclusiveMinimum.(bool)
+			if f < min || (exclusive && f == min) {
+				errs = append(errs, ValidationError{field, fmt.Sprintf("must not be less than %v", schema.Minimum)})
+			}
+		}
+	}
+	if schema.Maximum != nil {
+		if max, err := cast.ToFloat64E(schema.Maximum); err == nil {
+			exclusive, _ := schema.ExclusiveMaximum.(bool)
+			if f > max || (exclusive && f == max) {
+				errs = append(errs, ValidationError{field, fmt.Sprintf("must not be greater than %v", schema.Maximum)})
+			}
+		}
+	}
+	return errs
+}
+
+func validateString(field string, schema *Schema, value interface{}) ValidationErrors {
+	s, ok := value.(string)
+	if !ok {
+		return ValidationErrors{{field, "must be a string"}}
+	}
+	var errs ValidationErrors
+	length := utf8.RuneCountInString(s)
+	if schema.MinLength > 0 && length < schema.MinLength {
+		errs = append(errs, ValidationError{field, fmt.Sprintf("length must not be less than %d", schema.MinLength)})
+	}
+	if schema.MaxLength > 0 && length > schema.MaxLength {
+		errs = append(errs, ValidationError{field, fmt.Sprintf("length must not be greater than %d", schema.MaxLength)})
+	}
+	if pattern, ok := schema.Pattern.(string); ok && stringutils.IsNotEmpty(pattern) {
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(s) {
+			errs = append(errs, ValidationError{field, fmt.Sprintf("must match pattern %s", pattern)})
+		}
+	}
+	if schema.Format == DateTimeF {
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			errs = append(errs, ValidationError{field, "must be a date-time in RFC3339 format"})
+		}
+	}
+	return errs
+}
+
+// coerce converts raw string from query string or form to the go value json decoder would produce
+func (v *Validator) coerce(schema *Schema, raw []string) (interface{}, error) {
+	schema = v.Resolve(schema)
+	if schema == nil || len(raw) == 0 {
+		return nil, nil
+	}
+	switch schema.Type {
+	case ArrayT:
+		var items []interface{}
+		for _, item := range raw {
+			value, err := v.coerce(schema.Items, []string{item})
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		return items, nil
+	case IntegerT, NumberT:
+		if _, err := strconv.ParseFloat(raw[0], 64); err != nil {
+			return nil, fmt.Errorf("must be %s", schema.Type)
+		}
+		return json.Number(raw[0]), nil
+	case BooleanT:
+		b, err := strconv.ParseBool(raw[0])
+		if err != nil {
+			return nil, fmt.Errorf("must be a boolean")
+		}
+		return b, nil
+	default:
+		return raw[0], nil
+	}
+}
+
+func (v *Validator) validateRaw(field string, schema *Schema, raw []string, required bool) ValidationErrors {
+	if len(raw) == 0 {
+		if required {
+			return ValidationErrors{{field, "is required"}}
+		}
+		return nil
+	}
+	value, err := v.coerce(schema, raw)
+	if err != nil {
+		return ValidationErrors{{field, err.Error()}}
+	}
+	return v.ValidateValue(field, schema, value)
+}
+
+func formValues(values url.Values, name string) []string {
+	if raw, exists := values[name]; exists {
+		return raw
+	}
+	return values[name+"[]"]
+}
+
+// ValidateParams checks query, path and header parameters of op
+func (v *Validator) ValidateParams(op *Operation, pathVars map[string]string, query url.Values, header http.Header) ValidationErrors {
+	var errs ValidationErrors
+	for _, param := range op.Parameters {
+		var raw []string
+		switch param.In {
+		case InQuery:
+			raw = formValues(query, param.Name)
+		case InPath:
+			if value, exists := pathVars[param.Name]; exists {
+				raw = []string{value}
+			}
+		case InHeader:
+			raw = header.Values(param.Name)
+		default:
+			continue
+		}
+		errs = append(errs, v.validateRaw(param.Name, param.Schema, raw, param.Required || param.In == InPath)...)
+	}
+	return errs
+}
+
+// ValidateRequestBody checks request body of r against op. Body of r is restored after reading,
+// so handlers can read it again.
+func (v *Validator) ValidateRequestBody(op *Operation, r *http.Request) ValidationErrors {
+	body := v.RequestBody(op)
+	if body == nil || body.Content == nil {
+		return nil
+	}
+	contentType := r.Header.Get("Content-Type")
+	content := body.Content
+	switch {
+	case content.JSON != nil && (strings.Contains(contentType, "application/json") || stringutils.IsEmpty(contentType)):
+		raw, err := readBody(r)
+		if err != nil {
+			return ValidationErrors{{"body", err.Error()}}
+		}
+		if len(bytes.TrimSpace(raw)) == 0 {
+			if body.Required {
+				return ValidationErrors{{"body", "is required"}}
+			}
+			return nil
+		}
+		var value interface{}
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err = decoder.Decode(&value); err != nil {
+			return ValidationErrors{{"body", "must be valid json"}}
+		}
+		return v.ValidateValue("", content.JSON.Schema, value)
+	case content.FormURL != nil && strings.Contains(contentType, "application/x-www-form-urlencoded"):
+		raw, err := readBody(r)
+		if err != nil {
+			return ValidationErrors{{"body", err.Error()}}
+		}
+		values, err := url.ParseQuery(string(raw))
+		if err != nil {
+			return ValidationErrors{{"body", err.Error()}}
+		}
+		return v.validateForm(content.FormURL.Schema, values, nil)
+	case content.FormData != nil && strings.Contains(contentType, "multipart/form-data"):
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return ValidationErrors{{"body", err.Error()}}
+		}
+		return v.validateForm(content.FormData.Schema, r.MultipartForm.Value, r.MultipartForm.File)
+	case body.Required && r.ContentLength == 0:
+		return ValidationErrors{{"body", "is required"}}
+	}
+	return nil
+}
+
+func (v *Validator) validateForm(schema *Schema, values url.Values, files map[string][]*multipart.FileHeader) ValidationErrors {
+	schema = v.Resolve(schema)
+	if schema == nil {
+		return nil
+	}
+	var errs ValidationErrors
+	keys := make([]string, 0, len(schema.Properties))
+	for key := range schema.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		prop := v.Resolve(schema.Properties[key])
+		required := sliceutils.StringContains(schema.Required, key)
+		if prop == nil {
+			continue
+		}
+		if prop.Format == BinaryF || (prop.Type == ArrayT && prop.Items != nil && prop.Items.Format == BinaryF) {
+			if required && len(files[key]) == 0 {
+				errs = append(errs, ValidationError{key, "is required"})
+			}
+			continue
+		}
+		errs = append(errs, v.validateRaw(key, prop, formValues(values, key), required)...)
+	}
+	return errs
+}
+
+// ValidateResponse checks json response body against 200 response of op
+func (v *Validator) ValidateResponse(op *Operation, contentType string, body []byte) ValidationErrors {
+	resp := v.Response200(op)
+	if resp == nil || resp.Content == nil || resp.Content.JSON == nil || !strings.Contains(contentType, "application/json") {
+		return nil
+	}
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return ValidationErrors{{"body", "must be valid json"}}
+	}
+	return v.ValidateValue("", resp.Content.JSON.Schema, value)
+}
+
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	raw, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(raw))
+	return raw, nil
+}
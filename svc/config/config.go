@@ -62,10 +62,16 @@ const (
 	GddRouteRootPath envVariable = "GDD_ROUTE_ROOT_PATH"
 	// GddServiceName sets service name
 	GddServiceName envVariable = "GDD_SERVICE_NAME"
+	// GddServiceVersion sets service version, used for version based routing such as canary release
+	GddServiceVersion envVariable = "GDD_SERVICE_VERSION"
+	// GddZone sets zone or data center of this node, used for zone-aware routing
+	GddZone envVariable = "GDD_ZONE"
 	// GddHost sets bind host for http server
 	GddHost envVariable = "GDD_HOST"
 	// GddPort sets bind port for http server
 	GddPort envVariable = "GDD_PORT"
+	// GddGrpcPort sets bind port for grpc server. It is registered into memberlist meta so clients can choose the protocol
+	GddGrpcPort envVariable = "GDD_GRPC_PORT"
 	// GddMode accepts 'mono' for monolith mode or 'micro' for microservice mode
 	GddMode envVariable = "GDD_MODE"
 	// GddManage if true, it will add built-in apis with /go-doudou path prefix for online api document and service status monitor etc.
@@ -128,6 +134,10 @@ const (
 	GddRetryCount         envVariable = "GDD_RETRY_COUNT"
 	GddTracingMetricsRoot envVariable = "GDD_TRACING_METRICS_ROOT"
 	GddMemIndirectChecks  envVariable = "GDD_MEM_INDIRECT_CHECKS"
+	// GddClientTimeout sets default timeout for requests sent by http clients, such as 30s. Timeout of each method can
+	// be set by GDD_CLIENT_TIMEOUT_ appended with upper case service name and method name joined by underscore,
+	// such as GDD_CLIENT_TIMEOUT_USERSVC_GETUSER
+	GddClientTimeout envVariable = "GDD_CLIENT_TIMEOUT"
 )
 
 // Load loads value from environment variable
@@ -0,0 +1,51 @@
+package ddgrpc
+
+import (
+	"fmt"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/logger"
+	"github.com/unionj-cloud/go-doudou/svc/registry"
+	"google.golang.org/grpc"
+	"net"
+	"strings"
+)
+
+// DefaultPort is used when GDD_GRPC_PORT is not set
+const DefaultPort = "50051"
+
+// GrpcSrv wraps grpc.Server
+type GrpcSrv struct {
+	*grpc.Server
+}
+
+// NewGrpcSrv create a GrpcSrv instance
+func NewGrpcSrv(opts ...grpc.ServerOption) *GrpcSrv {
+	return &GrpcSrv{
+		Server: grpc.NewServer(opts...),
+	}
+}
+
+// Run listens on GDD_HOST:GDD_GRPC_PORT and serves grpc requests in background.
+// The port listened on is registered into memberlist meta no matter registry.NewNode is called before or after it.
+// Call GracefulStop to stop it after http server shut down.
+func (srv *GrpcSrv) Run() {
+	port := config.GddGrpcPort.Load()
+	if stringutils.IsEmpty(port) {
+		port = DefaultPort
+		logger.Warnf("No env variable %s found, use default port %s instead", config.GddGrpcPort, DefaultPort)
+	}
+	lis, err := net.Listen("tcp", strings.Join([]string{config.GddHost.Load(), port}, ":"))
+	if err != nil {
+		logger.Panicln(fmt.Sprintf("grpc server failed to listen: %+v", err))
+	}
+	logger.Infof("grpc server is listening on %s", lis.Addr())
+	if err = registry.SetGrpcPort(lis.Addr().(*net.TCPAddr).Port); err != nil {
+		logger.Errorln(fmt.Sprintf("grpc server failed to register port: %+v", err))
+	}
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			logger.Errorln(fmt.Sprintf("grpc server stopped: %+v", err))
+		}
+	}()
+}
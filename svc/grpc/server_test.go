@@ -0,0 +1,42 @@
+package ddgrpc
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"testing"
+	"time"
+)
+
+func TestGrpcSrv_Run(t *testing.T) {
+	_ = config.GddGrpcPort.Write("50061")
+	defer config.GddGrpcPort.Write("")
+	srv := NewGrpcSrv()
+	grpc_health_v1.RegisterHealthServer(srv, health.NewServer())
+	srv.Run()
+	defer srv.GracefulStop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, "localhost:50061", grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, resp.Status)
+}
+
+func TestGrpcSrv_RunDefaultPort(t *testing.T) {
+	_ = config.GddGrpcPort.Write("")
+	defer config.GddGrpcPort.Write("")
+	srv := NewGrpcSrv()
+	srv.Run()
+	defer srv.GracefulStop()
+	// port listened on is registered rather than zero
+	assert.Equal(t, DefaultPort, config.GddGrpcPort.Load())
+}
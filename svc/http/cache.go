@@ -0,0 +1,244 @@
+package ddhttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheSize is default max number of responses cached by each http client created by NewClient
+const DefaultCacheSize = 1000
+
+type cachingCtxKey struct{}
+
+// WithCaching returns a copy of ctx carrying name, GET requests with it sent by http clients created by NewClient
+// will be cached according to Cache-Control, Vary and ETag headers of responses. name identifies the method, such as
+// Usersvc.GetUser. Generated clients set it for methods annotated with @cache in svc.go file.
+// Requests with Authorization header and responses marked as private or no-store are never cached.
+func WithCaching(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, cachingCtxKey{}, name)
+}
+
+func cachingFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(cachingCtxKey{}).(string)
+	return name, ok
+}
+
+// WithCacheSize sets max number of responses cached by http client, DefaultCacheSize is used if not set
+func WithCacheSize(size int) ClientOption {
+	return func(o *clientOptions) {
+		o.cacheSize = size
+	}
+}
+
+// cacheEntry is a cached response
+type cacheEntry struct {
+	status  int
+	header  http.Header
+	body    []byte
+	etag    string
+	expires time.Time
+	// vary holds values of request headers listed in Vary header of the response
+	vary map[string]string
+}
+
+// matches reports whether req has the same values of headers listed in Vary header as the cached request
+func (e *cacheEntry) matches(req *http.Request) bool {
+	for k, v := range e.vary {
+		if req.Header.Get(k) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// varyOf returns values of request headers listed in Vary header of resp, false if Vary is *
+func varyOf(req *http.Request, resp *http.Response) (map[string]string, bool) {
+	vary := make(map[string]string)
+	for _, value := range resp.Header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return nil, false
+			}
+			if stringutils.IsNotEmpty(name) {
+				vary[http.CanonicalHeaderKey(name)] = req.Header.Get(name)
+			}
+		}
+	}
+	return vary, true
+}
+
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(e.status) + " " + http.StatusText(e.status),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+// cacheControl parses Cache-Control header, returns max-age and whether the response can be stored
+func cacheControl(header http.Header) (time.Duration, bool) {
+	var maxAge time.Duration
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store", directive == "private":
+			return 0, false
+		case directive == "no-cache":
+			return 0, true
+		case strings.HasPrefix(directive, "max-age="):
+			if seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil && seconds > 0 {
+				maxAge = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return maxAge, true
+}
+
+// cacheTransport caches responses of GET requests carrying name set by WithCaching
+type cacheTransport struct {
+	http.RoundTripper
+	size  int
+	once  sync.Once
+	store *lru.Cache
+}
+
+func (t *cacheTransport) cache() *lru.Cache {
+	t.once.Do(func() {
+		size := t.size
+		if size <= 0 {
+			size = DefaultCacheSize
+		}
+		t.store, _ = lru.New(size)
+	})
+	return t.store
+}
+
+// cacheKey returns key of req, base url of the selected node is trimmed so that responses can be shared among nodes
+func cacheKey(name string, req *http.Request) string {
+	rawURL := req.URL.String()
+	if state := retryStateFromContext(req.Context()); state != nil {
+		if tried := state.tried(); len(tried) > 0 && stringutils.IsNotEmpty(tried[len(tried)-1]) {
+			rawURL = strings.TrimPrefix(rawURL, tried[len(tried)-1])
+		}
+	}
+	return strings.Join([]string{name, req.Header.Get("Accept"), req.Header.Get(CanaryHeader), rawURL}, " ")
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	name, ok := cachingFromContext(req.Context())
+	if !ok || req.Method != http.MethodGet || strings.Contains(req.Header.Get("Cache-Control"), "no-store") ||
+		stringutils.IsNotEmpty(req.Header.Get("Authorization")) {
+		return t.RoundTripper.RoundTrip(req)
+	}
+	store := t.cache()
+	key := cacheKey(name, req)
+	var entry *cacheEntry
+	if value, exists := store.Get(key); exists && value.(*cacheEntry).matches(req) {
+		entry = value.(*cacheEntry)
+		if time.Now().Before(entry.expires) {
+			return entry.response(req), nil
+		}
+		if stringutils.IsNotEmpty(entry.etag) {
+			req = req.Clone(req.Context())
+			req.Header.Set("If-None-Match", entry.etag)
+		}
+	}
+	resp, err := t.RoundTripper.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+		maxAge, _ := cacheControl(resp.Header)
+		revalidated := *entry
+		revalidated.expires = time.Now().Add(maxAge)
+		store.Add(key, &revalidated)
+		return revalidated.response(req), nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	maxAge, storable := cacheControl(resp.Header)
+	etag := resp.Header.Get("ETag")
+	vary, varyable := varyOf(req, resp)
+	if !storable || !varyable || (maxAge == 0 && stringutils.IsEmpty(etag)) {
+		store.Remove(key)
+		return resp, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	store.Add(key, &cacheEntry{
+		status:  resp.StatusCode,
+		header:  resp.Header.Clone(),
+		body:    body,
+		etag:    etag,
+		expires: time.Now().Add(maxAge),
+		vary:    vary,
+	})
+	return resp, nil
+}
+
+// etagMatch reports whether If-None-Match header value matches etag
+func etagMatch(ifNoneMatch, etag string) bool {
+	for _, item := range strings.Split(ifNoneMatch, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" || strings.TrimPrefix(item, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ETag generates ETag header for successful responses of GET and HEAD requests from hash of response body,
+// and returns 304 Not Modified if it matches If-None-Match header of the request.
+// Error responses, file downloads and streaming responses are written through without ETag.
+func ETag(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		bw := newBufferedWriter(w, func(status int, header http.Header) bool {
+			return status != http.StatusOK || stringutils.IsNotEmpty(header.Get("Content-Disposition"))
+		})
+		inner.ServeHTTP(bw, r)
+		if bw.streaming {
+			return
+		}
+		etag := w.Header().Get("ETag")
+		if stringutils.IsEmpty(etag) {
+			sum := sha1.Sum(bw.buf.Bytes())
+			etag = `"` + hex.EncodeToString(sum[:]) + `"`
+			w.Header().Set("ETag", etag)
+		}
+		if ifNoneMatch := r.Header.Get("If-None-Match"); stringutils.IsNotEmpty(ifNoneMatch) && etagMatch(ifNoneMatch, etag) {
+			w.Header().Del("Content-Length")
+			w.Header().Del("Content-Type")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		bw.flush()
+	})
+}
@@ -0,0 +1,166 @@
+package ddhttp
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCacheTransport(t *testing.T) {
+	tests := []struct {
+		name string
+		// header is set to responses of the server
+		header http.Header
+		// reqHeaders are headers of the two requests sent one after another
+		reqHeaders [2]http.Header
+		caching    bool
+		wantHits   int32
+	}{
+		{"max-age", http.Header{"Cache-Control": {"max-age=60"}}, [2]http.Header{}, true, 1},
+		{"not enabled", http.Header{"Cache-Control": {"max-age=60"}}, [2]http.Header{}, false, 2},
+		{"no-store", http.Header{"Cache-Control": {"no-store"}}, [2]http.Header{}, true, 2},
+		{"private", http.Header{"Cache-Control": {"private, max-age=60"}}, [2]http.Header{}, true, 2},
+		{"no validator", http.Header{}, [2]http.Header{}, true, 2},
+		{"authorization", http.Header{"Cache-Control": {"max-age=60"}},
+			[2]http.Header{{"Authorization": {"Bearer a"}}, {"Authorization": {"Bearer b"}}}, true, 2},
+		{"vary matched", http.Header{"Cache-Control": {"max-age=60"}, "Vary": {"X-Tenant"}},
+			[2]http.Header{{"X-Tenant": {"a"}}, {"X-Tenant": {"a"}}}, true, 1},
+		{"vary mismatched", http.Header{"Cache-Control": {"max-age=60"}, "Vary": {"X-Tenant"}},
+			[2]http.Header{{"X-Tenant": {"a"}}, {"X-Tenant": {"b"}}}, true, 2},
+		{"vary star", http.Header{"Cache-Control": {"max-age=60"}, "Vary": {"*"}}, [2]http.Header{}, true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&hits, 1)
+				for k, v := range tt.header {
+					w.Header()[k] = v
+				}
+				w.Write([]byte("hello " + r.Header.Get("X-Tenant")))
+			}))
+			defer ts.Close()
+			client := &http.Client{Transport: &cacheTransport{RoundTripper: http.DefaultTransport}}
+			for i, header := range tt.reqHeaders {
+				ctx := context.Background()
+				if tt.caching {
+					ctx = WithCaching(ctx, "Usersvc.GetUser")
+				}
+				req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/user", nil)
+				for k, v := range header {
+					req.Header[k] = v
+				}
+				resp, err := client.Do(req)
+				if !assert.NoError(t, err) {
+					return
+				}
+				body, _ := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				assert.Equal(t, http.StatusOK, resp.StatusCode, i)
+				assert.Equal(t, "hello "+header.Get("X-Tenant"), string(body), i)
+			}
+			assert.Equal(t, tt.wantHits, atomic.LoadInt32(&hits))
+		})
+	}
+}
+
+func TestCacheTransportRevalidate(t *testing.T) {
+	var hits, notModified int32
+	ts := httptest.NewServer(ETag(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write([]byte("hello"))
+	})))
+	defer ts.Close()
+	client := &http.Client{Transport: &cacheTransport{RoundTripper: &countTransport{http.DefaultTransport, &notModified}}}
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequestWithContext(WithCaching(context.Background(), "Usersvc.GetUser"), http.MethodGet, ts.URL, nil)
+		resp, err := client.Do(req)
+		if !assert.NoError(t, err) {
+			return
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "hello", string(body))
+	}
+	// every request is revalidated because of no-cache, and served from cache after 304
+	assert.Equal(t, int32(3), atomic.LoadInt32(&hits))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&notModified))
+}
+
+// countTransport counts 304 responses
+type countTransport struct {
+	http.RoundTripper
+	notModified *int32
+}
+
+func (t *countTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.RoundTripper.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusNotModified {
+		atomic.AddInt32(t.notModified, 1)
+	}
+	return resp, err
+}
+
+func TestETag(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+	})
+	etag := `"aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"`
+	tests := []struct {
+		name        string
+		inner       http.HandlerFunc
+		method      string
+		ifNoneMatch string
+		wantCode    int
+		wantETag    string
+		wantBody    string
+	}{
+		{"generated", ok, http.MethodGet, "", http.StatusOK, etag, "hello"},
+		{"not modified", ok, http.MethodGet, etag, http.StatusNotModified, etag, ""},
+		{"weak not modified", ok, http.MethodGet, "W/" + etag, http.StatusNotModified, etag, ""},
+		{"modified", ok, http.MethodGet, `"other"`, http.StatusOK, etag, "hello"},
+		{"head", ok, http.MethodHead, etag, http.StatusNotModified, etag, ""},
+		{"post", ok, http.MethodPost, etag, http.StatusOK, "", "hello"},
+		{"custom etag", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte("hello"))
+		}, http.MethodGet, `"v1"`, http.StatusNotModified, `"v1"`, ""},
+		{"error", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		}, http.MethodGet, "*", http.StatusNotFound, "", "not found"},
+		{"download", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Disposition", "attachment; filename=a.txt")
+			w.Write([]byte("hello"))
+		}, http.MethodGet, "*", http.StatusOK, "", "hello"},
+		{"streaming", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("data: hello\n\n"))
+		}, http.MethodGet, "*", http.StatusOK, "", "data: hello\n\n"},
+		{"flushed", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hel"))
+			w.(http.Flusher).Flush()
+			w.Write([]byte("lo"))
+		}, http.MethodGet, "*", http.StatusOK, "", "hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.method, "/user", nil)
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			ETag(tt.inner).ServeHTTP(w, r)
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantETag, w.Header().Get("ETag"))
+			assert.Equal(t, tt.wantBody, w.Body.String())
+		})
+	}
+}
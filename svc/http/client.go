@@ -1,17 +1,23 @@
 package ddhttp
 
 import (
+	"context"
 	"github.com/go-resty/resty/v2"
 	"github.com/opentracing-contrib/go-stdlib/nethttp"
-	"github.com/unionj-cloud/cast"
-	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/pkg/errors"
 	"github.com/unionj-cloud/go-doudou/svc/logger"
 	"github.com/unionj-cloud/go-doudou/svc/registry"
 	"github.com/unionj-cloud/memberlist"
+	"hash/crc32"
+	"math"
+	"math/rand"
 	"net"
 	"net/http"
 	"os"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -66,29 +72,60 @@ func NewServiceProvider(env string) *ServiceProvider {
 	}
 }
 
+// ClientOption defines configure function type for NewClient
+type ClientOption func(*clientOptions)
+
+type clientOptions struct {
+	retry     RetryPolicy
+	cacheSize int
+}
+
+// WithRetryPolicy sets retry policy of http client, DefaultRetryPolicy is used if not set
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(o *clientOptions) {
+		o.retry = policy
+	}
+}
+
 // NewClient creates new resty Client instance
-func NewClient() *resty.Client {
+func NewClient(opts ...ClientOption) *resty.Client {
+	options := clientOptions{
+		retry: DefaultRetryPolicy(),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
 	client := resty.New()
-	client.SetTimeout(1 * time.Minute)
 	dialer := &net.Dialer{
 		Timeout:   30 * time.Second,
 		KeepAlive: 30 * time.Second,
 		DualStack: true,
 	}
 	client.SetTransport(&nethttp.Transport{
-		RoundTripper: &http.Transport{
-			Proxy:                 http.ProxyFromEnvironment,
-			DialContext:           dialer.DialContext,
-			ForceAttemptHTTP2:     true,
-			MaxIdleConns:          100,
-			IdleConnTimeout:       90 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-			MaxIdleConnsPerHost:   runtime.GOMAXPROCS(0) + 1,
-			MaxConnsPerHost:       100,
+		RoundTripper: &timeoutTransport{
+			RoundTripper: &canaryTransport{
+				RoundTripper: &cacheTransport{
+					size: options.cacheSize,
+					RoundTripper: &hedgeTransport{
+						RoundTripper: &feedbackTransport{
+							RoundTripper: &http.Transport{
+								Proxy:                 http.ProxyFromEnvironment,
+								DialContext:           dialer.DialContext,
+								ForceAttemptHTTP2:     true,
+								MaxIdleConns:          100,
+								IdleConnTimeout:       90 * time.Second,
+								TLSHandshakeTimeout:   10 * time.Second,
+								ExpectContinueTimeout: 1 * time.Second,
+								MaxIdleConnsPerHost:   runtime.GOMAXPROCS(0) + 1,
+								MaxConnsPerHost:       100,
+							},
+						},
+					},
+				},
+			},
 		},
 	})
-	client.SetRetryCount(cast.ToInt(config.GddRetryCount.Load()))
+	applyRetryPolicy(client, options.retry)
 	return client
 }
 
@@ -98,6 +135,33 @@ type server struct {
 	baseUrl       string
 	weight        int
 	currentWeight int
+	// inflight is number of requests sent to the node but not done yet
+	inflight int64
+	// ewma is exponentially weighted moving average of latency in nanosecond
+	ewma float64
+	// observedAt is the last time ewma updated
+	observedAt time.Time
+	outlierStats
+	// info is used for filtering nodes
+	info registry.NodeInfo
+}
+
+const (
+	// ewmaDecay is the time for old latency samples to decay to 1/e weight
+	ewmaDecay = 10 * time.Second
+	// failurePenalty is added to latency of failed requests, so that node failing fast won't attract more requests
+	failurePenalty = time.Second
+)
+
+// observe updates ewma of latency by sample. The weight of old value decays with the time elapsed since last sample.
+func (s *server) observe(sample time.Duration, now time.Time) {
+	if s.observedAt.IsZero() {
+		s.ewma = float64(sample)
+	} else {
+		w := math.Exp(-float64(now.Sub(s.observedAt)) / float64(ewmaDecay))
+		s.ewma = s.ewma*w + float64(sample)*(1-w)
+	}
+	s.observedAt = now
 }
 
 type base struct {
@@ -105,8 +169,19 @@ type base struct {
 	nodes   []*server
 	nodeMap map[string]*server
 	lock    sync.RWMutex
+	// outlier is nil if outlier detection disabled
+	outlier *OutlierDetection
+	filters []NodeFilter
+	// subsetSize zero means subsetting disabled
+	subsetSize int
+	subset     []*server
+	// subsetClient is local node name which subset is picked for
+	subsetClient string
 }
 
+// ProviderOption defines configure function type for service providers
+type ProviderOption func(registry.IServiceProvider)
+
 // AddNode add or update node providing the service
 func (m *base) AddNode(node *memberlist.Node) {
 	m.lock.Lock()
@@ -124,14 +199,17 @@ func (m *base) AddNode(node *memberlist.Node) {
 			baseUrl:       baseUrl,
 			weight:        weight,
 			currentWeight: 0,
+			info:          registry.Info(node),
 		}
 		m.nodes = append(m.nodes, s)
 		m.nodeMap[node.Name] = s
+		m.resubset()
 		logger.Infof("[go-doudou] node %s joined, supplying %s service", node.Name, svcName)
 	} else {
 		old := *s
 		s.baseUrl = baseUrl
 		s.weight = weight
+		s.info = registry.Info(node)
 		logger.Infof("[go-doudou] node %s update, supplying %s service, old: %+v, new: %+v", node.Name, svcName, old, *s)
 	}
 }
@@ -170,10 +248,66 @@ func (m *base) RemoveNode(node *memberlist.Node) {
 		}
 		m.nodes = append(m.nodes[:idx], m.nodes[idx+1:]...)
 		delete(m.nodeMap, node.Name)
+		m.resubset()
+		deleteNodeMetrics(m.name, node.Name)
 		logger.Infof("[go-doudou] node %s left, supplying %s service", node.Name, svcName)
 	}
 }
 
+// deleteNodeMetrics deletes metrics of the node which left
+func deleteNodeMetrics(service, node string) {
+	nodeLatencyEwma.DeleteLabelValues(service, node)
+	nodeInflight.DeleteLabelValues(service, node)
+	nodeEjected.DeleteLabelValues(service, node)
+	nodeEjections.DeleteLabelValues(service, node)
+}
+
+// serverOf returns the server which url is sent to, caller must hold the lock
+func (m *base) serverOf(url string) *server {
+	for _, s := range m.nodes {
+		if !strings.HasPrefix(url, s.baseUrl) {
+			continue
+		}
+		if rest := url[len(s.baseUrl):]; rest == "" || rest[0] == '/' || rest[0] == '?' {
+			return s
+		}
+	}
+	return nil
+}
+
+// RequestStarted increases in-flight requests of the node which url is sent to
+func (m *base) RequestStarted(url string) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if s := m.serverOf(url); s != nil {
+		nodeInflight.WithLabelValues(m.name, s.node).Set(float64(atomic.AddInt64(&s.inflight, 1)))
+	}
+}
+
+// RequestDone decreases in-flight requests and updates latency of the node which the request is sent to.
+// Requests cancelled by caller, such as the loser of hedged requests, are neither counted as success nor failure.
+func (m *base) RequestDone(outcome registry.RequestOutcome) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	s := m.serverOf(outcome.URL)
+	if s == nil {
+		return
+	}
+	nodeInflight.WithLabelValues(m.name, s.node).Set(float64(atomic.AddInt64(&s.inflight, -1)))
+	if errors.Is(outcome.Err, context.Canceled) {
+		return
+	}
+	sample := outcome.Latency
+	failed := outcome.Err != nil || outcome.StatusCode >= http.StatusInternalServerError
+	if failed {
+		sample += failurePenalty
+	}
+	now := time.Now()
+	s.observe(sample, now)
+	nodeLatencyEwma.WithLabelValues(m.name, s.node).Set(time.Duration(s.ewma).Seconds())
+	m.detect(s, failed, now)
+}
+
 // MemberlistServiceProvider defines an implementation for IServiceProvider
 type MemberlistServiceProvider struct {
 	base
@@ -182,25 +316,35 @@ type MemberlistServiceProvider struct {
 
 // SelectServer selects a node which is supplying service specified by name property from cluster
 func (m *MemberlistServiceProvider) SelectServer() string {
+	return m.SelectServerWithFilter()
+}
+
+// SelectServerWithFilter selects a node matching filters by round-robin
+func (m *MemberlistServiceProvider) SelectServerWithFilter(filters ...NodeFilter) string {
+	m.refreshSubset()
 	m.lock.RLock()
 	defer m.lock.RUnlock()
-	if len(m.nodes) == 0 {
+	nodes := m.candidates(filters...)
+	if len(nodes) == 0 {
 		return ""
 	}
-	next := int(atomic.AddUint64(&m.current, uint64(1)) % uint64(len(m.nodes)))
+	next := int(atomic.AddUint64(&m.current, uint64(1)) % uint64(len(nodes)))
 	m.current = uint64(next)
-	selected := m.nodes[next]
+	selected := nodes[next]
 	return selected.baseUrl
 }
 
 // NewMemberlistServiceProvider create an NewMemberlistServiceProvider instance
-func NewMemberlistServiceProvider(name string) *MemberlistServiceProvider {
+func NewMemberlistServiceProvider(name string, opts ...ProviderOption) *MemberlistServiceProvider {
 	sp := &MemberlistServiceProvider{
 		base: base{
 			name:    name,
 			nodeMap: make(map[string]*server),
 		},
 	}
+	for _, opt := range opts {
+		opt(sp)
+	}
 	registry.RegisterServiceProvider(sp)
 	return sp
 }
@@ -213,15 +357,22 @@ type SmoothWeightedRoundRobinProvider struct {
 
 // SelectServer selects a node which is supplying service specified by name property from cluster
 func (m *SmoothWeightedRoundRobinProvider) SelectServer() string {
+	return m.SelectServerWithFilter()
+}
+
+// SelectServerWithFilter selects a node matching filters by smooth weighted round-robin
+func (m *SmoothWeightedRoundRobinProvider) SelectServerWithFilter(filters ...NodeFilter) string {
+	m.refreshSubset()
 	m.lock.RLock()
 	defer m.lock.RUnlock()
-	if len(m.nodes) == 0 {
+	nodes := m.candidates(filters...)
+	if len(nodes) == 0 {
 		return ""
 	}
 	var selected *server
 	total := 0
-	for i := 0; i < len(m.nodes); i++ {
-		s := m.nodes[i]
+	for i := 0; i < len(nodes); i++ {
+		s := nodes[i]
 		if s == nil {
 			continue
 		}
@@ -239,13 +390,294 @@ func (m *SmoothWeightedRoundRobinProvider) SelectServer() string {
 }
 
 // NewSmoothWeightedRoundRobinProvider create an SmoothWeightedRoundRobinProvider instance
-func NewSmoothWeightedRoundRobinProvider(name string) *SmoothWeightedRoundRobinProvider {
+func NewSmoothWeightedRoundRobinProvider(name string, opts ...ProviderOption) *SmoothWeightedRoundRobinProvider {
 	sp := &SmoothWeightedRoundRobinProvider{
 		base: base{
 			name:    name,
 			nodeMap: make(map[string]*server),
 		},
 	}
+	for _, opt := range opts {
+		opt(sp)
+	}
+	registry.RegisterServiceProvider(sp)
+	return sp
+}
+
+// P2CProvider is a latency-aware power of two choices load balancer implementation for IServiceProvider.
+// It picks two random nodes and selects the one with lower score, which is ewma of latency multiplied by
+// in-flight requests plus one. Latency and in-flight requests are fed by http clients created by NewClient.
+type P2CProvider struct {
+	base
+}
+
+// score of s, the lower the better, caller must hold the lock
+func (p *P2CProvider) score(s *server) float64 {
+	return (s.ewma + float64(time.Millisecond)) * float64(atomic.LoadInt64(&s.inflight)+1)
+}
+
+// SelectServer selects the better one of two random nodes
+func (p *P2CProvider) SelectServer() string {
+	return p.SelectServerWithFilter()
+}
+
+// SelectServerWithFilter selects the better one of two random nodes matching filters
+func (p *P2CProvider) SelectServerWithFilter(filters ...NodeFilter) string {
+	p.refreshSubset()
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	nodes := p.candidates(filters...)
+	n := len(nodes)
+	switch n {
+	case 0:
+		return ""
+	case 1:
+		return nodes[0].baseUrl
+	}
+	i := rand.Intn(n)
+	j := rand.Intn(n - 1)
+	if j >= i {
+		j++
+	}
+	a, b := nodes[i], nodes[j]
+	if p.score(b) < p.score(a) {
+		return b.baseUrl
+	}
+	return a.baseUrl
+}
+
+// RequestDone updates latency of the node and exposes its score
+func (p *P2CProvider) RequestDone(outcome registry.RequestOutcome) {
+	p.base.RequestDone(outcome)
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if s := p.serverOf(outcome.URL); s != nil {
+		nodeP2CScore.WithLabelValues(p.name, s.node).Set(p.score(s))
+	}
+}
+
+// RemoveNode removes node and its score
+func (p *P2CProvider) RemoveNode(node *memberlist.Node) {
+	p.base.RemoveNode(node)
+	nodeP2CScore.DeleteLabelValues(p.name, node.Name)
+}
+
+// NewP2CProvider create an P2CProvider instance
+func NewP2CProvider(name string, opts ...ProviderOption) *P2CProvider {
+	sp := &P2CProvider{
+		base: base{
+			name:    name,
+			nodeMap: make(map[string]*server),
+		},
+	}
+	for _, opt := range opts {
+		opt(sp)
+	}
+	registry.RegisterServiceProvider(sp)
+	return sp
+}
+
+// IHashServiceProvider defines service provider interface which can select server by key
+type IHashServiceProvider interface {
+	registry.IServiceProvider
+	SelectServerByKey(key string) string
+}
+
+type hashKeyCtxKey struct{}
+
+// WithHashKey returns a copy of ctx carrying key, generated clients will pass it to IHashServiceProvider
+// for selecting server, so requests with the same key will be sent to the same node
+func WithHashKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, hashKeyCtxKey{}, key)
+}
+
+// HashKeyFromContext returns key set by WithHashKey
+func HashKeyFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	key, ok := ctx.Value(hashKeyCtxKey{}).(string)
+	return key, ok
+}
+
+// SelectServer selects a server from provider for the request with ctx.
+// If provider is IHashServiceProvider and ctx carries a key set by WithHashKey, the server will be selected by the key.
+// If ctx carries a version set by WithCanary, nodes of the version will be preferred by IFilterServiceProvider.
+// If the request is being retried by http clients created by NewClient, nodes tried before will be avoided.
+// Outcome of the request sent by http clients created by NewClient is reported to provider if it implements
+// registry.IFeedbackServiceProvider.
+func SelectServer(ctx context.Context, provider registry.IServiceProvider) string {
+	var filters []NodeFilter
+	if version, ok := CanaryFromContext(ctx); ok {
+		filters = append(filters, VersionFilter(version))
+	}
+	state := retryStateFromContext(ctx)
+	if state != nil {
+		if tried := state.tried(); len(tried) > 0 {
+			filters = append(filters, excludeFilter(tried))
+		}
+	}
+	selected := selectServer(ctx, provider, filters)
+	if state != nil {
+		state.record(provider, selected)
+	}
+	return selected
+}
+
+func selectServer(ctx context.Context, provider registry.IServiceProvider, filters []NodeFilter) string {
+	if key, ok := HashKeyFromContext(ctx); ok {
+		if hp, ok := provider.(interface {
+			SelectServerByKeyWithFilter(key string, filters ...NodeFilter) string
+		}); ok && len(filters) > 0 {
+			return hp.SelectServerByKeyWithFilter(key, filters...)
+		}
+		if hp, ok := provider.(IHashServiceProvider); ok {
+			return hp.SelectServerByKey(key)
+		}
+	}
+	if fp, ok := provider.(IFilterServiceProvider); ok && len(filters) > 0 {
+		return fp.SelectServerWithFilter(filters...)
+	}
+	return provider.SelectServer()
+}
+
+// DefaultReplicas is default number of virtual nodes for each unit of node weight on hash ring
+const DefaultReplicas = 50
+
+// ConsistentHashProvider is a consistent hashing implementation for IServiceProvider.
+// Each node is placed on the hash ring as replicas * weight virtual nodes, so only keys
+// around the virtual nodes of the joined or left node will be remapped.
+type ConsistentHashProvider struct {
+	base
+	replicas int
+	ring     []uint32
+	ringMap  map[uint32]*server
+	current  uint64
+}
+
+// WithReplicas sets number of virtual nodes for each unit of node weight, only works for ConsistentHashProvider
+func WithReplicas(replicas int) ProviderOption {
+	return func(sp registry.IServiceProvider) {
+		if p, ok := sp.(*ConsistentHashProvider); ok && replicas > 0 {
+			p.replicas = replicas
+		}
+	}
+}
+
+func (c *ConsistentHashProvider) rebuild() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	nodes := c.pool()
+	ring := make([]uint32, 0, len(nodes)*c.replicas)
+	ringMap := make(map[uint32]*server, len(nodes)*c.replicas)
+	for _, s := range nodes {
+		weight := s.weight
+		if weight < 1 {
+			weight = 1
+		}
+		for i := 0; i < c.replicas*weight; i++ {
+			h := crc32.ChecksumIEEE([]byte(s.node + "#" + strconv.Itoa(i)))
+			if _, exists := ringMap[h]; exists {
+				continue
+			}
+			ring = append(ring, h)
+			ringMap[h] = s
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool {
+		return ring[i] < ring[j]
+	})
+	c.ring = ring
+	c.ringMap = ringMap
+}
+
+// AddNode add or update node providing the service, and places its virtual nodes on hash ring
+func (c *ConsistentHashProvider) AddNode(node *memberlist.Node) {
+	c.base.AddNode(node)
+	c.rebuild()
+}
+
+// UpdateWeight updates weight of node and its virtual nodes on hash ring
+func (c *ConsistentHashProvider) UpdateWeight(node *memberlist.Node) {
+	c.base.UpdateWeight(node)
+	c.rebuild()
+}
+
+// RemoveNode removes node and its virtual nodes from hash ring
+func (c *ConsistentHashProvider) RemoveNode(node *memberlist.Node) {
+	c.base.RemoveNode(node)
+	c.rebuild()
+}
+
+// SelectServerByKey selects the node of the first virtual node clockwise from hash of key on hash ring
+func (c *ConsistentHashProvider) SelectServerByKey(key string) string {
+	return c.SelectServerByKeyWithFilter(key)
+}
+
+// SelectServerByKeyWithFilter selects the node of the first virtual node clockwise from hash of key on hash ring,
+// skipping nodes which are ejected or not matching filters, so only keys of the skipped nodes are remapped
+func (c *ConsistentHashProvider) SelectServerByKeyWithFilter(key string, filters ...NodeFilter) string {
+	if c.refreshSubset() {
+		c.rebuild()
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if len(c.ring) == 0 {
+		return ""
+	}
+	h := crc32.ChecksumIEEE([]byte(key))
+	idx := sort.Search(len(c.ring), func(i int) bool {
+		return c.ring[i] >= h
+	})
+	if c.outlier == nil && len(c.filters) == 0 && len(filters) == 0 {
+		return c.ringMap[c.ring[idx%len(c.ring)]].baseUrl
+	}
+	candidates := make(map[*server]struct{})
+	for _, s := range c.candidates(filters...) {
+		candidates[s] = struct{}{}
+	}
+	for i := 0; i < len(c.ring); i++ {
+		s := c.ringMap[c.ring[(idx+i)%len(c.ring)]]
+		if _, ok := candidates[s]; ok {
+			return s.baseUrl
+		}
+	}
+	return ""
+}
+
+// SelectServer selects a node by round-robin as there is no key
+func (c *ConsistentHashProvider) SelectServer() string {
+	return c.SelectServerWithFilter()
+}
+
+// SelectServerWithFilter selects a node matching filters by round-robin as there is no key
+func (c *ConsistentHashProvider) SelectServerWithFilter(filters ...NodeFilter) string {
+	if c.refreshSubset() {
+		c.rebuild()
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	nodes := c.candidates(filters...)
+	if len(nodes) == 0 {
+		return ""
+	}
+	next := int(atomic.AddUint64(&c.current, uint64(1)) % uint64(len(nodes)))
+	return nodes[next].baseUrl
+}
+
+// NewConsistentHashProvider create an ConsistentHashProvider instance
+func NewConsistentHashProvider(name string, opts ...ProviderOption) *ConsistentHashProvider {
+	sp := &ConsistentHashProvider{
+		base: base{
+			name:    name,
+			nodeMap: make(map[string]*server),
+		},
+		replicas: DefaultReplicas,
+		ringMap:  make(map[uint32]*server),
+	}
+	for _, opt := range opts {
+		opt(sp)
+	}
 	registry.RegisterServiceProvider(sp)
 	return sp
 }
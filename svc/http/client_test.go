@@ -0,0 +1,284 @@
+package ddhttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/svc/registry"
+	"github.com/unionj-cloud/memberlist"
+	"testing"
+	"time"
+)
+
+const testService = "testsvc"
+
+// testNode returns a fake memberlist node supplying testService at http://127.0.0.1:port.
+// Values in meta override default meta such as weight, version and zone, data is custom data of the node
+func testNode(name string, port int, meta map[string]interface{}, data map[string]interface{}) *memberlist.Node {
+	m := map[string]interface{}{
+		"service": testService,
+		"port":    port,
+		"weight":  1,
+	}
+	for k, v := range meta {
+		m[k] = v
+	}
+	raw, _ := json.Marshal(map[string]interface{}{
+		"_meta": m,
+		"data":  data,
+	})
+	return &memberlist.Node{
+		Name: name,
+		Addr: "127.0.0.1",
+		Port: 7946,
+		Meta: raw,
+	}
+}
+
+// testNodes returns n fake nodes named node0, node1... listening on port 6060, 6061...
+func testNodes(n int) []*memberlist.Node {
+	var nodes []*memberlist.Node
+	for i := 0; i < n; i++ {
+		nodes = append(nodes, testNode(fmt.Sprintf("node%d", i), 6060+i, nil, nil))
+	}
+	return nodes
+}
+
+func testBaseUrl(port int) string {
+	return fmt.Sprintf("http://127.0.0.1:%d", port)
+}
+
+// newTestBase returns base of service providers for testService without registering to memberlist
+func newTestBase() base {
+	return base{
+		name:    testService,
+		nodeMap: make(map[string]*server),
+	}
+}
+
+func newTestConsistentHashProvider(nodes []*memberlist.Node, opts ...ProviderOption) *ConsistentHashProvider {
+	sp := &ConsistentHashProvider{
+		base:     newTestBase(),
+		replicas: DefaultReplicas,
+		ringMap:  make(map[uint32]*server),
+	}
+	for _, opt := range opts {
+		opt(sp)
+	}
+	for _, node := range nodes {
+		sp.AddNode(node)
+	}
+	return sp
+}
+
+// keyMapping returns selected server of each key
+func keyMapping(sp *ConsistentHashProvider, keys int) map[string]string {
+	mapping := make(map[string]string)
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("user%d", i)
+		mapping[key] = sp.SelectServerByKey(key)
+	}
+	return mapping
+}
+
+func TestConsistentHashProvider_SelectServerByKey(t *testing.T) {
+	sp := newTestConsistentHashProvider(testNodes(3))
+	assert.Len(t, sp.ring, 3*DefaultReplicas)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("user%d", i)
+		assert.Equal(t, sp.SelectServerByKey(key), sp.SelectServerByKey(key))
+	}
+	counts := make(map[string]int)
+	for _, server := range keyMapping(sp, 3000) {
+		counts[server]++
+	}
+	assert.Len(t, counts, 3)
+	for server, count := range counts {
+		assert.Greater(t, count, 500, server)
+	}
+
+	empty := newTestConsistentHashProvider(nil)
+	assert.Empty(t, empty.SelectServerByKey("user0"))
+	assert.Empty(t, empty.SelectServer())
+}
+
+func TestConsistentHashProvider_Remapping(t *testing.T) {
+	const keys = 3000
+	tests := []struct {
+		name string
+		// change joins or leaves a node, and returns base url of the node
+		change func(sp *ConsistentHashProvider) string
+		// joined is true if the node joined, otherwise left
+		joined bool
+	}{
+		{"join", func(sp *ConsistentHashProvider) string {
+			sp.AddNode(testNode("node5", 6065, nil, nil))
+			return testBaseUrl(6065)
+		}, true},
+		{"leave", func(sp *ConsistentHashProvider) string {
+			sp.RemoveNode(testNode("node2", 6062, nil, nil))
+			return testBaseUrl(6062)
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp := newTestConsistentHashProvider(testNodes(5))
+			before := keyMapping(sp, keys)
+			changed := tt.change(sp)
+			after := keyMapping(sp, keys)
+			var remapped int
+			for key, server := range before {
+				if after[key] == server {
+					continue
+				}
+				remapped++
+				// only keys moving to the joined node or away from the left node are remapped
+				if tt.joined {
+					assert.Equal(t, changed, after[key], key)
+				} else {
+					assert.Equal(t, changed, server, key)
+				}
+			}
+			assert.Greater(t, remapped, 0)
+			assert.Less(t, remapped, keys/3)
+		})
+	}
+}
+
+func TestConsistentHashProvider_Weight(t *testing.T) {
+	nodes := testNodes(2)
+	nodes = append(nodes, testNode("heavy", 6070, map[string]interface{}{"weight": 4}, nil))
+	sp := newTestConsistentHashProvider(nodes, WithReplicas(10))
+	assert.Len(t, sp.ring, 10*6)
+	counts := make(map[string]int)
+	for _, server := range keyMapping(sp, 3000) {
+		counts[server]++
+	}
+	assert.Greater(t, counts[testBaseUrl(6070)], counts[testBaseUrl(6060)])
+	assert.Greater(t, counts[testBaseUrl(6070)], counts[testBaseUrl(6061)])
+}
+
+func TestConsistentHashProvider_SelectServerByKeyWithFilter(t *testing.T) {
+	sp := newTestConsistentHashProvider(testNodes(3))
+	before := keyMapping(sp, 1000)
+	excluded := testBaseUrl(6061)
+	filter := excludeFilter([]string{excluded})
+	for key, server := range before {
+		selected := sp.SelectServerByKeyWithFilter(key, filter)
+		assert.NotEqual(t, excluded, selected, key)
+		if server != excluded {
+			assert.Equal(t, server, selected, key)
+		}
+	}
+}
+
+func TestSelectServer_HashKey(t *testing.T) {
+	sp := newTestConsistentHashProvider(testNodes(3))
+	ctx := WithHashKey(context.Background(), "user1")
+	key, ok := HashKeyFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "user1", key)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, sp.SelectServerByKey("user1"), SelectServer(ctx, sp))
+	}
+	// requests without key are distributed by round-robin
+	selected := make(map[string]struct{})
+	for i := 0; i < 3; i++ {
+		selected[SelectServer(context.Background(), sp)] = struct{}{}
+	}
+	assert.Len(t, selected, 3)
+}
+
+func newTestP2CProvider(nodes []*memberlist.Node, opts ...ProviderOption) *P2CProvider {
+	sp := &P2CProvider{
+		base: newTestBase(),
+	}
+	for _, opt := range opts {
+		opt(sp)
+	}
+	for _, node := range nodes {
+		sp.AddNode(node)
+	}
+	return sp
+}
+
+func TestP2CProvider_SelectServer(t *testing.T) {
+	type outcome struct {
+		port    int
+		latency time.Duration
+		status  int
+	}
+	tests := []struct {
+		name     string
+		outcomes []outcome
+		// inflight requests started but not done
+		inflight map[int]int
+		want     string
+	}{
+		{"lower latency", []outcome{{6060, 100 * time.Millisecond, 200}, {6061, time.Millisecond, 200}}, nil, testBaseUrl(6061)},
+		{"fewer in-flight requests", []outcome{{6060, 10 * time.Millisecond, 200}, {6061, time.Millisecond, 200}},
+			map[int]int{6061: 20}, testBaseUrl(6060)},
+		{"failure penalty", []outcome{{6060, 100 * time.Millisecond, 200}, {6061, time.Millisecond, 503}}, nil, testBaseUrl(6060)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp := newTestP2CProvider(testNodes(2))
+			for _, o := range tt.outcomes {
+				url := testBaseUrl(o.port) + "/user"
+				sp.RequestStarted(url)
+				sp.RequestDone(registry.RequestOutcome{URL: url, Latency: o.latency, StatusCode: o.status})
+			}
+			for port, n := range tt.inflight {
+				for i := 0; i < n; i++ {
+					sp.RequestStarted(testBaseUrl(port) + "/user")
+				}
+			}
+			// both nodes are always picked as there are only two
+			for i := 0; i < 20; i++ {
+				assert.Equal(t, tt.want, sp.SelectServer())
+			}
+		})
+	}
+}
+
+func TestP2CProvider_SelectServerSpread(t *testing.T) {
+	sp := newTestP2CProvider(testNodes(4))
+	counts := make(map[string]int)
+	for i := 0; i < 400; i++ {
+		counts[sp.SelectServer()]++
+	}
+	// without latency samples every node has the same score, the first picked one wins
+	assert.Len(t, counts, 4)
+	assert.Equal(t, testBaseUrl(6060), newTestP2CProvider(testNodes(1)).SelectServer())
+	assert.Empty(t, newTestP2CProvider(nil).SelectServer())
+}
+
+func TestServer_observe(t *testing.T) {
+	now := time.Now()
+	s := &server{}
+	s.observe(100*time.Millisecond, now)
+	assert.Equal(t, float64(100*time.Millisecond), s.ewma)
+	// sample right after the last one barely moves ewma
+	s.observe(time.Millisecond, now.Add(time.Millisecond))
+	assert.InDelta(t, float64(100*time.Millisecond), s.ewma, float64(time.Millisecond))
+	// old value decays to almost nothing after a long time
+	s.observe(time.Millisecond, now.Add(10*ewmaDecay))
+	assert.InDelta(t, float64(time.Millisecond), s.ewma, float64(time.Millisecond))
+}
+
+func TestBase_RequestDone(t *testing.T) {
+	sp := newTestP2CProvider(testNodes(2))
+	sp.RequestStarted(testBaseUrl(6060) + "/user?id=1")
+	sp.RequestStarted(testBaseUrl(6060))
+	// port 60601 shares prefix with 6060 but is not the same node
+	sp.RequestStarted("http://127.0.0.1:60601/user")
+	assert.Equal(t, int64(2), sp.nodeMap["node0"].inflight)
+	assert.Equal(t, int64(0), sp.nodeMap["node1"].inflight)
+	sp.RequestDone(registry.RequestOutcome{URL: testBaseUrl(6060) + "/user?id=1", Latency: time.Millisecond, StatusCode: 200})
+	assert.Equal(t, int64(1), sp.nodeMap["node0"].inflight)
+	assert.Equal(t, float64(time.Millisecond), sp.nodeMap["node0"].ewma)
+	sp.RequestDone(registry.RequestOutcome{URL: testBaseUrl(6060), Latency: time.Millisecond, Err: errors.New("refused")})
+	assert.Greater(t, sp.nodeMap["node0"].ewma, float64(time.Millisecond))
+}
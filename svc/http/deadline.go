@@ -0,0 +1,187 @@
+package ddhttp
+
+import (
+	"context"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/logger"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeoutHeader carries remaining time before deadline of the caller in the same format as grpc-timeout,
+// such as 500m for 500 milliseconds
+const TimeoutHeader = "Grpc-Timeout"
+
+// DefaultClientTimeout is default timeout for requests sent by http clients created by NewClient
+const DefaultClientTimeout = time.Minute
+
+type timeoutCtxKey struct{}
+
+type methodTimeout struct {
+	name    string
+	timeout time.Duration
+}
+
+// WithTimeout returns a copy of ctx carrying timeout of method name such as Usersvc.GetUser, requests with it sent by
+// http clients created by NewClient will be cancelled after the timeout. The timeout can be overridden by environment
+// variable such as GDD_CLIENT_TIMEOUT_USERSVC_GETUSER. Generated clients set it for methods annotated with @timeout
+// in svc.go file.
+func WithTimeout(ctx context.Context, name string, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutCtxKey{}, methodTimeout{
+		name:    name,
+		timeout: timeout,
+	})
+}
+
+// timeoutEnv returns environment variable name for timeout of method name, such as GDD_CLIENT_TIMEOUT_USERSVC_GETUSER
+func timeoutEnv(name string) string {
+	return string(config.GddClientTimeout) + "_" + strings.ToUpper(strings.ReplaceAll(name, ".", "_"))
+}
+
+func parseTimeout(env string) (time.Duration, bool) {
+	value := os.Getenv(env)
+	if stringutils.IsEmpty(value) {
+		return 0, false
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		logger.Warnf("[go-doudou] invalid timeout %s from %s, ignored", value, env)
+		return 0, false
+	}
+	return timeout, true
+}
+
+// timeoutOf returns timeout for requests with ctx, environment variable of the method takes precedence over
+// timeout set by WithTimeout, and GDD_CLIENT_TIMEOUT is used if both are not set
+func timeoutOf(ctx context.Context) time.Duration {
+	if m, ok := ctx.Value(timeoutCtxKey{}).(methodTimeout); ok {
+		if timeout, ok := parseTimeout(timeoutEnv(m.name)); ok {
+			return timeout
+		}
+		if m.timeout > 0 {
+			return m.timeout
+		}
+	}
+	if timeout, ok := parseTimeout(string(config.GddClientTimeout)); ok {
+		return timeout
+	}
+	return DefaultClientTimeout
+}
+
+type deadlineCtxKey struct{}
+
+// withDeadline returns a copy of ctx carrying deadline after timeout of ctx from now,
+// so that all attempts of a request including retries and failover share the deadline
+func withDeadline(ctx context.Context) context.Context {
+	return context.WithValue(ctx, deadlineCtxKey{}, time.Now().Add(timeoutOf(ctx)))
+}
+
+// deadlineOf returns deadline set by withDeadline, or deadline after timeout of ctx from now if not set
+func deadlineOf(ctx context.Context) time.Time {
+	if deadline, ok := ctx.Value(deadlineCtxKey{}).(time.Time); ok {
+		return deadline
+	}
+	return time.Now().Add(timeoutOf(ctx))
+}
+
+var timeoutUnits = []struct {
+	unit     string
+	duration time.Duration
+}{
+	{"n", time.Nanosecond},
+	{"u", time.Microsecond},
+	{"m", time.Millisecond},
+	{"S", time.Second},
+	{"M", time.Minute},
+	{"H", time.Hour},
+}
+
+// encodeTimeout formats timeout as grpc-timeout, which is at most 8 digits followed by a unit
+func encodeTimeout(timeout time.Duration) string {
+	if timeout <= 0 {
+		return "0n"
+	}
+	const maxValue = 99999999
+	for _, u := range timeoutUnits {
+		// round up so that the callee never gets more time than the caller has
+		value := (timeout + u.duration - 1) / u.duration
+		if value <= maxValue {
+			return strconv.FormatInt(int64(value), 10) + u.unit
+		}
+	}
+	return strconv.Itoa(maxValue) + "H"
+}
+
+// decodeTimeout parses timeout in grpc-timeout format
+func decodeTimeout(value string) (time.Duration, bool) {
+	if len(value) < 2 || len(value) > 9 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	unit := value[len(value)-1:]
+	for _, u := range timeoutUnits {
+		if u.unit == unit {
+			return time.Duration(n) * u.duration, true
+		}
+	}
+	return 0, false
+}
+
+// timeoutTransport applies deadline to requests and propagates remaining time before deadline by TimeoutHeader.
+// Attempts of a request sent by http clients created by NewClient share the deadline set before the first attempt.
+type timeoutTransport struct {
+	http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithDeadline(req.Context(), deadlineOf(req.Context()))
+	deadline, _ := ctx.Deadline()
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		cancel()
+		return nil, context.DeadlineExceeded
+	}
+	req = req.Clone(ctx)
+	req.Header.Set(TimeoutHeader, encodeTimeout(remaining))
+	resp, err := t.RoundTripper.RoundTrip(req)
+	if err != nil {
+		cancel()
+		return resp, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// Deadline applies remaining time before deadline of the caller from TimeoutHeader to context of the request,
+// so that the request is cancelled once the caller has given up. Requests already exceeding the deadline are rejected
+// with 504 Gateway Timeout.
+func Deadline(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.Header.Get(TimeoutHeader)
+		if stringutils.IsEmpty(value) {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		timeout, ok := decodeTimeout(value)
+		if !ok {
+			logger.Warnf("[go-doudou] invalid %s header %s, ignored", TimeoutHeader, value)
+			inner.ServeHTTP(w, r)
+			return
+		}
+		if timeout <= 0 {
+			http.Error(w, context.DeadlineExceeded.Error(), http.StatusGatewayTimeout)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		inner.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
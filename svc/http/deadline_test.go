@@ -0,0 +1,218 @@
+package ddhttp
+
+import (
+	"context"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/memberlist"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEncodeTimeout(t *testing.T) {
+	tests := []struct {
+		timeout time.Duration
+		want    string
+	}{
+		{0, "0n"},
+		{-time.Second, "0n"},
+		{500 * time.Nanosecond, "500n"},
+		{500 * time.Millisecond, "500000u"},
+		{1500*time.Millisecond + time.Nanosecond, "1500001u"},
+		{200 * time.Second, "200000m"},
+		{1000 * time.Hour, "3600000S"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got := encodeTimeout(tt.timeout)
+			assert.Equal(t, tt.want, got)
+			decoded, ok := decodeTimeout(got)
+			assert.True(t, ok)
+			// encoded timeout is rounded up
+			assert.GreaterOrEqual(t, int64(decoded), int64(tt.timeout))
+		})
+	}
+}
+
+func TestDecodeTimeout(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{"500m", 500 * time.Millisecond, true},
+		{"3S", 3 * time.Second, true},
+		{"2M", 2 * time.Minute, true},
+		{"1H", time.Hour, true},
+		{"10u", 10 * time.Microsecond, true},
+		{"0n", 0, true},
+		{"m", 0, false},
+		{"123456789m", 0, false},
+		{"-1S", 0, false},
+		{"1s", 0, false},
+		{"abcm", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := decodeTimeout(tt.value)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTimeoutOf(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		ctx  context.Context
+		want time.Duration
+	}{
+		{"default", nil, context.Background(), DefaultClientTimeout},
+		{"client timeout", map[string]string{"GDD_CLIENT_TIMEOUT": "30s"}, context.Background(), 30 * time.Second},
+		{"method timeout", map[string]string{"GDD_CLIENT_TIMEOUT": "30s"},
+			WithTimeout(context.Background(), "Usersvc.GetUser", 3*time.Second), 3 * time.Second},
+		{"method env", map[string]string{"GDD_CLIENT_TIMEOUT_USERSVC_GETUSER": "5s"},
+			WithTimeout(context.Background(), "Usersvc.GetUser", 3*time.Second), 5 * time.Second},
+		{"env of other method", map[string]string{"GDD_CLIENT_TIMEOUT_USERSVC_PAGEUSERS": "5s"},
+			WithTimeout(context.Background(), "Usersvc.GetUser", 3*time.Second), 3 * time.Second},
+		{"invalid env", map[string]string{"GDD_CLIENT_TIMEOUT_USERSVC_GETUSER": "5", "GDD_CLIENT_TIMEOUT": "-1s"},
+			WithTimeout(context.Background(), "Usersvc.GetUser", 0), DefaultClientTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k := range tt.env {
+					os.Unsetenv(k)
+				}
+			}()
+			assert.Equal(t, tt.want, timeoutOf(tt.ctx))
+		})
+	}
+	assert.Equal(t, string(config.GddClientTimeout)+"_USERSVC_GETUSER", timeoutEnv("Usersvc.GetUser"))
+}
+
+func TestTimeoutTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(TimeoutHeader, r.Header.Get(TimeoutHeader))
+		if r.URL.Path == "/slow" {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		}
+	}))
+	defer ts.Close()
+	client := &http.Client{Transport: &timeoutTransport{http.DefaultTransport}}
+	tests := []struct {
+		name    string
+		path    string
+		timeout time.Duration
+		wantErr bool
+	}{
+		{"propagated", "/", 3 * time.Second, false},
+		{"timeout", "/slow", 50 * time.Millisecond, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequestWithContext(WithTimeout(context.Background(), "Testsvc.Get", tt.timeout), http.MethodGet, ts.URL+tt.path, nil)
+			resp, err := client.Do(req)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			resp.Body.Close()
+			remaining, ok := decodeTimeout(resp.Header.Get(TimeoutHeader))
+			assert.True(t, ok)
+			assert.LessOrEqual(t, int64(remaining), int64(tt.timeout))
+			assert.Greater(t, int64(remaining), int64(tt.timeout-time.Second))
+		})
+	}
+
+	// deadline set before the first attempt is shared by all attempts
+	ctx := withDeadline(WithTimeout(context.Background(), "Testsvc.Get", 3*time.Second))
+	deadline := deadlineOf(ctx)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, deadline, deadlineOf(ctx))
+	req, _ := http.NewRequestWithContext(context.WithValue(context.Background(), deadlineCtxKey{}, time.Now().Add(-time.Second)),
+		http.MethodGet, ts.URL, nil)
+	_, err := client.Do(req)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestDeadline(t *testing.T) {
+	tests := []struct {
+		name         string
+		header       string
+		wantCode     int
+		wantDeadline time.Duration
+	}{
+		{"no header", "", http.StatusOK, 0},
+		{"with header", "500m", http.StatusOK, 500 * time.Millisecond},
+		{"invalid header", "500", http.StatusOK, 0},
+		{"exceeded", "0n", http.StatusGatewayTimeout, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				deadline time.Time
+				ok       bool
+			)
+			r := httptest.NewRequest(http.MethodGet, "/user", nil)
+			if tt.header != "" {
+				r.Header.Set(TimeoutHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			start := time.Now()
+			Deadline(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				deadline, ok = r.Context().Deadline()
+			})).ServeHTTP(w, r)
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantDeadline > 0, ok)
+			if ok {
+				assert.WithinDuration(t, start.Add(tt.wantDeadline), deadline, 100*time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestNewClient_Timeout(t *testing.T) {
+	var hits int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(60 * time.Millisecond)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	ts1 := httptest.NewServer(handler)
+	defer ts1.Close()
+	ts2 := httptest.NewServer(handler)
+	defer ts2.Close()
+	provider := newTestRoundRobinProvider([]*memberlist.Node{testServerNode("node0", ts1), testServerNode("node1", ts2)})
+	policy := DefaultRetryPolicy()
+	policy.MaxRetries = 5
+	policy.WaitTime = time.Millisecond
+	policy.MaxWaitTime = time.Millisecond
+	policy.MinRetriesPerWindow = 10
+	client := NewClient(WithRetryPolicy(policy))
+	client.OnBeforeRequest(func(_ *resty.Client, request *resty.Request) error {
+		request.URL = SelectServer(request.Context(), provider) + request.URL
+		return nil
+	})
+	start := time.Now()
+	_, err := client.R().SetContext(WithTimeout(context.Background(), "Testsvc.Get", 150*time.Millisecond)).Get("/user")
+	// retries and failover give up at the deadline instead of getting a fresh timeout each
+	assert.Error(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(300*time.Millisecond))
+	assert.LessOrEqual(t, atomic.LoadInt32(&hits), int32(3))
+}
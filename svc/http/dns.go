@@ -0,0 +1,144 @@
+package ddhttp
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/unionj-cloud/go-doudou/svc/logger"
+	"github.com/unionj-cloud/go-doudou/svc/registry"
+	"github.com/unionj-cloud/memberlist"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Resolver resolves SRV and A records, *net.Resolver implements it
+type Resolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// DnsSrvProvider is an IServiceProvider implementation for nodes resolved from DNS periodically,
+// such as Kubernetes headless service. Nodes are selected by smooth weighted round-robin algo.
+type DnsSrvProvider struct {
+	SmoothWeightedRoundRobinProvider
+	refresher
+	target   string
+	resolver Resolver
+	scheme   string
+	rootPath string
+}
+
+// WithResolver sets resolver of DnsSrvProvider
+func WithResolver(resolver Resolver) ProviderOption {
+	return func(sp registry.IServiceProvider) {
+		if p, ok := sp.(*DnsSrvProvider); ok && resolver != nil {
+			p.resolver = resolver
+		}
+	}
+}
+
+// WithRouteRootPath sets scheme and route root path of base urls of nodes resolved by DnsSrvProvider,
+// such as https and /api, default is http and empty root path
+func WithRouteRootPath(scheme, rootPath string) ProviderOption {
+	return func(sp registry.IServiceProvider) {
+		if p, ok := sp.(*DnsSrvProvider); ok {
+			p.scheme = scheme
+			p.rootPath = strings.TrimSuffix(rootPath, "/")
+		}
+	}
+}
+
+// AddNode does nothing as nodes are not discovered by memberlist
+func (d *DnsSrvProvider) AddNode(node *memberlist.Node) {
+}
+
+// RemoveNode does nothing as nodes are not discovered by memberlist
+func (d *DnsSrvProvider) RemoveNode(node *memberlist.Node) {
+}
+
+// UpdateWeight does nothing as nodes are not discovered by memberlist
+func (d *DnsSrvProvider) UpdateWeight(node *memberlist.Node) {
+}
+
+func (d *DnsSrvProvider) endpoint(host string, port uint16, weight int) endpoint {
+	addr := net.JoinHostPort(strings.TrimSuffix(host, "."), fmt.Sprint(port))
+	return endpoint{
+		name:    addr,
+		baseUrl: fmt.Sprintf("%s://%s%s", d.scheme, addr, d.rootPath),
+		weight:  weight,
+	}
+}
+
+// resolve resolves SRV records if target starts with underscore such as _http._tcp.usersvc.default.svc.cluster.local,
+// otherwise resolves A records of host in target such as usersvc.default.svc.cluster.local:6060
+func (d *DnsSrvProvider) resolve() ([]endpoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var endpoints []endpoint
+	if strings.HasPrefix(d.target, "_") {
+		_, records, err := d.resolver.LookupSRV(ctx, "", "", d.target)
+		if err != nil {
+			return nil, errors.Wrapf(err, "lookup SRV records of %s failed", d.target)
+		}
+		for _, record := range records {
+			endpoints = append(endpoints, d.endpoint(record.Target, record.Port, int(record.Weight)))
+		}
+	} else {
+		host, port, err := net.SplitHostPort(d.target)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid target %s", d.target)
+		}
+		var p int
+		if _, err = fmt.Sscan(port, &p); err != nil || p <= 0 || p > 65535 {
+			return nil, errors.Errorf("invalid port of target %s", d.target)
+		}
+		addrs, err := d.resolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, errors.Wrapf(err, "lookup A records of %s failed", host)
+		}
+		for _, addr := range addrs {
+			endpoints = append(endpoints, d.endpoint(addr, uint16(p), 1))
+		}
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].name < endpoints[j].name
+	})
+	return endpoints, nil
+}
+
+func (d *DnsSrvProvider) reload() {
+	endpoints, err := d.resolve()
+	if err != nil {
+		logger.Errorf("[go-doudou] resolve nodes of %s service failed: %+v", d.name, err)
+		return
+	}
+	d.syncNodes(endpoints)
+}
+
+// NewDnsSrvProvider creates a DnsSrvProvider instance resolving target periodically. If target starts with underscore
+// such as _http._tcp.usersvc.default.svc.cluster.local, SRV records are resolved and their weights are used,
+// otherwise target should be host and port such as usersvc-headless.default.svc.cluster.local:6060, and A records are resolved.
+func NewDnsSrvProvider(name, target string, opts ...ProviderOption) *DnsSrvProvider {
+	sp := &DnsSrvProvider{
+		SmoothWeightedRoundRobinProvider: SmoothWeightedRoundRobinProvider{
+			base: base{
+				name:    name,
+				nodeMap: make(map[string]*server),
+			},
+		},
+		refresher: refresher{
+			interval: DefaultRefreshInterval,
+		},
+		target:   target,
+		resolver: net.DefaultResolver,
+		scheme:   "http",
+	}
+	for _, opt := range opts {
+		opt(sp)
+	}
+	sp.reload()
+	sp.run(sp.reload)
+	return sp
+}
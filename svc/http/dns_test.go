@@ -0,0 +1,129 @@
+package ddhttp
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeResolver resolves records set by tests
+type fakeResolver struct {
+	lock  sync.Mutex
+	srv   []*net.SRV
+	hosts []string
+	err   error
+}
+
+func (r *fakeResolver) set(srv []*net.SRV, hosts []string, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.srv, r.hosts, r.err = srv, hosts, err
+}
+
+func (r *fakeResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return "", r.srv, r.err
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.hosts, r.err
+}
+
+func TestNewDnsSrvProvider(t *testing.T) {
+	srv := []*net.SRV{
+		{Target: "usersvc-1.usersvc.default.svc.cluster.local.", Port: 6060, Weight: 2},
+		{Target: "usersvc-0.usersvc.default.svc.cluster.local.", Port: 6060, Weight: 1},
+	}
+	tests := []struct {
+		name   string
+		target string
+		opts   []ProviderOption
+		srv    []*net.SRV
+		hosts  []string
+		err    error
+		want   map[string]int
+	}{
+		{"srv", "_http._tcp.usersvc.default.svc.cluster.local", nil, srv, nil, nil, map[string]int{
+			"http://usersvc-0.usersvc.default.svc.cluster.local:6060": 1,
+			"http://usersvc-1.usersvc.default.svc.cluster.local:6060": 2,
+		}},
+		{"a", "usersvc.default.svc.cluster.local:6060", nil, nil, []string{"10.0.0.2", "10.0.0.1"}, nil, map[string]int{
+			"http://10.0.0.1:6060": 1,
+			"http://10.0.0.2:6060": 1,
+		}},
+		{"ipv6", "usersvc.default.svc.cluster.local:6060", nil, nil, []string{"fd00::1"}, nil, map[string]int{
+			"http://[fd00::1]:6060": 1,
+		}},
+		{"route root path", "usersvc.default.svc.cluster.local:6060", []ProviderOption{WithRouteRootPath("https", "/api/")},
+			nil, []string{"10.0.0.1"}, nil, map[string]int{
+				"https://10.0.0.1:6060/api": 1,
+			}},
+		{"missing port", "usersvc.default.svc.cluster.local", nil, nil, []string{"10.0.0.1"}, nil, map[string]int{}},
+		{"invalid port", "usersvc.default.svc.cluster.local:http", nil, nil, []string{"10.0.0.1"}, nil, map[string]int{}},
+		{"lookup failed", "_http._tcp.usersvc.default.svc.cluster.local", nil, nil, nil, errors.New("no such host"), map[string]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := &fakeResolver{}
+			resolver.set(tt.srv, tt.hosts, tt.err)
+			sp := NewDnsSrvProvider(testService, tt.target, append(tt.opts, WithResolver(resolver))...)
+			defer sp.Close()
+			assert.Equal(t, tt.want, nodeWeights(&sp.base))
+		})
+	}
+}
+
+func TestDnsSrvProvider_reload(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+	addr := ts.Listener.Addr().(*net.TCPAddr)
+	resolver := &fakeResolver{}
+	resolver.set([]*net.SRV{{Target: "127.0.0.1.", Port: uint16(addr.Port), Weight: 1}}, nil, nil)
+	sp := NewDnsSrvProvider(testService, "_http._tcp.usersvc.default.svc.cluster.local",
+		WithResolver(resolver), WithRefreshInterval(10*time.Millisecond))
+	defer sp.Close()
+	assert.Equal(t, ts.URL, sp.SelectServer())
+	resp, err := http.Get(sp.SelectServer())
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// records changed
+	resolver.set([]*net.SRV{
+		{Target: "127.0.0.1.", Port: uint16(addr.Port), Weight: 3},
+		{Target: "127.0.0.2.", Port: 6060, Weight: 1},
+	}, nil, nil)
+	want := map[string]int{ts.URL: 3, "http://127.0.0.2:6060": 1}
+	assert.Eventually(t, func() bool {
+		return reflect.DeepEqual(want, nodeWeights(&sp.base))
+	}, time.Second, 10*time.Millisecond)
+
+	// nodes are kept if lookup fails
+	resolver.set(nil, nil, errors.New("timeout"))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, want, nodeWeights(&sp.base))
+
+	// nodes are not reloaded after closed
+	sp.Close()
+	resolver.set([]*net.SRV{{Target: "127.0.0.3.", Port: 6060, Weight: 1}}, nil, nil)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, want, nodeWeights(&sp.base))
+	assert.NotPanics(t, sp.Close)
+	assertCollected(t, func() interface{ Close() } {
+		return NewDnsSrvProvider(testService, "_http._tcp.usersvc.default.svc.cluster.local",
+			WithResolver(resolver), WithRefreshInterval(10*time.Millisecond))
+	})
+}
@@ -0,0 +1,47 @@
+package ddhttp
+
+import (
+	"context"
+	"github.com/unionj-cloud/go-doudou/svc/registry"
+	"net/http"
+	"time"
+)
+
+// feedbackProviderOf returns the provider which selected server of the request with ctx by SelectServer,
+// nil if it doesn't receive outcome of requests
+func feedbackProviderOf(ctx context.Context) registry.IFeedbackServiceProvider {
+	state := retryStateFromContext(ctx)
+	if state == nil {
+		return nil
+	}
+	provider, _ := state.selectedBy().(registry.IFeedbackServiceProvider)
+	return provider
+}
+
+// feedbackTransport reports outcome of each request attempt to the provider which selected its server,
+// so providers only receive outcome of requests sent to their own nodes
+type feedbackTransport struct {
+	http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *feedbackTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	provider := feedbackProviderOf(req.Context())
+	if provider == nil {
+		return t.RoundTripper.RoundTrip(req)
+	}
+	url := req.URL.String()
+	provider.RequestStarted(url)
+	start := time.Now()
+	resp, err := t.RoundTripper.RoundTrip(req)
+	outcome := registry.RequestOutcome{
+		URL:     url,
+		Latency: time.Since(start),
+		Err:     err,
+	}
+	if resp != nil {
+		outcome.StatusCode = resp.StatusCode
+	}
+	provider.RequestDone(outcome)
+	return resp, err
+}
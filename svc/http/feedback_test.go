@@ -0,0 +1,63 @@
+package ddhttp
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/svc/registry"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type recordingProvider struct {
+	ServiceProvider
+	lock     sync.Mutex
+	started  []string
+	outcomes []registry.RequestOutcome
+}
+
+func (p *recordingProvider) RequestStarted(url string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.started = append(p.started, url)
+}
+
+func (p *recordingProvider) RequestDone(outcome registry.RequestOutcome) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.outcomes = append(p.outcomes, outcome)
+}
+
+func TestFeedbackTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	p := &recordingProvider{ServiceProvider: ServiceProvider{server: ts.URL}}
+	client := &http.Client{Transport: &feedbackTransport{RoundTripper: http.DefaultTransport}}
+	get := func(provider registry.IServiceProvider) error {
+		ctx := context.WithValue(context.Background(), retryStateCtxKey{}, &retryState{})
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, SelectServer(ctx, provider)+"/user", nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	assert.NoError(t, get(p))
+	ts.Close()
+	assert.Error(t, get(p))
+	// requests not selected by providers are not reported
+	resp, err := client.Get(ts.URL + "/user")
+	if err == nil {
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, []string{ts.URL + "/user", ts.URL + "/user"}, p.started)
+	if assert.Len(t, p.outcomes, 2) {
+		assert.Equal(t, http.StatusServiceUnavailable, p.outcomes[0].StatusCode)
+		assert.NoError(t, p.outcomes[0].Err)
+		assert.Greater(t, int64(p.outcomes[0].Latency), int64(0))
+		assert.Error(t, p.outcomes[1].Err)
+	}
+}
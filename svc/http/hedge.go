@@ -0,0 +1,284 @@
+package ddhttp
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HedgePolicy configures hedged requests. If a GET request hasn't completed after the delay, a second request will be
+// sent to a different node, the first successful response wins and the other request is cancelled.
+type HedgePolicy struct {
+	// Name identifies the method for collecting latency samples and metrics, such as Usersvc.GetUser
+	Name string
+	// Percentile of recent latencies of the method is used as delay, such as 95, zero means disabled
+	Percentile float64
+	// Delay is used if Percentile is zero or there are not enough latency samples.
+	// If it is zero, requests are not hedged until there are enough latency samples
+	Delay time.Duration
+}
+
+var hedgeRequests = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "client_hedge_requests_total",
+		Help: "Number of requests which can be hedged.",
+	},
+	[]string{"method"},
+)
+
+var hedgedRequests = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "client_hedged_requests_total",
+		Help: "Number of hedged requests sent.",
+	},
+	[]string{"method"},
+)
+
+var hedgeWins = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "client_hedge_wins_total",
+		Help: "Number of hedged requests which returned before the original ones.",
+	},
+	[]string{"method"},
+)
+
+func init() {
+	prometheus.Register(hedgeRequests)
+	prometheus.Register(hedgedRequests)
+	prometheus.Register(hedgeWins)
+}
+
+type hedgeCtxKey struct{}
+
+// WithHedging returns a copy of ctx carrying policy, GET requests with it sent by http clients created by NewClient
+// will be hedged. Generated clients set it for methods annotated with @hedge in svc.go file.
+func WithHedging(ctx context.Context, policy HedgePolicy) context.Context {
+	return context.WithValue(ctx, hedgeCtxKey{}, policy)
+}
+
+func hedgePolicyFromContext(ctx context.Context) (HedgePolicy, bool) {
+	policy, ok := ctx.Value(hedgeCtxKey{}).(HedgePolicy)
+	return policy, ok
+}
+
+const (
+	latencySamples    = 1000
+	minLatencySamples = 20
+	percentileRefresh = 50
+)
+
+// latencyWindow keeps recent latency samples of a method
+type latencyWindow struct {
+	lock    sync.Mutex
+	samples []time.Duration
+	next    int
+	// added counts samples added since percentiles computed
+	added       int
+	percentiles map[float64]time.Duration
+}
+
+func (w *latencyWindow) add(latency time.Duration) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if len(w.samples) < latencySamples {
+		w.samples = append(w.samples, latency)
+	} else {
+		w.samples[w.next] = latency
+		w.next = (w.next + 1) % latencySamples
+	}
+	w.added++
+}
+
+func (w *latencyWindow) percentile(p float64) (time.Duration, bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if len(w.samples) < minLatencySamples {
+		return 0, false
+	}
+	if w.added >= percentileRefresh || w.percentiles == nil {
+		w.added = 0
+		w.percentiles = make(map[float64]time.Duration)
+	}
+	if d, ok := w.percentiles[p]; ok {
+		return d, true
+	}
+	sorted := append([]time.Duration{}, w.samples...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	idx := int(p / 100 * float64(len(sorted)))
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	w.percentiles[p] = sorted[idx]
+	return sorted[idx], true
+}
+
+var latencyWindows sync.Map
+
+func latencyWindowOf(name string) *latencyWindow {
+	w, _ := latencyWindows.LoadOrStore(name, &latencyWindow{})
+	return w.(*latencyWindow)
+}
+
+// delay returns delay before sending hedged request, false if the request should not be hedged
+func (p HedgePolicy) delay() (time.Duration, bool) {
+	if p.Percentile > 0 {
+		if d, ok := latencyWindowOf(p.Name).percentile(p.Percentile); ok {
+			return d, true
+		}
+	}
+	return p.Delay, p.Delay > 0
+}
+
+// hedgeTransport sends hedged requests for GET requests carrying HedgePolicy
+type hedgeTransport struct {
+	http.RoundTripper
+}
+
+type hedgeResult struct {
+	resp    *http.Response
+	err     error
+	hedged  bool
+	latency time.Duration
+}
+
+func (r hedgeResult) ok() bool {
+	return r.err == nil && r.resp.StatusCode < http.StatusInternalServerError
+}
+
+// discard releases response of the request
+func (r hedgeResult) discard() {
+	if r.resp != nil {
+		io.Copy(ioutil.Discard, r.resp.Body)
+		r.resp.Body.Close()
+	}
+}
+
+// cancelOnClose cancels context of the request when response body closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+func (t *hedgeTransport) send(req *http.Request, hedged bool, results chan<- hedgeResult) {
+	start := time.Now()
+	resp, err := t.RoundTripper.RoundTrip(req)
+	results <- hedgeResult{
+		resp:    resp,
+		err:     err,
+		hedged:  hedged,
+		latency: time.Since(start),
+	}
+}
+
+// hedgeURL selects another server for the request, returns empty string if there is no other server
+func hedgeURL(req *http.Request) string {
+	state := retryStateFromContext(req.Context())
+	if state == nil || state.selectedBy() == nil {
+		return ""
+	}
+	tried := state.tried()
+	if len(tried) == 0 {
+		return ""
+	}
+	original := tried[len(tried)-1]
+	rawURL := req.URL.String()
+	if stringutils.IsEmpty(original) || !strings.HasPrefix(rawURL, original) {
+		return ""
+	}
+	server := SelectServer(req.Context(), state.selectedBy())
+	if stringutils.IsEmpty(server) || server == original {
+		return ""
+	}
+	return server + strings.TrimPrefix(rawURL, original)
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *hedgeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	policy, ok := hedgePolicyFromContext(req.Context())
+	if !ok || (req.Method != http.MethodGet && req.Method != http.MethodHead) || (req.Body != nil && req.GetBody == nil) {
+		return t.RoundTripper.RoundTrip(req)
+	}
+	hedgeRequests.WithLabelValues(policy.Name).Inc()
+	results := make(chan hedgeResult, 2)
+	primaryCtx, cancelPrimary := context.WithCancel(req.Context())
+	cancels := map[bool]context.CancelFunc{false: cancelPrimary}
+	go t.send(req.Clone(primaryCtx), false, results)
+	// hedge stays nil if there is no delay yet, latency samples are still collected
+	var hedge <-chan time.Time
+	if delay, ok := policy.delay(); ok {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		hedge = timer.C
+	}
+	inflight := 1
+	var last hedgeResult
+	for {
+		select {
+		case <-hedge:
+			if inflight > 1 {
+				continue
+			}
+			target := hedgeURL(req)
+			if stringutils.IsEmpty(target) {
+				continue
+			}
+			u, err := url.Parse(target)
+			if err != nil {
+				continue
+			}
+			hedgedCtx, cancelHedged := context.WithCancel(req.Context())
+			cancels[true] = cancelHedged
+			hedged := req.Clone(hedgedCtx)
+			hedged.URL = u
+			hedged.Host = ""
+			inflight++
+			hedgedRequests.WithLabelValues(policy.Name).Inc()
+			go t.send(hedged, true, results)
+		case result := <-results:
+			inflight--
+			if result.ok() {
+				latencyWindowOf(policy.Name).add(result.latency)
+			} else if inflight > 0 {
+				// wait for the other one
+				last = result
+				continue
+			}
+			if result.hedged && result.ok() {
+				hedgeWins.WithLabelValues(policy.Name).Inc()
+			}
+			last.discard()
+			for hedged, cancel := range cancels {
+				if hedged != result.hedged {
+					cancel()
+				}
+			}
+			if inflight > 0 {
+				go func() {
+					(<-results).discard()
+				}()
+			}
+			if result.resp != nil {
+				result.resp.Body = &cancelOnClose{ReadCloser: result.resp.Body, cancel: cancels[result.hedged]}
+			} else {
+				cancels[result.hedged]()
+			}
+			return result.resp, result.err
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package ddhttp
+
+import (
+	"context"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/memberlist"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLatencyWindow_percentile(t *testing.T) {
+	w := &latencyWindow{}
+	for i := 1; i < minLatencySamples; i++ {
+		w.add(time.Duration(i) * time.Millisecond)
+	}
+	_, ok := w.percentile(95)
+	assert.False(t, ok)
+	for i := minLatencySamples; i <= 100; i++ {
+		w.add(time.Duration(i) * time.Millisecond)
+	}
+	tests := []struct {
+		percentile float64
+		want       time.Duration
+	}{
+		{50, 51 * time.Millisecond},
+		{95, 96 * time.Millisecond},
+		{99, 100 * time.Millisecond},
+		{100, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		d, ok := w.percentile(tt.percentile)
+		assert.True(t, ok)
+		assert.Equal(t, tt.want, d, tt.percentile)
+	}
+	// cached percentiles are refreshed after enough new samples
+	for i := 0; i < percentileRefresh; i++ {
+		w.add(time.Second)
+	}
+	d, _ := w.percentile(95)
+	assert.Equal(t, time.Second, d)
+}
+
+func TestHedgeTransport(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		policy HedgePolicy
+		// samples are latency samples collected before the request
+		samples  []time.Duration
+		wantBody string
+		// wantHits are requests received by the slow node and the fast one
+		wantHits [2]int32
+	}{
+		{"delay", http.MethodGet, HedgePolicy{Delay: 20 * time.Millisecond}, nil, "fast", [2]int32{1, 1}},
+		{"percentile", http.MethodGet, HedgePolicy{Percentile: 95},
+			[]time.Duration{20 * time.Millisecond}, "fast", [2]int32{1, 1}},
+		{"fallback to delay", http.MethodGet, HedgePolicy{Percentile: 95, Delay: 20 * time.Millisecond}, nil, "fast", [2]int32{1, 1}},
+		{"no latency samples", http.MethodGet, HedgePolicy{Percentile: 95}, nil, "slow", [2]int32{1, 0}},
+		{"not get", http.MethodPost, HedgePolicy{Delay: 20 * time.Millisecond}, nil, "slow", [2]int32{1, 0}},
+		{"primary wins", http.MethodGet, HedgePolicy{Delay: time.Second}, nil, "slow", [2]int32{1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits [2]int32
+			slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&hits[0], 1)
+				select {
+				case <-time.After(200 * time.Millisecond):
+				case <-r.Context().Done():
+					return
+				}
+				w.Write([]byte("slow"))
+			}))
+			defer slow.Close()
+			fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&hits[1], 1)
+				w.Write([]byte("fast"))
+			}))
+			defer fast.Close()
+			// round-robin selects the second node first
+			provider := newTestRoundRobinProvider([]*memberlist.Node{
+				testServerNode("fast", fast),
+				testServerNode("slow", slow),
+			})
+			tt.policy.Name = "Testsvc." + t.Name()
+			for i := 0; i < minLatencySamples; i++ {
+				for _, sample := range tt.samples {
+					latencyWindowOf(tt.policy.Name).add(sample)
+				}
+			}
+			ctx := context.WithValue(WithHedging(context.Background(), tt.policy), retryStateCtxKey{}, &retryState{})
+			req, _ := http.NewRequestWithContext(ctx, tt.method, SelectServer(ctx, provider)+"/user", nil)
+			client := &http.Client{Transport: &hedgeTransport{http.DefaultTransport}}
+			resp, err := client.Do(req)
+			if !assert.NoError(t, err) {
+				return
+			}
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			assert.Equal(t, tt.wantBody, string(body))
+			assert.Equal(t, tt.wantHits[0], atomic.LoadInt32(&hits[0]))
+			assert.Equal(t, tt.wantHits[1], atomic.LoadInt32(&hits[1]))
+		})
+	}
+}
+
+func TestHedgeTransport_Feedback(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		w.Write([]byte("slow"))
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("fast"))
+	}))
+	defer fast.Close()
+	provider := newTestP2CProvider([]*memberlist.Node{
+		testServerNode("fast", fast),
+		testServerNode("slow", slow),
+	}, WithOutlierDetection(OutlierDetection{
+		ConsecutiveFailures: 1,
+		BaseEjectionTime:    time.Minute,
+		MaxEjectionTime:     time.Minute,
+		MaxEjectionPercent:  100,
+	}))
+	client := NewClient()
+	client.OnBeforeRequest(func(_ *resty.Client, request *resty.Request) error {
+		request.URL = SelectServer(request.Context(), provider) + request.URL
+		return nil
+	})
+	ctx := WithHedging(context.Background(), HedgePolicy{Name: "Testsvc." + t.Name(), Delay: 20 * time.Millisecond})
+	for i := 0; i < 10; i++ {
+		resp, err := client.R().SetContext(ctx).Get("/user")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "fast", resp.String())
+		}
+	}
+	// the slow node which lost hedged requests is neither penalized nor ejected
+	provider.lock.RLock()
+	s := provider.nodeMap["slow"]
+	provider.lock.RUnlock()
+	if !assert.NotNil(t, s) {
+		return
+	}
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt64(&s.inflight) == 0
+	}, time.Second, 10*time.Millisecond)
+	provider.lock.RLock()
+	defer provider.lock.RUnlock()
+	assert.False(t, s.isEjected(time.Now()))
+	assert.Less(t, s.ewma, float64(failurePenalty))
+}
@@ -0,0 +1,35 @@
+package ddhttp
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var nodeLatencyEwma = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "client_node_latency_ewma_seconds",
+		Help: "Exponentially weighted moving average of latency of requests sent to service node.",
+	},
+	[]string{"service", "node"},
+)
+
+var nodeInflight = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "client_node_inflight_requests",
+		Help: "Number of in-flight requests sent to service node.",
+	},
+	[]string{"service", "node"},
+)
+
+var nodeP2CScore = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "client_node_p2c_score",
+		Help: "Score of service node used by power of two choices load balancer, the lower the better.",
+	},
+	[]string{"service", "node"},
+)
+
+func init() {
+	prometheus.Register(nodeLatencyEwma)
+	prometheus.Register(nodeInflight)
+	prometheus.Register(nodeP2CScore)
+}
@@ -0,0 +1,188 @@
+package ddhttp
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/unionj-cloud/go-doudou/svc/logger"
+	"github.com/unionj-cloud/go-doudou/svc/registry"
+	"sync/atomic"
+	"time"
+)
+
+// OutlierDetection configures passive outlier detection of service providers. A node will be ejected from load balancing
+// if its consecutive failures reach ConsecutiveFailures, or its failure rate reaches FailureRate within Interval when
+// there are at least MinRequests requests. Requests failed with error or 5xx status code are treated as failures.
+type OutlierDetection struct {
+	// ConsecutiveFailures zero means disabled
+	ConsecutiveFailures int
+	// FailureRate between 0 and 1, zero means disabled
+	FailureRate float64
+	// MinRequests is minimum number of requests within Interval for failure rate detection
+	MinRequests int
+	// Interval is time window for failure rate detection
+	Interval time.Duration
+	// BaseEjectionTime is the ejection time for the first time, and it doubles every time the node ejected again
+	BaseEjectionTime time.Duration
+	// MaxEjectionTime caps the ejection time
+	MaxEjectionTime time.Duration
+	// MaxEjectionPercent is maximum percentage of nodes which can be ejected at the same time.
+	// At least one node can be ejected and at least one node will be kept regardless of the value
+	MaxEjectionPercent int
+}
+
+// DefaultOutlierDetection returns OutlierDetection with default thresholds
+func DefaultOutlierDetection() OutlierDetection {
+	return OutlierDetection{
+		ConsecutiveFailures: 5,
+		FailureRate:         0.5,
+		MinRequests:         10,
+		Interval:            10 * time.Second,
+		BaseEjectionTime:    30 * time.Second,
+		MaxEjectionTime:     300 * time.Second,
+		MaxEjectionPercent:  50,
+	}
+}
+
+var nodeEjections = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "client_node_ejections_total",
+		Help: "Number of times service node ejected by outlier detection.",
+	},
+	[]string{"service", "node"},
+)
+
+var nodeEjected = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "client_node_ejected",
+		Help: "Whether service node is ejected by outlier detection or not.",
+	},
+	[]string{"service", "node"},
+)
+
+func init() {
+	prometheus.Register(nodeEjections)
+	prometheus.Register(nodeEjected)
+}
+
+// outlierStats is the state of a node for outlier detection
+type outlierStats struct {
+	consecutiveFailures int
+	windowStart         time.Time
+	windowRequests      int
+	windowFailures      int
+	// ejections is number of times the node ejected in a row, used for calculating ejection time
+	ejections    int
+	ejectedUntil time.Time
+	// ejected is 1 if the node is regarded as ejected by the last check, accessed atomically
+	ejected int32
+}
+
+func (s *server) isEjected(now time.Time) bool {
+	return now.Before(s.ejectedUntil)
+}
+
+// WithOutlierDetection enables passive outlier detection for service provider
+func WithOutlierDetection(od OutlierDetection) ProviderOption {
+	return func(sp registry.IServiceProvider) {
+		if p, ok := sp.(interface {
+			setOutlierDetection(od OutlierDetection)
+		}); ok {
+			p.setOutlierDetection(od)
+		}
+	}
+}
+
+func (m *base) setOutlierDetection(od OutlierDetection) {
+	m.outlier = &od
+}
+
+// ejectedCount returns number of nodes ejected at now, caller must hold the lock
+func (m *base) ejectedCount(now time.Time) int {
+	var count int
+	for _, s := range m.pool() {
+		if s.isEjected(now) {
+			count++
+		}
+	}
+	return count
+}
+
+// detect records outcome of a request sent to s and ejects s if it is an outlier, caller must hold the write lock
+func (m *base) detect(s *server, failed bool, now time.Time) {
+	od := m.outlier
+	if od == nil {
+		return
+	}
+	if od.Interval > 0 && now.Sub(s.windowStart) > od.Interval {
+		s.windowStart = now
+		s.windowRequests = 0
+		s.windowFailures = 0
+	}
+	s.windowRequests++
+	if !failed {
+		s.consecutiveFailures = 0
+		if !s.isEjected(now) && s.ejections > 0 && now.Sub(s.ejectedUntil) > od.MaxEjectionTime {
+			s.ejections = 0
+		}
+		return
+	}
+	s.consecutiveFailures++
+	s.windowFailures++
+	if s.isEjected(now) {
+		return
+	}
+	var reason string
+	if od.ConsecutiveFailures > 0 && s.consecutiveFailures >= od.ConsecutiveFailures {
+		reason = "consecutive failures"
+	} else if od.FailureRate > 0 && s.windowRequests >= od.MinRequests &&
+		float64(s.windowFailures)/float64(s.windowRequests) >= od.FailureRate {
+		reason = "failure rate"
+	}
+	if reason == "" {
+		return
+	}
+	pool := m.pool()
+	ejected := m.ejectedCount(now)
+	max := len(pool) * od.MaxEjectionPercent / 100
+	if max < 1 {
+		max = 1
+	}
+	if ejected >= max || ejected+1 >= len(pool) {
+		logger.Warnf("[go-doudou] node %s supplying %s service reached %s threshold, but max ejection reached", s.node, m.name, reason)
+		return
+	}
+	duration := od.BaseEjectionTime << uint(s.ejections)
+	if duration > od.MaxEjectionTime || duration <= 0 {
+		duration = od.MaxEjectionTime
+	}
+	s.ejections++
+	s.ejectedUntil = now.Add(duration)
+	s.consecutiveFailures = 0
+	s.windowStart = now
+	s.windowRequests = 0
+	s.windowFailures = 0
+	atomic.StoreInt32(&s.ejected, 1)
+	nodeEjections.WithLabelValues(m.name, s.node).Inc()
+	nodeEjected.WithLabelValues(m.name, s.node).Set(1)
+	logger.Warnf("[go-doudou] node %s supplying %s service ejected for %s because of %s", s.node, m.name, duration, reason)
+}
+
+// available returns nodes in pool not ejected, caller must hold the lock
+func (m *base) available() []*server {
+	pool := m.pool()
+	if m.outlier == nil {
+		return pool
+	}
+	now := time.Now()
+	ret := make([]*server, 0, len(pool))
+	for _, s := range pool {
+		if s.isEjected(now) {
+			continue
+		}
+		if atomic.CompareAndSwapInt32(&s.ejected, 1, 0) {
+			nodeEjected.WithLabelValues(m.name, s.node).Set(0)
+			logger.Infof("[go-doudou] node %s supplying %s service is back from ejection", s.node, m.name)
+		}
+		ret = append(ret, s)
+	}
+	return ret
+}
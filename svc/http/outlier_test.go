@@ -0,0 +1,136 @@
+package ddhttp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+	"time"
+)
+
+type testOutcome struct {
+	node   string
+	failed bool
+}
+
+func failures(node string, n int) []testOutcome {
+	var ret []testOutcome
+	for i := 0; i < n; i++ {
+		ret = append(ret, testOutcome{node, true})
+	}
+	return ret
+}
+
+func TestBase_detect(t *testing.T) {
+	od := OutlierDetection{
+		ConsecutiveFailures: 3,
+		BaseEjectionTime:    30 * time.Second,
+		MaxEjectionTime:     300 * time.Second,
+		MaxEjectionPercent:  50,
+	}
+	rate := OutlierDetection{
+		FailureRate:        0.5,
+		MinRequests:        4,
+		Interval:           10 * time.Second,
+		BaseEjectionTime:   30 * time.Second,
+		MaxEjectionTime:    300 * time.Second,
+		MaxEjectionPercent: 50,
+	}
+	capped := od
+	capped.MaxEjectionPercent = 25
+	all := od
+	all.MaxEjectionPercent = 100
+	tests := []struct {
+		name     string
+		od       OutlierDetection
+		nodes    int
+		outcomes []testOutcome
+		want     []string
+	}{
+		{"consecutive failures", od, 4, failures("node0", 3), []string{"node0"}},
+		{"below threshold", od, 4, failures("node0", 2), nil},
+		{"success resets consecutive failures", od, 4,
+			append(append(failures("node0", 2), testOutcome{"node0", false}), failures("node0", 2)...), nil},
+		{"failure rate", rate, 4,
+			[]testOutcome{{"node0", true}, {"node0", false}, {"node0", false}, {"node0", true}}, []string{"node0"}},
+		{"failure rate below min requests", rate, 4,
+			[]testOutcome{{"node0", true}, {"node0", false}, {"node0", true}}, nil},
+		{"max ejection percent", capped, 4, append(failures("node0", 3), failures("node1", 3)...), []string{"node0"}},
+		{"max ejection percent allows two", od, 4, append(failures("node0", 3), failures("node1", 3)...), []string{"node0", "node1"}},
+		{"keep at least one node", all, 2, append(failures("node0", 3), failures("node1", 3)...), []string{"node0"}},
+		{"eject at least one node", capped, 2, failures("node1", 3), []string{"node1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp := newTestP2CProvider(testNodes(tt.nodes), WithOutlierDetection(tt.od))
+			now := time.Now()
+			for _, o := range tt.outcomes {
+				now = now.Add(time.Millisecond)
+				sp.detect(sp.nodeMap[o.node], o.failed, now)
+			}
+			var ejected []string
+			for _, s := range sp.nodes {
+				if s.isEjected(now) {
+					ejected = append(ejected, s.node)
+				}
+			}
+			sort.Strings(ejected)
+			assert.Equal(t, tt.want, ejected)
+		})
+	}
+}
+
+func TestBase_detectEjectionTime(t *testing.T) {
+	od := OutlierDetection{
+		ConsecutiveFailures: 1,
+		BaseEjectionTime:    30 * time.Second,
+		MaxEjectionTime:     100 * time.Second,
+		MaxEjectionPercent:  50,
+	}
+	sp := newTestP2CProvider(testNodes(2), WithOutlierDetection(od))
+	s := sp.nodeMap["node0"]
+	now := time.Now()
+	// ejection time doubles every time the node is ejected again, and is capped by MaxEjectionTime
+	for _, want := range []time.Duration{30 * time.Second, 60 * time.Second, 100 * time.Second, 100 * time.Second} {
+		sp.detect(s, true, now)
+		assert.Equal(t, now.Add(want), s.ejectedUntil)
+		now = s.ejectedUntil.Add(time.Second)
+	}
+	// failures during ejection don't extend it
+	sp.detect(s, true, now)
+	until := s.ejectedUntil
+	sp.detect(s, true, now.Add(time.Second))
+	assert.Equal(t, until, s.ejectedUntil)
+	// ejection time is reset after the node stays healthy for MaxEjectionTime
+	now = until.Add(od.MaxEjectionTime + time.Second)
+	sp.detect(s, false, now)
+	assert.Equal(t, 0, s.ejections)
+}
+
+func TestBase_available(t *testing.T) {
+	sp := newTestP2CProvider(testNodes(3), WithOutlierDetection(DefaultOutlierDetection()))
+	for i := 0; i < 5; i++ {
+		sp.detect(sp.nodeMap["node1"], true, time.Now())
+	}
+	assert.True(t, sp.nodeMap["node1"].isEjected(time.Now()))
+	for i := 0; i < 30; i++ {
+		assert.NotEqual(t, testBaseUrl(6061), sp.SelectServer())
+	}
+	// the node is back after ejection time
+	sp.nodeMap["node1"].ejectedUntil = time.Now().Add(-time.Second)
+	assert.Len(t, sp.available(), 3)
+	assert.Equal(t, int32(0), sp.nodeMap["node1"].ejected)
+
+	// consistent hash provider only remaps keys of the ejected node
+	ch := newTestConsistentHashProvider(testNodes(3), WithOutlierDetection(DefaultOutlierDetection()))
+	before := keyMapping(ch, 300)
+	for i := 0; i < 5; i++ {
+		ch.detect(ch.nodeMap["node1"], true, time.Now())
+	}
+	for key, server := range before {
+		selected := ch.SelectServerByKey(key)
+		assert.NotEqual(t, testBaseUrl(6061), selected)
+		if server != testBaseUrl(6061) {
+			assert.Equal(t, server, selected)
+		}
+	}
+}
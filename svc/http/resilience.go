@@ -0,0 +1,265 @@
+package ddhttp
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/slok/goresilience"
+	"github.com/slok/goresilience/bulkhead"
+	"github.com/slok/goresilience/circuitbreaker"
+	"github.com/slok/goresilience/metrics"
+	"github.com/slok/goresilience/retry"
+	"github.com/slok/goresilience/timeout"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/logger"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResiliencePolicy configures the runner chain used by generated client proxies. Each field can be overridden by
+// environment variable GDD_CLIENT_<SERVICE>_<KEY> for all methods of the service, or GDD_CLIENT_<SERVICE>_<METHOD>_<KEY>
+// for a single method, service and method names are in upper case, such as GDD_CLIENT_USERSVC_GETUSER_CB_ERROR_PERCENT.
+type ResiliencePolicy struct {
+	// ErrorPercentThresholdToOpen overridden by CB_ERROR_PERCENT
+	ErrorPercentThresholdToOpen int
+	// MinimumRequestToOpen overridden by CB_MIN_REQUESTS
+	MinimumRequestToOpen int
+	// SuccessfulRequiredOnHalfOpen overridden by CB_HALF_OPEN_SUCCESSES
+	SuccessfulRequiredOnHalfOpen int
+	// WaitDurationInOpenState overridden by CB_OPEN_WAIT
+	WaitDurationInOpenState time.Duration
+	// MetricsSlidingWindowBucketQuantity overridden by CB_WINDOW_BUCKETS
+	MetricsSlidingWindowBucketQuantity int
+	// MetricsBucketDuration overridden by CB_BUCKET_DURATION
+	MetricsBucketDuration time.Duration
+	// Timeout of the whole call including retries, overridden by TIMEOUT
+	Timeout time.Duration
+	// Retries overridden by RETRY, zero means disabled
+	Retries int
+	// BulkheadWorkers limits concurrent calls, overridden by BULKHEAD_WORKERS, zero means disabled
+	BulkheadWorkers int
+	// BulkheadMaxWaitTime is max time a call waits for a worker, overridden by BULKHEAD_MAX_WAIT,
+	// zero means waiting until a worker is free
+	BulkheadMaxWaitTime time.Duration
+}
+
+// DefaultResiliencePolicy returns ResiliencePolicy with default values
+func DefaultResiliencePolicy() ResiliencePolicy {
+	return ResiliencePolicy{
+		ErrorPercentThresholdToOpen:        50,
+		MinimumRequestToOpen:               6,
+		SuccessfulRequiredOnHalfOpen:       1,
+		WaitDurationInOpenState:            5 * time.Second,
+		MetricsSlidingWindowBucketQuantity: 10,
+		MetricsBucketDuration:              1 * time.Second,
+		Timeout:                            3 * time.Minute,
+		Retries:                            3,
+	}
+}
+
+func envInt(env string, value *int) {
+	raw := os.Getenv(env)
+	if stringutils.IsEmpty(raw) {
+		return
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		logger.Warnf("[go-doudou] invalid value %s from %s, ignored", raw, env)
+		return
+	}
+	*value = n
+}
+
+func envDuration(env string, value *time.Duration) {
+	if d, ok := parseTimeout(env); ok {
+		*value = d
+	}
+}
+
+// override overrides fields of p by environment variables with prefix
+func (p ResiliencePolicy) override(prefix string) ResiliencePolicy {
+	envInt(prefix+"_CB_ERROR_PERCENT", &p.ErrorPercentThresholdToOpen)
+	envInt(prefix+"_CB_MIN_REQUESTS", &p.MinimumRequestToOpen)
+	envInt(prefix+"_CB_HALF_OPEN_SUCCESSES", &p.SuccessfulRequiredOnHalfOpen)
+	envDuration(prefix+"_CB_OPEN_WAIT", &p.WaitDurationInOpenState)
+	envInt(prefix+"_CB_WINDOW_BUCKETS", &p.MetricsSlidingWindowBucketQuantity)
+	envDuration(prefix+"_CB_BUCKET_DURATION", &p.MetricsBucketDuration)
+	envDuration(prefix+"_TIMEOUT", &p.Timeout)
+	envInt(prefix+"_RETRY", &p.Retries)
+	envInt(prefix+"_BULKHEAD_WORKERS", &p.BulkheadWorkers)
+	envDuration(prefix+"_BULKHEAD_MAX_WAIT", &p.BulkheadMaxWaitTime)
+	return p
+}
+
+var circuitBreakerState = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "client_circuit_breaker_state",
+		Help: "Current state of circuit breakers in client proxies, 1 for the current state and 0 for others.",
+	},
+	[]string{"runner", "state"},
+)
+
+func init() {
+	prometheus.Register(circuitBreakerState)
+}
+
+var circuitBreakerStates = []string{"closed", "open", "halfopen"}
+
+// stateRecorder logs and exposes circuit breaker state transitions
+type stateRecorder struct {
+	metrics.Recorder
+	id string
+}
+
+// WithID implements metrics.Recorder
+func (r stateRecorder) WithID(id string) metrics.Recorder {
+	return stateRecorder{
+		Recorder: r.Recorder.WithID(id),
+		id:       id,
+	}
+}
+
+// IncCircuitbreakerState implements metrics.Recorder
+func (r stateRecorder) IncCircuitbreakerState(state string) {
+	r.Recorder.IncCircuitbreakerState(state)
+	if state == "closed" {
+		logger.Infof("[go-doudou] circuit breaker of %s moved to %s state", r.id, state)
+	} else {
+		logger.Warnf("[go-doudou] circuit breaker of %s moved to %s state", r.id, state)
+	}
+	for _, item := range circuitBreakerStates {
+		var value float64
+		if item == state {
+			value = 1
+		}
+		circuitBreakerState.WithLabelValues(r.id, item).Set(value)
+	}
+}
+
+var (
+	resilienceRecorder     metrics.Recorder
+	resilienceRecorderOnce sync.Once
+)
+
+// recorderOf returns recorder of runner with id, goresilience metrics are registered to prometheus only once
+// and only if GDD_MANAGE_ENABLE is true
+func recorderOf(id string) metrics.Recorder {
+	resilienceRecorderOnce.Do(func() {
+		resilienceRecorder = metrics.Dummy
+		if config.GddManage.Load() == "true" {
+			resilienceRecorder = metrics.NewPrometheusRecorder(prometheus.DefaultRegisterer)
+		}
+	})
+	rec := stateRecorder{Recorder: resilienceRecorder}.WithID(id)
+	for _, item := range circuitBreakerStates {
+		var value float64
+		if item == "closed" {
+			value = 1
+		}
+		circuitBreakerState.WithLabelValues(id, item).Set(value)
+	}
+	return rec
+}
+
+func newRunner(id string, policy ResiliencePolicy) goresilience.Runner {
+	mid := []goresilience.Middleware{
+		metrics.NewMiddleware(id, recorderOf(id)),
+	}
+	if policy.BulkheadWorkers > 0 {
+		mid = append(mid, bulkhead.NewMiddleware(bulkhead.Config{
+			Workers:     policy.BulkheadWorkers,
+			MaxWaitTime: policy.BulkheadMaxWaitTime,
+		}))
+	}
+	mid = append(mid, circuitbreaker.NewMiddleware(circuitbreaker.Config{
+		ErrorPercentThresholdToOpen:        policy.ErrorPercentThresholdToOpen,
+		MinimumRequestToOpen:               policy.MinimumRequestToOpen,
+		SuccessfulRequiredOnHalfOpen:       policy.SuccessfulRequiredOnHalfOpen,
+		WaitDurationInOpenState:            policy.WaitDurationInOpenState,
+		MetricsSlidingWindowBucketQuantity: policy.MetricsSlidingWindowBucketQuantity,
+		MetricsBucketDuration:              policy.MetricsBucketDuration,
+	}), timeout.NewMiddleware(timeout.Config{
+		Timeout: policy.Timeout,
+	}))
+	if policy.Retries > 0 {
+		mid = append(mid, retry.NewMiddleware(retry.Config{
+			Times: policy.Retries,
+		}))
+	}
+	return goresilience.RunnerChain(mid...)
+}
+
+type resilienceMethodCtxKey struct{}
+
+// WithResilienceMethod returns a copy of ctx carrying method name, runners created by NewResilienceRunner run
+// functions with it by runner of the method if it has its own policy. Generated client proxies set it for each method.
+func WithResilienceMethod(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, resilienceMethodCtxKey{}, method)
+}
+
+// resilienceRunner runs functions by runner of the service, or by runner of the method if there is any environment
+// variable for the method
+type resilienceRunner struct {
+	service string
+	// id is id of goresilience metrics and client_circuit_breaker_state gauge, service name by default
+	id      string
+	prefix  string
+	policy  ResiliencePolicy
+	runner  goresilience.Runner
+	methods sync.Map
+}
+
+// ResilienceOption configures runner created by NewResilienceRunner
+type ResilienceOption func(*resilienceRunner)
+
+// WithMetricsID sets id of goresilience metrics and client_circuit_breaker_state gauge, runners of single methods
+// use id.<method>. Generated client proxies set it to <module>_client, the same as earlier versions, to keep
+// label values of existing dashboards.
+func WithMetricsID(id string) ResilienceOption {
+	return func(r *resilienceRunner) {
+		r.id = id
+	}
+}
+
+func (r *resilienceRunner) runnerOf(method string) goresilience.Runner {
+	if runner, ok := r.methods.Load(method); ok {
+		return runner.(goresilience.Runner)
+	}
+	var runner goresilience.Runner
+	if policy := r.policy.override(r.prefix + "_" + strings.ToUpper(method)); policy != r.policy {
+		runner = newRunner(r.id+"."+method, policy)
+	} else {
+		runner = r.runner
+	}
+	actual, _ := r.methods.LoadOrStore(method, runner)
+	return actual.(goresilience.Runner)
+}
+
+// Run implements goresilience.Runner
+func (r *resilienceRunner) Run(ctx context.Context, f goresilience.Func) error {
+	if method, ok := ctx.Value(resilienceMethodCtxKey{}).(string); ok && stringutils.IsNotEmpty(method) {
+		return r.runnerOf(method).Run(ctx, f)
+	}
+	return r.runner.Run(ctx, f)
+}
+
+// NewResilienceRunner creates a goresilience.Runner for client proxy of service, which chains metrics, bulkhead,
+// circuit breaker, timeout and retry middlewares configured by policy overridden by environment variables.
+// Circuit breaker state transitions are logged and exposed as client_circuit_breaker_state gauge.
+func NewResilienceRunner(service string, policy ResiliencePolicy, opts ...ResilienceOption) goresilience.Runner {
+	prefix := "GDD_CLIENT_" + strings.ToUpper(service)
+	r := &resilienceRunner{
+		service: service,
+		id:      service,
+		prefix:  prefix,
+		policy:  policy.override(prefix),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.runner = newRunner(r.id, r.policy)
+	return r
+}
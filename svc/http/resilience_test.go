@@ -0,0 +1,170 @@
+package ddhttp
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	gerrors "github.com/slok/goresilience/errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// setEnv sets environment variables, returns a func to unset them
+func setEnv(env map[string]string) func() {
+	for k, v := range env {
+		os.Setenv(k, v)
+	}
+	return func() {
+		for k := range env {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+func TestResiliencePolicy_override(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]string
+		modify func(p *ResiliencePolicy)
+	}{
+		{"none", nil, func(p *ResiliencePolicy) {}},
+		{"circuit breaker", map[string]string{
+			"GDD_CLIENT_TESTSVC_CB_ERROR_PERCENT":       "30",
+			"GDD_CLIENT_TESTSVC_CB_MIN_REQUESTS":        "20",
+			"GDD_CLIENT_TESTSVC_CB_HALF_OPEN_SUCCESSES": "3",
+			"GDD_CLIENT_TESTSVC_CB_OPEN_WAIT":           "10s",
+			"GDD_CLIENT_TESTSVC_CB_WINDOW_BUCKETS":      "5",
+			"GDD_CLIENT_TESTSVC_CB_BUCKET_DURATION":     "2s",
+		}, func(p *ResiliencePolicy) {
+			p.ErrorPercentThresholdToOpen = 30
+			p.MinimumRequestToOpen = 20
+			p.SuccessfulRequiredOnHalfOpen = 3
+			p.WaitDurationInOpenState = 10 * time.Second
+			p.MetricsSlidingWindowBucketQuantity = 5
+			p.MetricsBucketDuration = 2 * time.Second
+		}},
+		{"bulkhead", map[string]string{
+			"GDD_CLIENT_TESTSVC_BULKHEAD_WORKERS":  "8",
+			"GDD_CLIENT_TESTSVC_BULKHEAD_MAX_WAIT": "100ms",
+		}, func(p *ResiliencePolicy) {
+			p.BulkheadWorkers = 8
+			p.BulkheadMaxWaitTime = 100 * time.Millisecond
+		}},
+		{"timeout and retry", map[string]string{
+			"GDD_CLIENT_TESTSVC_TIMEOUT": "30s",
+			"GDD_CLIENT_TESTSVC_RETRY":   "0",
+		}, func(p *ResiliencePolicy) {
+			p.Timeout = 30 * time.Second
+			p.Retries = 0
+		}},
+		{"invalid", map[string]string{
+			"GDD_CLIENT_TESTSVC_CB_ERROR_PERCENT": "-1",
+			"GDD_CLIENT_TESTSVC_CB_OPEN_WAIT":     "10",
+			"GDD_CLIENT_TESTSVC_RETRY":            "a",
+		}, func(p *ResiliencePolicy) {}},
+		{"other service", map[string]string{
+			"GDD_CLIENT_ORDERSVC_RETRY": "5",
+		}, func(p *ResiliencePolicy) {}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer setEnv(tt.env)()
+			want := DefaultResiliencePolicy()
+			tt.modify(&want)
+			assert.Equal(t, want, DefaultResiliencePolicy().override("GDD_CLIENT_TESTSVC"))
+		})
+	}
+}
+
+var errTest = errors.New("test error")
+
+func fail(ctx context.Context) error {
+	return errTest
+}
+
+func succeed(ctx context.Context) error {
+	return nil
+}
+
+func TestNewResilienceRunner_CircuitBreaker(t *testing.T) {
+	defer setEnv(map[string]string{
+		"GDD_CLIENT_TESTSVC_CB_MIN_REQUESTS":         "2",
+		"GDD_CLIENT_TESTSVC_RETRY":                   "0",
+		"GDD_CLIENT_TESTSVC_GETUSER_CB_MIN_REQUESTS": "4",
+	})()
+	runner := NewResilienceRunner("Testsvc", DefaultResiliencePolicy())
+	getUser := WithResilienceMethod(context.Background(), "GetUser")
+	// runner of GetUser method is isolated and opens after 4 requests
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, errTest, runner.Run(getUser, fail))
+	}
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, errTest, runner.Run(context.Background(), fail))
+	}
+	assert.Equal(t, gerrors.ErrCircuitOpen, runner.Run(context.Background(), succeed))
+	assert.Equal(t, errTest, runner.Run(getUser, fail))
+	assert.Equal(t, gerrors.ErrCircuitOpen, runner.Run(getUser, succeed))
+	// methods without environment variables share runner of the service
+	assert.Equal(t, gerrors.ErrCircuitOpen, runner.Run(WithResilienceMethod(context.Background(), "PageUsers"), succeed))
+}
+
+func TestNewResilienceRunner_Bulkhead(t *testing.T) {
+	defer setEnv(map[string]string{
+		"GDD_CLIENT_TESTSVC_GETUSER_BULKHEAD_WORKERS":  "1",
+		"GDD_CLIENT_TESTSVC_GETUSER_BULKHEAD_MAX_WAIT": "10ms",
+	})()
+	runner := NewResilienceRunner("Testsvc", DefaultResiliencePolicy())
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{"limited", WithResilienceMethod(context.Background(), "GetUser"), gerrors.ErrTimeoutWaitingForExecution},
+		{"unlimited", WithResilienceMethod(context.Background(), "PageUsers"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				runner.Run(tt.ctx, func(ctx context.Context) error {
+					close(started)
+					<-release
+					return nil
+				})
+			}()
+			<-started
+			assert.Equal(t, tt.wantErr, runner.Run(tt.ctx, succeed))
+			close(release)
+			wg.Wait()
+		})
+	}
+}
+
+func TestWithMetricsID(t *testing.T) {
+	defer setEnv(map[string]string{
+		"GDD_CLIENT_TESTSVC_GETUSER_RETRY": "1",
+	})()
+	tests := []struct {
+		name   string
+		opts   []ResilienceOption
+		wantID string
+	}{
+		{"default", nil, "Testsvc"},
+		{"module client", []ResilienceOption{WithMetricsID("testsvc_client")}, "testsvc_client"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := NewResilienceRunner("Testsvc", DefaultResiliencePolicy(), tt.opts...)
+			assert.Equal(t, float64(1), testutil.ToFloat64(circuitBreakerState.WithLabelValues(tt.wantID, "closed")))
+			assert.NoError(t, runner.Run(WithResilienceMethod(context.Background(), "GetUser"), succeed))
+			assert.Equal(t, float64(1), testutil.ToFloat64(circuitBreakerState.WithLabelValues(tt.wantID+".GetUser", "closed")))
+		})
+	}
+}
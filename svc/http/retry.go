@@ -0,0 +1,227 @@
+package ddhttp
+
+import (
+	"context"
+	"github.com/go-resty/resty/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/unionj-cloud/cast"
+	"github.com/unionj-cloud/go-doudou/sliceutils"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/logger"
+	"github.com/unionj-cloud/go-doudou/svc/registry"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RetryPolicy configures retrying failed requests sent by http clients created by NewClient.
+// Each attempt waits for exponential backoff with jitter between WaitTime and MaxWaitTime, and is sent to
+// a node different from the ones tried before if there is any. Requests failed with error or status code in
+// RetryableStatusCodes will be retried only if their methods are in RetryableMethods or their contexts are
+// marked by WithRetryable.
+type RetryPolicy struct {
+	// MaxRetries zero means disabled
+	MaxRetries int
+	// WaitTime is the base backoff
+	WaitTime time.Duration
+	// MaxWaitTime caps the backoff
+	MaxWaitTime time.Duration
+	// RetryableMethods are idempotent http methods by default
+	RetryableMethods []string
+	// RetryableStatusCodes are status codes of responses which should be retried
+	RetryableStatusCodes []int
+	// BudgetRatio is maximum ratio of retries to requests within BudgetWindow, zero means no budget limit
+	BudgetRatio float64
+	// MinRetriesPerWindow allows some retries within BudgetWindow even if there are few requests
+	MinRetriesPerWindow int
+	// BudgetWindow is time window for counting requests and retries
+	BudgetWindow time.Duration
+}
+
+// DefaultRetryPolicy returns RetryPolicy with default values, MaxRetries is loaded from GDD_RETRY_COUNT
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:  cast.ToInt(config.GddRetryCount.Load()),
+		WaitTime:    100 * time.Millisecond,
+		MaxWaitTime: 2 * time.Second,
+		RetryableMethods: []string{
+			http.MethodGet,
+			http.MethodHead,
+			http.MethodOptions,
+			http.MethodPut,
+			http.MethodDelete,
+			http.MethodTrace,
+		},
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		BudgetRatio:         0.2,
+		MinRetriesPerWindow: 10,
+		BudgetWindow:        10 * time.Second,
+	}
+}
+
+var clientRetries = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "client_retries_total",
+		Help: "Number of retries of requests sent by http clients, result is allowed or budget_exhausted.",
+	},
+	[]string{"result"},
+)
+
+func init() {
+	prometheus.Register(clientRetries)
+}
+
+type retryableCtxKey struct{}
+
+// WithRetryable returns a copy of ctx marking requests with it as retryable regardless of their http methods
+func WithRetryable(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryableCtxKey{}, true)
+}
+
+func isRetryable(ctx context.Context) bool {
+	retryable, _ := ctx.Value(retryableCtxKey{}).(bool)
+	return retryable
+}
+
+type retryStateCtxKey struct{}
+
+// retryState records servers tried by a request and the provider selecting them
+type retryState struct {
+	lock     sync.Mutex
+	servers  []string
+	provider registry.IServiceProvider
+}
+
+func (s *retryState) record(provider registry.IServiceProvider, server string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.provider = provider
+	s.servers = append(s.servers, server)
+}
+
+// selectedBy returns the provider which selected the last tried server
+func (s *retryState) selectedBy() registry.IServiceProvider {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.provider
+}
+
+func (s *retryState) tried() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string{}, s.servers...)
+}
+
+func retryStateFromContext(ctx context.Context) *retryState {
+	if ctx == nil {
+		return nil
+	}
+	state, _ := ctx.Value(retryStateCtxKey{}).(*retryState)
+	return state
+}
+
+func excludeFilter(servers []string) NodeFilter {
+	return func(info registry.NodeInfo) bool {
+		return !sliceutils.StringContains(servers, info.BaseUrl)
+	}
+}
+
+// retryBudget limits retries to a ratio of requests within a time window
+type retryBudget struct {
+	lock        sync.Mutex
+	ratio       float64
+	minRetries  int
+	window      time.Duration
+	windowStart time.Time
+	requests    int
+	retries     int
+}
+
+func (b *retryBudget) roll(now time.Time) {
+	if now.Sub(b.windowStart) > b.window {
+		b.windowStart = now
+		b.requests = 0
+		b.retries = 0
+	}
+}
+
+func (b *retryBudget) request(now time.Time) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.roll(now)
+	b.requests++
+}
+
+// withdraw reports whether a retry is allowed and counts it
+func (b *retryBudget) withdraw(now time.Time) bool {
+	if b.ratio <= 0 {
+		return true
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.roll(now)
+	allowed := int(b.ratio * float64(b.requests))
+	if allowed < b.minRetries {
+		allowed = b.minRetries
+	}
+	if b.retries >= allowed {
+		return false
+	}
+	b.retries++
+	return true
+}
+
+func retryableStatus(codes []int, code int) bool {
+	for _, item := range codes {
+		if item == code {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRetryPolicy configures retry of client by policy
+func applyRetryPolicy(client *resty.Client, policy RetryPolicy) {
+	budget := &retryBudget{
+		ratio:      policy.BudgetRatio,
+		minRetries: policy.MinRetriesPerWindow,
+		window:     policy.BudgetWindow,
+	}
+	client.SetRetryCount(policy.MaxRetries)
+	client.SetRetryWaitTime(policy.WaitTime)
+	client.SetRetryMaxWaitTime(policy.MaxWaitTime)
+	client.OnBeforeRequest(func(_ *resty.Client, request *resty.Request) error {
+		if request.Attempt <= 1 {
+			budget.request(time.Now())
+			request.SetContext(withDeadline(context.WithValue(request.Context(), retryStateCtxKey{}, &retryState{})))
+		}
+		return nil
+	})
+	client.AddRetryCondition(func(resp *resty.Response, err error) bool {
+		if resp == nil || resp.Request == nil {
+			return false
+		}
+		req := resp.Request
+		if req.Context().Err() != nil || !time.Now().Before(deadlineOf(req.Context())) {
+			return false
+		}
+		if err == nil && !retryableStatus(policy.RetryableStatusCodes, resp.StatusCode()) {
+			return false
+		}
+		if !sliceutils.StringContains(policy.RetryableMethods, req.Method) && !isRetryable(req.Context()) {
+			return false
+		}
+		if !budget.withdraw(time.Now()) {
+			clientRetries.WithLabelValues("budget_exhausted").Inc()
+			logger.Warnf("[go-doudou] retry budget exhausted, give up retrying %s %s", req.Method, req.URL)
+			return false
+		}
+		clientRetries.WithLabelValues("allowed").Inc()
+		return true
+	})
+}
@@ -0,0 +1,144 @@
+package ddhttp
+
+import (
+	"context"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/memberlist"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBudget_withdraw(t *testing.T) {
+	tests := []struct {
+		name       string
+		ratio      float64
+		minRetries int
+		requests   int
+		want       int
+	}{
+		{"ratio", 0.2, 0, 50, 10},
+		{"min retries", 0.2, 5, 10, 5},
+		{"ratio over min retries", 0.5, 5, 20, 10},
+		{"no request", 0.2, 0, 0, 0},
+		{"unlimited", 0, 0, 0, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &retryBudget{ratio: tt.ratio, minRetries: tt.minRetries, window: 10 * time.Second}
+			now := time.Now()
+			for i := 0; i < tt.requests; i++ {
+				b.request(now)
+			}
+			var allowed int
+			for i := 0; i < 100; i++ {
+				if b.withdraw(now) {
+					allowed++
+				}
+			}
+			assert.Equal(t, tt.want, allowed)
+		})
+	}
+}
+
+func TestRetryBudget_roll(t *testing.T) {
+	b := &retryBudget{ratio: 0.5, window: 10 * time.Second}
+	now := time.Now()
+	b.request(now)
+	b.request(now)
+	assert.True(t, b.withdraw(now))
+	assert.False(t, b.withdraw(now.Add(5*time.Second)))
+	// requests and retries are reset in a new window
+	later := now.Add(11 * time.Second)
+	assert.False(t, b.withdraw(later))
+	b.request(later)
+	b.request(later)
+	assert.True(t, b.withdraw(later))
+}
+
+// testServerNode returns a fake node for httptest server ts
+func testServerNode(name string, ts *httptest.Server) *memberlist.Node {
+	return testNode(name, ts.Listener.Addr().(*net.TCPAddr).Port, nil, nil)
+}
+
+func TestApplyRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		// policy is modified based on DefaultRetryPolicy
+		policy    func(p *RetryPolicy)
+		method    string
+		retryable bool
+		// healthy is false if both nodes fail
+		healthy  bool
+		wantCode int
+		// wantHits are requests received by the failing node and the healthy one
+		wantHits [2]int32
+	}{
+		{"failover", nil, http.MethodGet, false, true, http.StatusOK, [2]int32{1, 1}},
+		{"not retryable method", nil, http.MethodPost, false, true, http.StatusServiceUnavailable, [2]int32{1, 0}},
+		{"retryable context", nil, http.MethodPost, true, true, http.StatusOK, [2]int32{1, 1}},
+		{"retryable method", func(p *RetryPolicy) {
+			p.RetryableMethods = append(p.RetryableMethods, http.MethodPost)
+		}, http.MethodPost, false, true, http.StatusOK, [2]int32{1, 1}},
+		{"not retryable status", func(p *RetryPolicy) {
+			p.RetryableStatusCodes = []int{http.StatusBadGateway}
+		}, http.MethodGet, false, true, http.StatusServiceUnavailable, [2]int32{1, 0}},
+		{"budget exhausted", func(p *RetryPolicy) {
+			p.MinRetriesPerWindow = 0
+		}, http.MethodGet, false, true, http.StatusServiceUnavailable, [2]int32{1, 0}},
+		{"disabled", func(p *RetryPolicy) {
+			p.MaxRetries = 0
+		}, http.MethodGet, false, true, http.StatusServiceUnavailable, [2]int32{1, 0}},
+		{"all failed", nil, http.MethodGet, false, false, http.StatusServiceUnavailable, [2]int32{2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits [2]int32
+			failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&hits[0], 1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer failing.Close()
+			other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&hits[1], 1)
+				if !tt.healthy {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer other.Close()
+			// round-robin selects the second node first
+			provider := newTestRoundRobinProvider([]*memberlist.Node{
+				testServerNode("other", other),
+				testServerNode("failing", failing),
+			})
+			policy := DefaultRetryPolicy()
+			policy.MaxRetries = 2
+			policy.WaitTime = time.Millisecond
+			policy.MaxWaitTime = 5 * time.Millisecond
+			if tt.policy != nil {
+				tt.policy(&policy)
+			}
+			client := resty.New()
+			applyRetryPolicy(client, policy)
+			client.OnBeforeRequest(func(_ *resty.Client, request *resty.Request) error {
+				request.URL = SelectServer(request.Context(), provider) + request.URL
+				return nil
+			})
+			ctx := context.Background()
+			if tt.retryable {
+				ctx = WithRetryable(ctx)
+			}
+			resp, err := client.R().SetContext(ctx).Execute(tt.method, "/user")
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.wantCode, resp.StatusCode())
+			assert.Equal(t, tt.wantHits[0], atomic.LoadInt32(&hits[0]))
+			assert.Equal(t, tt.wantHits[1], atomic.LoadInt32(&hits[1]))
+		})
+	}
+}
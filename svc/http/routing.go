@@ -0,0 +1,136 @@
+package ddhttp
+
+import (
+	"context"
+	"fmt"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/registry"
+	"net/http"
+)
+
+// CanaryHeader is the http header carrying target service version for canary routing
+const CanaryHeader = "x-canary"
+
+// NodeFilter reports whether a node should be selected by service providers
+type NodeFilter func(info registry.NodeInfo) bool
+
+// IFilterServiceProvider defines service provider interface which can select server from nodes matching filters
+type IFilterServiceProvider interface {
+	registry.IServiceProvider
+	SelectServerWithFilter(filters ...NodeFilter) string
+}
+
+// WithNodeFilter makes service provider only select nodes matching filter. Multiple filters can be set,
+// and a node should match all of them. If no node matches, the provider falls back to the whole pool.
+func WithNodeFilter(filter NodeFilter) ProviderOption {
+	return func(sp registry.IServiceProvider) {
+		if p, ok := sp.(interface {
+			addNodeFilter(filter NodeFilter)
+		}); ok && filter != nil {
+			p.addNodeFilter(filter)
+		}
+	}
+}
+
+// VersionFilter matches nodes with GDD_SERVICE_VERSION equal to version
+func VersionFilter(version string) NodeFilter {
+	return func(info registry.NodeInfo) bool {
+		return info.Version == version
+	}
+}
+
+// ZoneFilter matches nodes with GDD_ZONE equal to zone
+func ZoneFilter(zone string) NodeFilter {
+	return func(info registry.NodeInfo) bool {
+		return info.Zone == zone
+	}
+}
+
+// LabelFilter matches nodes whose custom data passed to registry.NewNode has key with value
+func LabelFilter(key, value string) NodeFilter {
+	return func(info registry.NodeInfo) bool {
+		v, ok := info.Data[key]
+		return ok && fmt.Sprint(v) == value
+	}
+}
+
+type canaryCtxKey struct{}
+
+// WithCanary returns a copy of ctx carrying version, generated clients will send requests with ctx
+// to nodes of the version if there is any, and propagate it by CanaryHeader to downstream services
+func WithCanary(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, canaryCtxKey{}, version)
+}
+
+// CanaryFromContext returns version set by WithCanary
+func CanaryFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	version, ok := ctx.Value(canaryCtxKey{}).(string)
+	return version, ok && stringutils.IsNotEmpty(version)
+}
+
+// Canary puts version from CanaryHeader of incoming request into request context,
+// so that calls to downstream services with the context will be steered to nodes of the version
+func Canary(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if version := r.Header.Get(CanaryHeader); stringutils.IsNotEmpty(version) {
+			r = r.WithContext(WithCanary(r.Context(), version))
+		}
+		inner.ServeHTTP(w, r)
+	})
+}
+
+// canaryTransport propagates version set by WithCanary to downstream services by CanaryHeader
+type canaryTransport struct {
+	http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *canaryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if version, ok := CanaryFromContext(req.Context()); ok && stringutils.IsEmpty(req.Header.Get(CanaryHeader)) {
+		req = req.Clone(req.Context())
+		req.Header.Set(CanaryHeader, version)
+	}
+	return t.RoundTripper.RoundTrip(req)
+}
+
+func (m *base) addNodeFilter(filter NodeFilter) {
+	m.filters = append(m.filters, filter)
+}
+
+func filterServers(nodes []*server, filters ...NodeFilter) []*server {
+	if len(filters) == 0 {
+		return nodes
+	}
+	ret := make([]*server, 0, len(nodes))
+OUTER:
+	for _, s := range nodes {
+		for _, filter := range filters {
+			if !filter(s.info) {
+				continue OUTER
+			}
+		}
+		ret = append(ret, s)
+	}
+	return ret
+}
+
+// candidates returns available nodes matching filters of the provider and then filters passed in one by one.
+// A filter is ignored if no node matches, so that all available nodes will be returned at last. Caller must hold the lock
+func (m *base) candidates(filters ...NodeFilter) []*server {
+	nodes := m.available()
+	if matched := filterServers(nodes, m.filters...); len(matched) > 0 {
+		nodes = matched
+	}
+	for _, filter := range filters {
+		if filter == nil {
+			continue
+		}
+		if matched := filterServers(nodes, filter); len(matched) > 0 {
+			nodes = matched
+		}
+	}
+	return nodes
+}
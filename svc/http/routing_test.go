@@ -0,0 +1,184 @@
+package ddhttp
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/svc/registry"
+	"github.com/unionj-cloud/memberlist"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+)
+
+func newTestRoundRobinProvider(nodes []*memberlist.Node, opts ...ProviderOption) *MemberlistServiceProvider {
+	sp := &MemberlistServiceProvider{
+		base: newTestBase(),
+	}
+	for _, opt := range opts {
+		opt(sp)
+	}
+	for _, node := range nodes {
+		sp.AddNode(node)
+	}
+	return sp
+}
+
+// routingNodes returns nodes of version v1 in zone a, v1 in zone b and v2 in zone b, node2 is labeled with env=gray
+func routingNodes() []*memberlist.Node {
+	return []*memberlist.Node{
+		testNode("node0", 6060, map[string]interface{}{"version": "v1", "zone": "a"}, nil),
+		testNode("node1", 6061, map[string]interface{}{"version": "v1", "zone": "b"}, nil),
+		testNode("node2", 6062, map[string]interface{}{"version": "v2", "zone": "b"}, map[string]interface{}{"env": "gray", "shard": 1}),
+	}
+}
+
+func TestNodeFilter(t *testing.T) {
+	info := registry.Info(routingNodes()[2])
+	tests := []struct {
+		name   string
+		filter NodeFilter
+		want   bool
+	}{
+		{"version matched", VersionFilter("v2"), true},
+		{"version mismatched", VersionFilter("v1"), false},
+		{"zone matched", ZoneFilter("b"), true},
+		{"zone mismatched", ZoneFilter("a"), false},
+		{"label matched", LabelFilter("env", "gray"), true},
+		{"label mismatched", LabelFilter("env", "prod"), false},
+		{"label missing", LabelFilter("region", "gray"), false},
+		{"label not string", LabelFilter("shard", "1"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter(info))
+		})
+	}
+}
+
+func TestBase_candidates(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []ProviderOption
+		filters []NodeFilter
+		want    []string
+	}{
+		{"no filter", nil, nil, []string{"node0", "node1", "node2"}},
+		{"provider filter", []ProviderOption{WithNodeFilter(ZoneFilter("b"))}, nil, []string{"node1", "node2"}},
+		{"provider filters all matched", []ProviderOption{WithNodeFilter(ZoneFilter("b")), WithNodeFilter(VersionFilter("v1"))},
+			nil, []string{"node1"}},
+		{"provider filter fallback", []ProviderOption{WithNodeFilter(ZoneFilter("c"))}, nil, []string{"node0", "node1", "node2"}},
+		{"request filter", nil, []NodeFilter{VersionFilter("v2")}, []string{"node2"}},
+		{"request filter within provider filter", []ProviderOption{WithNodeFilter(ZoneFilter("b"))},
+			[]NodeFilter{VersionFilter("v1")}, []string{"node1"}},
+		{"request filter fallback", []ProviderOption{WithNodeFilter(ZoneFilter("b"))},
+			[]NodeFilter{VersionFilter("v3")}, []string{"node1", "node2"}},
+		{"only unmatched request filter ignored", nil,
+			[]NodeFilter{VersionFilter("v1"), LabelFilter("env", "gray"), ZoneFilter("a")}, []string{"node0"}},
+		{"nil filter", []ProviderOption{WithNodeFilter(nil)}, []NodeFilter{nil}, []string{"node0", "node1", "node2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp := newTestRoundRobinProvider(routingNodes(), tt.opts...)
+			var got []string
+			for _, s := range sp.candidates(tt.filters...) {
+				got = append(got, s.node)
+			}
+			sort.Strings(got)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSelectServer_Canary(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider registry.IServiceProvider
+		version  string
+		want     []string
+	}{
+		{"round robin", newTestRoundRobinProvider(routingNodes()), "v2", []string{testBaseUrl(6062)}},
+		{"p2c", newTestP2CProvider(routingNodes()), "v2", []string{testBaseUrl(6062)}},
+		{"consistent hash", newTestConsistentHashProvider(routingNodes()), "v1", []string{testBaseUrl(6060), testBaseUrl(6061)}},
+		{"fallback", newTestRoundRobinProvider(routingNodes()), "v3", []string{testBaseUrl(6060), testBaseUrl(6061), testBaseUrl(6062)}},
+		{"no canary", newTestRoundRobinProvider(routingNodes()), "", []string{testBaseUrl(6060), testBaseUrl(6061), testBaseUrl(6062)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithCanary(context.Background(), tt.version)
+			selected := make(map[string]struct{})
+			for i := 0; i < 30; i++ {
+				selected[SelectServer(ctx, tt.provider)] = struct{}{}
+			}
+			var got []string
+			for url := range selected {
+				got = append(got, url)
+			}
+			sort.Strings(got)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCanary(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+		wantOk bool
+	}{
+		{"with header", "v2", "v2", true},
+		{"without header", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				got   string
+				gotOk bool
+			)
+			r := httptest.NewRequest(http.MethodGet, "/user", nil)
+			if tt.header != "" {
+				r.Header.Set(CanaryHeader, tt.header)
+			}
+			Canary(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, gotOk = CanaryFromContext(r.Context())
+			})).ServeHTTP(httptest.NewRecorder(), r)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantOk, gotOk)
+		})
+	}
+}
+
+func TestCanaryTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get(CanaryHeader)))
+	}))
+	defer ts.Close()
+	tests := []struct {
+		name    string
+		version string
+		header  string
+		want    string
+	}{
+		{"propagated", "v2", "", "v2"},
+		{"explicit header kept", "v2", "v3", "v3"},
+		{"no canary", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{Transport: &canaryTransport{http.DefaultTransport}}
+			req, _ := http.NewRequestWithContext(WithCanary(context.Background(), tt.version), http.MethodGet, ts.URL, nil)
+			if tt.header != "" {
+				req.Header.Set(CanaryHeader, tt.header)
+			}
+			resp, err := client.Do(req)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer resp.Body.Close()
+			body, _ := ioutil.ReadAll(resp.Body)
+			assert.Equal(t, tt.want, string(body))
+		})
+	}
+}
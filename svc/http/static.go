@@ -0,0 +1,245 @@
+package ddhttp
+
+import (
+	"github.com/pkg/errors"
+	"github.com/unionj-cloud/go-doudou/svc/logger"
+	"github.com/unionj-cloud/go-doudou/svc/registry"
+	"github.com/unionj-cloud/memberlist"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshInterval is default interval for StaticListProvider and DnsSrvProvider to reload nodes
+const DefaultRefreshInterval = 10 * time.Second
+
+// endpoint is a node not discovered by memberlist
+type endpoint struct {
+	name    string
+	baseUrl string
+	weight  int
+}
+
+// syncNodes replaces nodes with endpoints, states of nodes still existing are kept
+func (m *base) syncNodes(endpoints []endpoint) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	current := make(map[string]struct{}, len(endpoints))
+	for _, e := range endpoints {
+		current[e.name] = struct{}{}
+		weight := e.weight
+		if weight < 1 {
+			weight = 1
+		}
+		info := registry.NodeInfo{
+			SvcName:  m.name,
+			Hostname: e.name,
+			BaseUrl:  e.baseUrl,
+			Status:   "up",
+		}
+		if s, exists := m.nodeMap[e.name]; exists {
+			if s.baseUrl != e.baseUrl || s.weight != weight {
+				logger.Infof("[go-doudou] node %s update, supplying %s service, weight: %d", e.name, m.name, weight)
+			}
+			s.baseUrl = e.baseUrl
+			s.weight = weight
+			s.info = info
+			continue
+		}
+		s := &server{
+			service: m.name,
+			node:    e.name,
+			baseUrl: e.baseUrl,
+			weight:  weight,
+			info:    info,
+		}
+		m.nodes = append(m.nodes, s)
+		m.nodeMap[e.name] = s
+		logger.Infof("[go-doudou] node %s joined, supplying %s service", e.name, m.name)
+	}
+	nodes := make([]*server, 0, len(current))
+	for _, s := range m.nodes {
+		if _, ok := current[s.node]; ok {
+			nodes = append(nodes, s)
+			continue
+		}
+		delete(m.nodeMap, s.node)
+		deleteNodeMetrics(m.name, s.node)
+		logger.Infof("[go-doudou] node %s left, supplying %s service", s.node, m.name)
+	}
+	m.nodes = nodes
+	m.resubset()
+}
+
+// refresher reloads nodes periodically until closed
+type refresher struct {
+	interval time.Duration
+	done     chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+func (r *refresher) setRefreshInterval(interval time.Duration) {
+	r.interval = interval
+}
+
+func (r *refresher) run(reload func()) {
+	r.done = make(chan struct{})
+	r.stopped = make(chan struct{})
+	go func() {
+		defer close(r.stopped)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				select {
+				case <-r.done:
+					return
+				default:
+				}
+				reload()
+			case <-r.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops reloading nodes, and waits for reloading in progress to finish.
+// Closed provider is not referenced by go-doudou, so it can be garbage collected once clients using it are.
+func (r *refresher) Close() {
+	r.once.Do(func() {
+		if r.done != nil {
+			close(r.done)
+			<-r.stopped
+		}
+	})
+}
+
+// WithRefreshInterval sets interval for StaticListProvider and DnsSrvProvider to reload nodes
+func WithRefreshInterval(interval time.Duration) ProviderOption {
+	return func(sp registry.IServiceProvider) {
+		if p, ok := sp.(interface {
+			setRefreshInterval(interval time.Duration)
+		}); ok && interval > 0 {
+			p.setRefreshInterval(interval)
+		}
+	}
+}
+
+// parseStaticList parses comma or newline separated urls, each url can be followed by |weight
+// such as http://10.0.0.1:6060|2,http://10.0.0.2:6060
+func parseStaticList(value string) ([]endpoint, error) {
+	var endpoints []endpoint
+	items := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	})
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" || strings.HasPrefix(item, "#") {
+			continue
+		}
+		url, weight := item, 1
+		if idx := strings.LastIndex(item, "|"); idx >= 0 {
+			var err error
+			if weight, err = strconv.Atoi(strings.TrimSpace(item[idx+1:])); err != nil {
+				return nil, errors.Errorf("invalid weight of %s", item)
+			}
+			url = strings.TrimSpace(item[:idx])
+		}
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return nil, errors.Errorf("invalid url %s", url)
+		}
+		url = strings.TrimSuffix(url, "/")
+		endpoints = append(endpoints, endpoint{
+			name:    url,
+			baseUrl: url,
+			weight:  weight,
+		})
+	}
+	return endpoints, nil
+}
+
+// StaticListProvider is an IServiceProvider implementation for nodes listed in environment variable or file.
+// The list is reloaded on change, and nodes are selected by smooth weighted round-robin algo.
+type StaticListProvider struct {
+	SmoothWeightedRoundRobinProvider
+	refresher
+	load func() (string, error)
+	last string
+}
+
+// AddNode does nothing as nodes are not discovered by memberlist
+func (s *StaticListProvider) AddNode(node *memberlist.Node) {
+}
+
+// RemoveNode does nothing as nodes are not discovered by memberlist
+func (s *StaticListProvider) RemoveNode(node *memberlist.Node) {
+}
+
+// UpdateWeight does nothing as nodes are not discovered by memberlist
+func (s *StaticListProvider) UpdateWeight(node *memberlist.Node) {
+}
+
+func (s *StaticListProvider) reload() {
+	value, err := s.load()
+	if err != nil {
+		logger.Errorf("[go-doudou] load node list of %s service failed: %+v", s.name, err)
+		return
+	}
+	if value == s.last {
+		return
+	}
+	endpoints, err := parseStaticList(value)
+	if err != nil {
+		logger.Errorf("[go-doudou] parse node list of %s service failed: %+v", s.name, err)
+		return
+	}
+	s.last = value
+	s.syncNodes(endpoints)
+}
+
+func newStaticListProvider(name string, load func() (string, error), opts ...ProviderOption) *StaticListProvider {
+	sp := &StaticListProvider{
+		SmoothWeightedRoundRobinProvider: SmoothWeightedRoundRobinProvider{
+			base: base{
+				name:    name,
+				nodeMap: make(map[string]*server),
+			},
+		},
+		refresher: refresher{
+			interval: DefaultRefreshInterval,
+		},
+		load: load,
+	}
+	for _, opt := range opts {
+		opt(sp)
+	}
+	sp.reload()
+	sp.run(sp.reload)
+	return sp
+}
+
+// NewStaticListProviderFromEnv creates a StaticListProvider instance for nodes listed in environment variable env,
+// such as USERSVC=http://10.0.0.1:6060|2,http://10.0.0.2:6060
+func NewStaticListProviderFromEnv(name, env string, opts ...ProviderOption) *StaticListProvider {
+	return newStaticListProvider(name, func() (string, error) {
+		return os.Getenv(env), nil
+	}, opts...)
+}
+
+// NewStaticListProviderFromFile creates a StaticListProvider instance for nodes listed in file,
+// urls are separated by comma or newline, and lines starting with # are ignored
+func NewStaticListProviderFromFile(name, file string, opts ...ProviderOption) *StaticListProvider {
+	return newStaticListProvider(name, func() (string, error) {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", errors.Wrapf(err, "read file %s failed", file)
+		}
+		return string(data), nil
+	}, opts...)
+}
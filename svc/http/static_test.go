@@ -0,0 +1,156 @@
+package ddhttp
+
+import (
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// assertCollected asserts the provider created by newProvider can be garbage collected after closed,
+// which means it is not referenced by go-doudou anymore
+func assertCollected(t *testing.T, newProvider func() interface{ Close() }) {
+	collected := make(chan struct{})
+	func() {
+		sp := newProvider()
+		runtime.SetFinalizer(sp, func(interface{}) {
+			close(collected)
+		})
+		sp.Close()
+	}()
+	assert.Eventually(t, func() bool {
+		runtime.GC()
+		select {
+		case <-collected:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+}
+
+// nodeWeights returns weights of nodes by base url
+func nodeWeights(m *base) map[string]int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	ret := make(map[string]int)
+	for _, s := range m.nodes {
+		ret[s.baseUrl] = s.weight
+	}
+	return ret
+}
+
+func TestParseStaticList(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []endpoint
+		wantErr bool
+	}{
+		{"comma separated", "http://10.0.0.1:6060|2,http://10.0.0.2:6060", []endpoint{
+			{"http://10.0.0.1:6060", "http://10.0.0.1:6060", 2},
+			{"http://10.0.0.2:6060", "http://10.0.0.2:6060", 1},
+		}, false},
+		{"newline separated with comments", "# usersvc\nhttps://usersvc.com/api/ | 3\r\n\n  http://10.0.0.2:6060  \n", []endpoint{
+			{"https://usersvc.com/api", "https://usersvc.com/api", 3},
+			{"http://10.0.0.2:6060", "http://10.0.0.2:6060", 1},
+		}, false},
+		{"empty", "", nil, false},
+		{"invalid weight", "http://10.0.0.1:6060|a", nil, true},
+		{"invalid url", "10.0.0.1:6060", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStaticList(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewStaticListProviderFromEnv(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+	const env = "DDHTTP_TEST_STATIC_LIST"
+	os.Setenv(env, ts.URL+"|2,http://10.0.0.2:6060")
+	defer os.Unsetenv(env)
+	sp := NewStaticListProviderFromEnv(testService, env, WithRefreshInterval(10*time.Millisecond))
+	defer sp.Close()
+	assert.Equal(t, map[string]int{ts.URL: 2, "http://10.0.0.2:6060": 1}, nodeWeights(&sp.base))
+	counts := make(map[string]int)
+	for i := 0; i < 30; i++ {
+		counts[sp.SelectServer()]++
+	}
+	assert.Equal(t, map[string]int{ts.URL: 20, "http://10.0.0.2:6060": 10}, counts)
+
+	// the list is reloaded on change
+	os.Setenv(env, ts.URL)
+	assert.Eventually(t, func() bool {
+		return reflect.DeepEqual(map[string]int{ts.URL: 1}, nodeWeights(&sp.base))
+	}, time.Second, 10*time.Millisecond)
+	client := resty.New()
+	client.OnBeforeRequest(func(_ *resty.Client, request *resty.Request) error {
+		request.URL = SelectServer(request.Context(), sp) + request.URL
+		return nil
+	})
+	resp, err := client.R().Get("/hello")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "hello", resp.String())
+	}
+
+	// invalid list is ignored
+	os.Setenv(env, "10.0.0.3:6060")
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, map[string]int{ts.URL: 1}, nodeWeights(&sp.base))
+}
+
+func TestNewStaticListProviderFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ddhttp")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "usersvc.list")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("# usersvc\nhttp://10.0.0.1:6060|2\nhttp://10.0.0.2:6060\n"), 0644))
+	sp := NewStaticListProviderFromFile(testService, file, WithRefreshInterval(10*time.Millisecond))
+	defer sp.Close()
+	assert.Equal(t, map[string]int{"http://10.0.0.1:6060": 2, "http://10.0.0.2:6060": 1}, nodeWeights(&sp.base))
+
+	// states of nodes still existing are kept
+	sp.RequestStarted("http://10.0.0.2:6060/user")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("http://10.0.0.2:6060|3\nhttp://10.0.0.3:6060\n"), 0644))
+	want := map[string]int{"http://10.0.0.2:6060": 3, "http://10.0.0.3:6060": 1}
+	assert.Eventually(t, func() bool {
+		return reflect.DeepEqual(want, nodeWeights(&sp.base))
+	}, time.Second, 10*time.Millisecond)
+	sp.lock.RLock()
+	assert.Equal(t, int64(1), sp.nodeMap["http://10.0.0.2:6060"].inflight)
+	sp.lock.RUnlock()
+
+	// nodes are kept if the file can't be read
+	assert.NoError(t, os.Remove(file))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, want, nodeWeights(&sp.base))
+
+	// nodes are not reloaded after closed
+	sp.Close()
+	assert.NoError(t, ioutil.WriteFile(file, []byte("http://10.0.0.4:6060\n"), 0644))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, want, nodeWeights(&sp.base))
+	assertCollected(t, func() interface{ Close() } {
+		return NewStaticListProviderFromFile(testService, file, WithRefreshInterval(10*time.Millisecond))
+	})
+}
@@ -0,0 +1,108 @@
+package ddhttp
+
+import (
+	"github.com/unionj-cloud/go-doudou/loadbalance"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/logger"
+	"github.com/unionj-cloud/go-doudou/svc/registry"
+	"os"
+)
+
+// WithSubset makes service provider only select nodes from a subset of size nodes, so that each client
+// doesn't have to open connections to every node in a large cluster. The subset is picked by rendezvous hashing
+// on memberlist name of local node, so it is stable and only changes minimally when nodes join or leave.
+// Providers created before registry.NewNode hash on GDD_MEM_NAME or hostname, and recompute the subset on next
+// selection once the local node is created with a different name.
+func WithSubset(size int) ProviderOption {
+	return func(sp registry.IServiceProvider) {
+		if p, ok := sp.(interface {
+			setSubsetSize(size int)
+		}); ok && size > 0 {
+			p.setSubsetSize(size)
+		}
+	}
+}
+
+func (m *base) setSubsetSize(size int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.subsetSize = size
+	m.resubset()
+}
+
+// subsetClient returns name of local node for rendezvous hashing
+func subsetClient() string {
+	if local := registry.LocalNode(); local != nil {
+		return local.Name
+	}
+	if name := config.GddMemName.Load(); name != "" {
+		return name
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// subsetStale reports whether subset was picked for a client name different from the current one
+func (m *base) subsetStale() bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.subsetSize > 0 && m.subsetClient != subsetClient()
+}
+
+// refreshSubset recomputes subset if local node name changed since it was picked, returns true if recomputed
+func (m *base) refreshSubset() bool {
+	if !m.subsetStale() {
+		return false
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.resubset()
+	return true
+}
+
+// resubset recomputes subset of nodes, caller must hold the write lock
+func (m *base) resubset() {
+	if m.subsetSize <= 0 {
+		return
+	}
+	client := subsetClient()
+	m.subsetClient = client
+	names := make([]string, 0, len(m.nodes))
+	for _, s := range m.nodes {
+		names = append(names, s.node)
+	}
+	selected := make(map[string]struct{})
+	for _, name := range loadbalance.RendezvousSubset(names, client, m.subsetSize) {
+		selected[name] = struct{}{}
+	}
+	subset := make([]*server, 0, len(selected))
+	for _, s := range m.nodes {
+		if _, ok := selected[s.node]; ok {
+			subset = append(subset, s)
+		}
+	}
+	if !sameServers(m.subset, subset) {
+		logger.Infof("[go-doudou] subset of %s service changed to %d of %d nodes", m.name, len(subset), len(m.nodes))
+	}
+	m.subset = subset
+}
+
+func sameServers(a, b []*server) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pool returns nodes in subset if subsetting enabled, otherwise all nodes. Caller must hold the lock
+func (m *base) pool() []*server {
+	if m.subsetSize > 0 {
+		return m.subset
+	}
+	return m.nodes
+}
@@ -0,0 +1,157 @@
+package ddhttp
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/loadbalance"
+	"github.com/unionj-cloud/go-doudou/sliceutils"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/registry"
+	"github.com/unionj-cloud/memberlist"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// setMemName sets GDD_MEM_NAME which is used for subsetting as there is no local node in tests, returns a func to restore it
+func setMemName(name string) func() {
+	old := config.GddMemName.Load()
+	_ = config.GddMemName.Write(name)
+	return func() {
+		_ = config.GddMemName.Write(old)
+	}
+}
+
+func testNodeNames(n int) []string {
+	var names []string
+	for i := 0; i < n; i++ {
+		names = append(names, fmt.Sprintf("node%d", i))
+	}
+	return names
+}
+
+// subsetUrls returns sorted base urls of the rendezvous subset of nodes named by testNodes for client
+func subsetUrls(names []string, client string, size int) []string {
+	var urls []string
+	for _, name := range loadbalance.RendezvousSubset(names, client, size) {
+		var i int
+		fmt.Sscanf(name, "node%d", &i)
+		urls = append(urls, testBaseUrl(6060+i))
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// selectedUrls returns sorted base urls selected by provider within 100 selections
+func selectedUrls(provider registry.IServiceProvider, ctx func(i int) context.Context) []string {
+	selected := make(map[string]struct{})
+	for i := 0; i < 100; i++ {
+		selected[SelectServer(ctx(i), provider)] = struct{}{}
+	}
+	var urls []string
+	for url := range selected {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+func noKey(int) context.Context {
+	return context.Background()
+}
+
+func withKey(i int) context.Context {
+	return WithHashKey(context.Background(), fmt.Sprintf("user%d", i))
+}
+
+type subsetProviderCase struct {
+	name string
+	new  func(nodes []*memberlist.Node) registry.IServiceProvider
+	ctx  func(i int) context.Context
+}
+
+func subsetProviderCases() []subsetProviderCase {
+	return []subsetProviderCase{
+		{"round robin", func(nodes []*memberlist.Node) registry.IServiceProvider {
+			return newTestRoundRobinProvider(nodes, WithSubset(3))
+		}, noKey},
+		{"smooth weighted round robin", func(nodes []*memberlist.Node) registry.IServiceProvider {
+			sp := &SmoothWeightedRoundRobinProvider{base: newTestBase()}
+			WithSubset(3)(sp)
+			for _, node := range nodes {
+				sp.AddNode(node)
+			}
+			return sp
+		}, noKey},
+		{"p2c", func(nodes []*memberlist.Node) registry.IServiceProvider {
+			return newTestP2CProvider(nodes, WithSubset(3))
+		}, noKey},
+		{"consistent hash", func(nodes []*memberlist.Node) registry.IServiceProvider {
+			return newTestConsistentHashProvider(nodes, WithSubset(3))
+		}, withKey},
+	}
+}
+
+func TestWithSubset(t *testing.T) {
+	defer setMemName("client0")()
+	names := testNodeNames(8)
+	want := subsetUrls(names, "client0", 3)
+	for _, tt := range subsetProviderCases() {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, want, selectedUrls(tt.new(testNodes(8)), tt.ctx))
+		})
+	}
+}
+
+func TestWithSubset_JoinLeave(t *testing.T) {
+	defer setMemName("client0")()
+	names := testNodeNames(8)
+	before := subsetUrls(names, "client0", 3)
+	for _, tt := range subsetProviderCases() {
+		t.Run(tt.name, func(t *testing.T) {
+			sp := tt.new(testNodes(8))
+			var in, out []int
+			for i := range names {
+				if sliceutils.StringContains(before, testBaseUrl(6060+i)) {
+					in = append(in, i)
+				} else {
+					out = append(out, i)
+				}
+			}
+			// subset doesn't change if a node out of it leaves
+			sp.RemoveNode(testNode(names[out[0]], 6060+out[0], nil, nil))
+			assert.Equal(t, before, selectedUrls(sp, tt.ctx))
+			// only the node in subset which left is replaced
+			sp.RemoveNode(testNode(names[in[0]], 6060+in[0], nil, nil))
+			after := selectedUrls(sp, tt.ctx)
+			assert.Len(t, after, 3)
+			assert.NotContains(t, after, testBaseUrl(6060+in[0]))
+			for _, i := range in[1:] {
+				assert.Contains(t, after, testBaseUrl(6060+i))
+			}
+		})
+	}
+}
+
+func TestWithSubset_LocalNameChanged(t *testing.T) {
+	restore := setMemName("client0")
+	defer restore()
+	names := testNodeNames(8)
+	before := subsetUrls(names, "client0", 3)
+	// find a name with a different subset, like memberlist name of local node created later
+	changed := "client1"
+	for i := 1; reflect.DeepEqual(before, subsetUrls(names, changed, 3)); i++ {
+		changed = fmt.Sprintf("client%d", i)
+	}
+	after := subsetUrls(names, changed, 3)
+	for _, tt := range subsetProviderCases() {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = config.GddMemName.Write("client0")
+			sp := tt.new(testNodes(8))
+			assert.Equal(t, before, selectedUrls(sp, tt.ctx))
+			_ = config.GddMemName.Write(changed)
+			assert.Equal(t, after, selectedUrls(sp, tt.ctx))
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package ddhttp
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/http/onlinedoc"
+	"github.com/unionj-cloud/go-doudou/svc/logger"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// maxValidatedResponseSize is the max size of response body buffered for validation in debug mode,
+// larger responses are written through without validation
+const maxValidatedResponseSize = 1 << 20
+
+type oasContract struct {
+	validator  *v3.Validator
+	router     *mux.Router
+	operations map[string]*v3.Operation
+}
+
+var (
+	contract     *oasContract
+	contractOnce sync.Once
+)
+
+// loadContract parses onlinedoc.Oas only once
+func loadContract() *oasContract {
+	contractOnce.Do(func() {
+		if stringutils.IsEmpty(onlinedoc.Oas) {
+			logger.Warnln("[go-doudou] onlinedoc.Oas is empty, request validation is disabled")
+			return
+		}
+		var api v3.API
+		if err := json.Unmarshal([]byte(onlinedoc.Oas), &api); err != nil {
+			logger.Errorf("[go-doudou] parse onlinedoc.Oas failed, request validation is disabled: %s\n", err)
+			return
+		}
+		c := &oasContract{
+			validator:  v3.NewValidator(api),
+			router:     mux.NewRouter(),
+			operations: v3.Operations(api),
+		}
+		rootPath := config.GddRouteRootPath.Load()
+		for key := range c.operations {
+			splits := strings.SplitN(key, " ", 2)
+			c.router.Methods(splits[0]).Path(path.Clean(rootPath + splits[1])).Name(key)
+		}
+		contract = c
+	})
+	return contract
+}
+
+func (c *oasContract) match(r *http.Request) (*v3.Operation, map[string]string) {
+	var match mux.RouteMatch
+	if !c.router.Match(r, &match) || match.Route == nil {
+		return nil, nil
+	}
+	return c.operations[match.Route.GetName()], match.Vars
+}
+
+func writeValidationErrors(w http.ResponseWriter, errs v3.ValidationErrors) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(struct {
+		Errors v3.ValidationErrors `json:"errors"`
+	}{
+		Errors: errs,
+	})
+}
+
+// Validation validates query string parameters and request bodies against OpenAPI3.0 document stored in onlinedoc.Oas,
+// and responds 400 with violations as json if there is any. Requests not defined in the document will be passed through.
+// If GDD_LOG_LEVEL is debug, json responses up to 1MB will also be validated, and contract drift will be logged as warning.
+// Other responses such as file downloads and streams are written through without buffering.
+func Validation(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := loadContract()
+		if c == nil {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		op, vars := c.match(r)
+		if op == nil {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		errs := c.validator.ValidateParams(op, vars, r.URL.Query(), r.Header)
+		errs = append(errs, c.validator.ValidateRequestBody(op, r)...)
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+		if config.GddLogLevel.Load() != "debug" {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		bw := newBufferedWriter(w, func(status int, header http.Header) bool {
+			return status != http.StatusOK || !strings.Contains(header.Get("Content-Type"), "application/json")
+		})
+		bw.maxSize = maxValidatedResponseSize
+		inner.ServeHTTP(bw, r)
+		if !bw.streaming {
+			if drift := c.validator.ValidateResponse(op, bw.Header().Get("Content-Type"), bw.buf.Bytes()); len(drift) > 0 {
+				logger.Warnf("[go-doudou] response of %s %s does not match OpenAPI3.0 document: %s\n", r.Method, r.URL.Path, drift.Error())
+			}
+		}
+		bw.flush()
+	})
+}
@@ -0,0 +1,178 @@
+package ddhttp
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/svc/http/onlinedoc"
+	"github.com/unionj-cloud/go-doudou/svc/logger"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testOas = `{
+  "openapi": "3.0.2",
+  "paths": {
+    "/users": {
+      "post": {
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/User"}
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {"id": {"type": "integer"}},
+                  "required": ["id"]
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/users/{id}": {
+      "get": {
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}},
+          {"name": "verbose", "in": "query", "schema": {"type": "boolean"}}
+        ]
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "User": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string"},
+          "age": {"type": "integer", "minimum": 0}
+        },
+        "required": ["name"]
+      }
+    }
+  }
+}`
+
+func setupContract(oas string) {
+	onlinedoc.Oas = oas
+	contract = nil
+	contractOnce = sync.Once{}
+}
+
+func TestValidation(t *testing.T) {
+	setupContract(testOas)
+	defer setupContract("")
+	_ = config.GddLogLevel.Write("info")
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":1}`))
+	})
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{"valid body", http.MethodPost, "/users", `{"name":"jack","age":18}`, http.StatusOK, `{"id":1}`},
+		{"missing required property", http.MethodPost, "/users", `{"age":18}`, http.StatusBadRequest, `"field":"name"`},
+		{"violate minimum", http.MethodPost, "/users", `{"name":"jack","age":-1}`, http.StatusBadRequest, `"field":"age"`},
+		{"invalid json", http.MethodPost, "/users", `{"name"`, http.StatusBadRequest, `must be valid json`},
+		{"empty required body", http.MethodPost, "/users", ``, http.StatusBadRequest, `is required`},
+		{"valid params", http.MethodGet, "/users/1?verbose=true", ``, http.StatusOK, `{"id":1}`},
+		{"invalid path param", http.MethodGet, "/users/abc", ``, http.StatusBadRequest, `"field":"id"`},
+		{"invalid query param", http.MethodGet, "/users/1?verbose=maybe", ``, http.StatusBadRequest, `"field":"verbose"`},
+		{"undocumented", http.MethodDelete, "/users/1", ``, http.StatusOK, `{"id":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			Validation(inner).ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+		})
+	}
+}
+
+func TestValidationResponse(t *testing.T) {
+	setupContract(testOas)
+	defer setupContract("")
+	_ = config.GddLogLevel.Write("debug")
+	defer config.GddLogLevel.Write("info")
+	hook := test.NewLocal(logger.Entry().Logger)
+	defer hook.Reset()
+	largePad := strings.Repeat("a", maxValidatedResponseSize)
+	tests := []struct {
+		name          string
+		inner         http.HandlerFunc
+		wantCode      int
+		wantBody      string
+		wantDrift     bool
+		wantStreaming bool
+	}{
+		{"matched", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":1}`))
+		}, http.StatusOK, `{"id":1}`, false, false},
+		{"drift", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"1"}`))
+		}, http.StatusOK, `{"id":"1"}`, true, false},
+		{"error status is not checked", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`oops`))
+		}, http.StatusInternalServerError, `oops`, false, true},
+		{"streaming is not buffered", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":`))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(`"1"}`))
+		}, http.StatusOK, `{"id":"1"}`, false, true},
+		{"file download is not buffered", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte(`file content`))
+		}, http.StatusOK, `file content`, false, true},
+		{"large response is not validated", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"1","pad":"`))
+			w.Write([]byte(largePad))
+			w.Write([]byte(`"}`))
+		}, http.StatusOK, `{"id":"1","pad":"` + largePad + `"}`, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook.Reset()
+			var streaming bool
+			inner := func(w http.ResponseWriter, r *http.Request) {
+				tt.inner(w, r)
+				streaming = w.(*bufferedWriter).streaming
+			}
+			w := httptest.NewRecorder()
+			Validation(http.HandlerFunc(inner)).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"jack"}`)))
+			assert.Equal(t, tt.wantStreaming, streaming)
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantBody, w.Body.String())
+			var drift bool
+			for _, entry := range hook.AllEntries() {
+				if entry.Level == logrus.WarnLevel && strings.Contains(entry.Message, "does not match OpenAPI3.0 document") {
+					drift = true
+				}
+			}
+			assert.Equal(t, tt.wantDrift, drift)
+		})
+	}
+}
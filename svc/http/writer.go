@@ -0,0 +1,91 @@
+package ddhttp
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// bufferedWriter buffers response written by inner handler, so that middleware can inspect it before it is sent.
+// Header is shared with the underlying http.ResponseWriter. Response is written through without buffering
+// if passThrough returns true when status code is written, if it is text/event-stream, since inner handler
+// calls Flush, or since it grows over maxSize if maxSize is positive, so streaming and large responses are not held in memory.
+type bufferedWriter struct {
+	http.ResponseWriter
+	passThrough func(status int, header http.Header) bool
+	maxSize     int
+	status      int
+	buf         bytes.Buffer
+	streaming   bool
+}
+
+func newBufferedWriter(w http.ResponseWriter, passThrough func(status int, header http.Header) bool) *bufferedWriter {
+	return &bufferedWriter{
+		ResponseWriter: w,
+		passThrough:    passThrough,
+	}
+}
+
+// WriteHeader implements http.ResponseWriter
+func (bw *bufferedWriter) WriteHeader(status int) {
+	if bw.status != 0 {
+		return
+	}
+	bw.status = status
+	if strings.HasPrefix(bw.Header().Get("Content-Type"), "text/event-stream") ||
+		(bw.passThrough != nil && bw.passThrough(status, bw.Header())) {
+		bw.stream()
+	}
+}
+
+// Write implements http.ResponseWriter
+func (bw *bufferedWriter) Write(p []byte) (int, error) {
+	if bw.status == 0 {
+		bw.WriteHeader(http.StatusOK)
+	}
+	if !bw.streaming && bw.maxSize > 0 && bw.buf.Len()+len(p) > bw.maxSize {
+		bw.stream()
+	}
+	if bw.streaming {
+		return bw.ResponseWriter.Write(p)
+	}
+	return bw.buf.Write(p)
+}
+
+// Flush implements http.Flusher, response is written through since then
+func (bw *bufferedWriter) Flush() {
+	if bw.status == 0 {
+		bw.WriteHeader(http.StatusOK)
+	}
+	bw.stream()
+	if f, ok := bw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (bw *bufferedWriter) stream() {
+	if bw.streaming {
+		return
+	}
+	bw.streaming = true
+	bw.ResponseWriter.WriteHeader(bw.status)
+	bw.buf.WriteTo(bw.ResponseWriter)
+}
+
+// statusCode returns status code written by inner handler, 200 if it is not written explicitly
+func (bw *bufferedWriter) statusCode() int {
+	if bw.status == 0 {
+		return http.StatusOK
+	}
+	return bw.status
+}
+
+// flush sends buffered response to the underlying http.ResponseWriter if it has not been written through
+func (bw *bufferedWriter) flush() {
+	if bw.streaming {
+		return
+	}
+	bw.streaming = true
+	bw.ResponseWriter.WriteHeader(bw.statusCode())
+	bw.buf.WriteTo(bw.ResponseWriter)
+}
@@ -0,0 +1,276 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// annotation represents a line like @name(param1, param2) in comments of service interface or its methods in svc.go file
+type annotation struct {
+	Name   string
+	Params []string
+}
+
+var annotationReg = regexp.MustCompile(`^@(\w+)\((.*)\)$`)
+
+// parseAnnotations separates annotations from other comment lines
+func parseAnnotations(comments []string) ([]annotation, []string) {
+	var (
+		annotations []annotation
+		rest        []string
+	)
+	for _, comment := range comments {
+		matches := annotationReg.FindStringSubmatch(strings.TrimSpace(comment))
+		if len(matches) == 0 {
+			rest = append(rest, comment)
+			continue
+		}
+		var params []string
+		for _, item := range strings.Split(matches[2], ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				params = append(params, item)
+			}
+		}
+		annotations = append(annotations, annotation{
+			Name:   matches[1],
+			Params: params,
+		})
+	}
+	return annotations, rest
+}
+
+func filterAnnotations(annotations []annotation, name string) []annotation {
+	var ret []annotation
+	for _, item := range annotations {
+		if item.Name == name {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+// securitySchemeOf converts @securityScheme annotation to v3.SecurityScheme. Supported forms are:
+//
+//	@securityScheme(bearerAuth, bearer)
+//	@securityScheme(basicAuth, basic)
+//	@securityScheme(apiKeyAuth, apiKey, header, X-API-KEY)
+//	@securityScheme(apiKeyAuth, apiKey, query, api_key)
+func securitySchemeOf(a annotation) (string, v3.SecurityScheme) {
+	if len(a.Params) < 2 {
+		panic(fmt.Errorf("@securityScheme requires name and type, got @securityScheme(%s)", strings.Join(a.Params, ", ")))
+	}
+	name, typ := a.Params[0], a.Params[1]
+	switch strings.ToLower(typ) {
+	case "bearer":
+		return name, v3.SecurityScheme{
+			Type:         v3.HTTPS,
+			Scheme:       "bearer",
+			BearerFormat: "JWT",
+		}
+	case "basic":
+		return name, v3.SecurityScheme{
+			Type:   v3.HTTPS,
+			Scheme: "basic",
+		}
+	case "apikey":
+		if len(a.Params) < 4 {
+			panic(fmt.Errorf("@securityScheme(%s, apiKey) requires in and parameter name", name))
+		}
+		in := v3.In(strings.ToLower(a.Params[2]))
+		if in != v3.InHeader && in != v3.InQuery && in != v3.InCookie {
+			panic(fmt.Errorf("@securityScheme(%s, apiKey) not support %s", name, a.Params[2]))
+		}
+		return name, v3.SecurityScheme{
+			Type: v3.APIKeyS,
+			In:   in,
+			Name: a.Params[3],
+		}
+	default:
+		panic(fmt.Errorf("@securityScheme(%s) not support type %s, only bearer, basic and apiKey are supported", name, typ))
+	}
+}
+
+// securityOf converts @security annotations to security requirements.
+// Schemes listed in one annotation are all required, different annotations are alternatives.
+func securityOf(annotations []annotation) []v3.Security {
+	var ret []v3.Security
+	for _, item := range filterAnnotations(annotations, "security") {
+		requirement := make(v3.Security)
+		for _, name := range item.Params {
+			requirement[name] = []string{}
+		}
+		ret = append(ret, requirement)
+	}
+	return ret
+}
+
+// securitySchemesOf collects security schemes and global security requirements from comments of service interface
+func securitySchemesOf(inter astutils.InterfaceMeta) (map[string]v3.SecurityScheme, []v3.Security) {
+	annotations, _ := parseAnnotations(inter.Comments)
+	var schemes map[string]v3.SecurityScheme
+	for _, item := range filterAnnotations(annotations, "securityScheme") {
+		if schemes == nil {
+			schemes = make(map[string]v3.SecurityScheme)
+		}
+		name, scheme := securitySchemeOf(item)
+		schemes[name] = scheme
+	}
+	return schemes, securityOf(annotations)
+}
+
+// checkSecurity panics if any security requirement refers to undefined security scheme
+func checkSecurity(api v3.API) {
+	var schemes map[string]v3.SecurityScheme
+	if api.Components != nil {
+		schemes = api.Components.SecuritySchemes
+	}
+	requirements := append([]v3.Security{}, api.Security...)
+	ops := v3.Operations(api)
+	keys := make([]string, 0, len(ops))
+	for key := range ops {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		requirements = append(requirements, ops[key].Security...)
+	}
+	for _, requirement := range requirements {
+		for name := range requirement {
+			if _, exists := schemes[name]; !exists {
+				panic(fmt.Errorf("security scheme %s not defined, please add @securityScheme(%s, ...) to comments of service interface", name, name))
+			}
+		}
+	}
+}
+
+// durationExpr returns go expression of d
+func durationExpr(d time.Duration) string {
+	switch {
+	case d%time.Second == 0:
+		return fmt.Sprintf("%d * time.Second", d/time.Second)
+	case d%time.Millisecond == 0:
+		return fmt.Sprintf("%d * time.Millisecond", d/time.Millisecond)
+	case d%time.Microsecond == 0:
+		return fmt.Sprintf("%d * time.Microsecond", d/time.Microsecond)
+	default:
+		return fmt.Sprintf("time.Duration(%d)", d)
+	}
+}
+
+// checkGetWithContext panics if method annotated with name is not a GET method with context.Context parameter
+func checkGetWithContext(name string, method astutils.MethodMeta) {
+	if httpMethod(method.Name) != "GET" {
+		panic(fmt.Errorf("%s is only supported by GET method, but %s is %s", name, method.Name, httpMethod(method.Name)))
+	}
+	checkContext(name, method)
+}
+
+// checkContext panics if method annotated with name has no context.Context parameter
+func checkContext(name string, method astutils.MethodMeta) {
+	for _, param := range method.Params {
+		if param.Type == "context.Context" {
+			return
+		}
+	}
+	panic(fmt.Errorf("%s requires context.Context parameter of method %s", name, method.Name))
+}
+
+// hedgeOf converts @hedge annotation of GET method to ddhttp.HedgePolicy literal for generated client,
+// returns empty string if there is no @hedge annotation. Supported forms are:
+//
+//	@hedge(p95)
+//	@hedge(100ms)
+//	@hedge(p99, 200ms)
+//
+// The percentile of recent latencies of the method is used as delay, and the duration is used if there are not enough
+// latency samples.
+func hedgeOf(svcName string, method astutils.MethodMeta) string {
+	annotations, _ := parseAnnotations(method.Comments)
+	hedges := filterAnnotations(annotations, "hedge")
+	if len(hedges) == 0 {
+		return ""
+	}
+	checkGetWithContext("@hedge", method)
+	a := hedges[0]
+	if len(a.Params) == 0 || len(a.Params) > 2 {
+		panic(fmt.Errorf("@hedge of method %s requires percentile or delay, got @hedge(%s)", method.Name, strings.Join(a.Params, ", ")))
+	}
+	var (
+		percentile float64
+		delay      time.Duration
+	)
+	for _, param := range a.Params {
+		if strings.HasPrefix(param, "p") {
+			p, err := strconv.ParseFloat(strings.TrimPrefix(param, "p"), 64)
+			if err != nil || p <= 0 || p >= 100 {
+				panic(fmt.Errorf("@hedge of method %s has invalid percentile %s", method.Name, param))
+			}
+			percentile = p
+			continue
+		}
+		d, err := time.ParseDuration(param)
+		if err != nil || d <= 0 {
+			panic(fmt.Errorf("@hedge of method %s has invalid delay %s", method.Name, param))
+		}
+		delay = d
+	}
+	fields := []string{fmt.Sprintf("Name: %q", svcName+"."+method.Name)}
+	if percentile > 0 {
+		fields = append(fields, "Percentile: "+strconv.FormatFloat(percentile, 'f', -1, 64))
+	}
+	if delay > 0 {
+		fields = append(fields, "Delay: "+durationExpr(delay))
+	}
+	return "ddhttp.HedgePolicy{" + strings.Join(fields, ", ") + "}"
+}
+
+// cacheOf reports whether GET method is annotated with @cache(), responses of which will be cached by generated client
+// according to Cache-Control and ETag headers
+func cacheOf(method astutils.MethodMeta) bool {
+	annotations, _ := parseAnnotations(method.Comments)
+	if len(filterAnnotations(annotations, "cache")) == 0 {
+		return false
+	}
+	checkGetWithContext("@cache", method)
+	return true
+}
+
+// timeoutOf converts @timeout annotation to duration expression for generated client, returns empty string if there is
+// no @timeout annotation. For example, @timeout(1500ms) is converted to 1500 * time.Millisecond.
+func timeoutOf(method astutils.MethodMeta) string {
+	annotations, _ := parseAnnotations(method.Comments)
+	timeouts := filterAnnotations(annotations, "timeout")
+	if len(timeouts) == 0 {
+		return ""
+	}
+	checkContext("@timeout", method)
+	a := timeouts[0]
+	if len(a.Params) != 1 {
+		panic(fmt.Errorf("@timeout of method %s requires a duration, got @timeout(%s)", method.Name, strings.Join(a.Params, ", ")))
+	}
+	d, err := time.ParseDuration(a.Params[0])
+	if err != nil || d <= 0 {
+		panic(fmt.Errorf("@timeout of method %s has invalid duration %s", method.Name, a.Params[0]))
+	}
+	return durationExpr(d)
+}
+
+// clientCtxOf returns go expression of context for the request sent by generated client from ctx parameter of method
+func clientCtxOf(svcName string, method astutils.MethodMeta, ctx string) string {
+	if policy := hedgeOf(svcName, method); policy != "" {
+		ctx = fmt.Sprintf("ddhttp.WithHedging(%s, %s)", ctx, policy)
+	}
+	if cacheOf(method) {
+		ctx = fmt.Sprintf("ddhttp.WithCaching(%s, %q)", ctx, svcName+"."+method.Name)
+	}
+	if timeout := timeoutOf(method); timeout != "" {
+		ctx = fmt.Sprintf("ddhttp.WithTimeout(%s, %q, %s)", ctx, svcName+"."+method.Name, timeout)
+	}
+	return ctx
+}
@@ -0,0 +1,213 @@
+package codegen
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+	"testing"
+)
+
+func Test_parseAnnotations(t *testing.T) {
+	annotations, rest := parseAnnotations([]string{
+		"GetUser returns user by id",
+		"@security(bearerAuth, apiKeyAuth)",
+		" @security() ",
+		"email like @someone is not annotation",
+	})
+	assert.Equal(t, []annotation{
+		{
+			Name:   "security",
+			Params: []string{"bearerAuth", "apiKeyAuth"},
+		},
+		{
+			Name: "security",
+		},
+	}, annotations)
+	assert.Equal(t, []string{"GetUser returns user by id", "email like @someone is not annotation"}, rest)
+}
+
+func Test_securitySchemesOf(t *testing.T) {
+	schemes, security := securitySchemesOf(astutils.InterfaceMeta{
+		Name: "Usersvc",
+		Comments: []string{
+			"Usersvc user service",
+			"@securityScheme(bearerAuth, bearer)",
+			"@securityScheme(basicAuth, basic)",
+			"@securityScheme(apiKeyAuth, apiKey, query, api_key)",
+			"@security(bearerAuth)",
+		},
+	})
+	assert.Equal(t, map[string]v3.SecurityScheme{
+		"bearerAuth": {
+			Type:         v3.HTTPS,
+			Scheme:       "bearer",
+			BearerFormat: "JWT",
+		},
+		"basicAuth": {
+			Type:   v3.HTTPS,
+			Scheme: "basic",
+		},
+		"apiKeyAuth": {
+			Type: v3.APIKeyS,
+			In:   v3.InQuery,
+			Name: "api_key",
+		},
+	}, schemes)
+	assert.Equal(t, []v3.Security{{"bearerAuth": []string{}}}, security)
+}
+
+func Test_securitySchemeOfPanic(t *testing.T) {
+	assert.Panics(t, func() {
+		securitySchemeOf(annotation{Name: "securityScheme", Params: []string{"oauth", "oauth2"}})
+	})
+	assert.Panics(t, func() {
+		securitySchemeOf(annotation{Name: "securityScheme", Params: []string{"apiKeyAuth", "apiKey", "body", "key"}})
+	})
+}
+
+func Test_checkSecurity(t *testing.T) {
+	api := v3.API{
+		Paths: map[string]v3.Path{
+			"/user": {
+				Get: &v3.Operation{
+					Security: []v3.Security{{"bearerAuth": []string{}}},
+				},
+			},
+		},
+		Components: &v3.Components{},
+	}
+	assert.Panics(t, func() {
+		checkSecurity(api)
+	})
+	api.Components.SecuritySchemes = map[string]v3.SecurityScheme{
+		"bearerAuth": {
+			Type:   v3.HTTPS,
+			Scheme: "bearer",
+		},
+	}
+	assert.NotPanics(t, func() {
+		checkSecurity(api)
+	})
+}
+
+func Test_operationOfSecurity(t *testing.T) {
+	v3.Schemas = make(map[string]v3.Schema)
+	op := operationOf(astutils.MethodMeta{
+		Name: "GetUser",
+		Params: []astutils.FieldMeta{
+			{
+				Name: "userId",
+				Type: "int",
+			},
+		},
+		Comments: []string{"GetUser returns user", "@security(basicAuth)"},
+	}, get)
+	assert.Equal(t, "GetUser returns user", op.Description)
+	assert.Equal(t, []v3.Security{{"basicAuth": []string{}}}, op.Security)
+}
+
+func Test_hedgeOf(t *testing.T) {
+	ctx := astutils.FieldMeta{Name: "ctx", Type: "context.Context"}
+	assert.Equal(t, "", hedgeOf("Usersvc", astutils.MethodMeta{
+		Name:   "GetUser",
+		Params: []astutils.FieldMeta{ctx},
+	}))
+	assert.Equal(t, `ddhttp.HedgePolicy{Name: "Usersvc.GetUser", Percentile: 99.9}`, hedgeOf("Usersvc", astutils.MethodMeta{
+		Name:     "GetUser",
+		Params:   []astutils.FieldMeta{ctx},
+		Comments: []string{"@hedge(p99.9)"},
+	}))
+	assert.Equal(t, `ddhttp.HedgePolicy{Name: "Usersvc.GetUser", Delay: 1500 * time.Millisecond}`, hedgeOf("Usersvc", astutils.MethodMeta{
+		Name:     "GetUser",
+		Params:   []astutils.FieldMeta{ctx},
+		Comments: []string{"@hedge(1.5s)"},
+	}))
+}
+
+func Test_hedgeOfPanic(t *testing.T) {
+	ctx := astutils.FieldMeta{Name: "ctx", Type: "context.Context"}
+	assert.Panics(t, func() {
+		hedgeOf("Usersvc", astutils.MethodMeta{
+			Name:     "PostUser",
+			Params:   []astutils.FieldMeta{ctx},
+			Comments: []string{"@hedge(p95)"},
+		})
+	})
+	assert.Panics(t, func() {
+		hedgeOf("Usersvc", astutils.MethodMeta{
+			Name:     "GetUser",
+			Comments: []string{"@hedge(p95)"},
+		})
+	})
+	assert.Panics(t, func() {
+		hedgeOf("Usersvc", astutils.MethodMeta{
+			Name:     "GetUser",
+			Params:   []astutils.FieldMeta{ctx},
+			Comments: []string{"@hedge(p100)"},
+		})
+	})
+}
+
+func Test_cacheOf(t *testing.T) {
+	ctx := astutils.FieldMeta{Name: "ctx", Type: "context.Context"}
+	assert.False(t, cacheOf(astutils.MethodMeta{
+		Name:   "GetUser",
+		Params: []astutils.FieldMeta{ctx},
+	}))
+	assert.True(t, cacheOf(astutils.MethodMeta{
+		Name:     "GetUser",
+		Params:   []astutils.FieldMeta{ctx},
+		Comments: []string{"@cache()"},
+	}))
+	assert.Panics(t, func() {
+		cacheOf(astutils.MethodMeta{
+			Name:     "PutUser",
+			Params:   []astutils.FieldMeta{ctx},
+			Comments: []string{"@cache()"},
+		})
+	})
+}
+
+func Test_clientCtxOf(t *testing.T) {
+	ctx := astutils.FieldMeta{Name: "ctx", Type: "context.Context"}
+	assert.Equal(t, "ctx", clientCtxOf("Usersvc", astutils.MethodMeta{
+		Name:   "GetUser",
+		Params: []astutils.FieldMeta{ctx},
+	}, "ctx"))
+	assert.Equal(t, `ddhttp.WithCaching(ddhttp.WithHedging(ctx, ddhttp.HedgePolicy{Name: "Usersvc.GetUser", Percentile: 95}), "Usersvc.GetUser")`, clientCtxOf("Usersvc", astutils.MethodMeta{
+		Name:     "GetUser",
+		Params:   []astutils.FieldMeta{ctx},
+		Comments: []string{"@hedge(p95)", "@cache()"},
+	}, "ctx"))
+}
+
+func Test_timeoutOf(t *testing.T) {
+	ctx := astutils.FieldMeta{Name: "ctx", Type: "context.Context"}
+	assert.Equal(t, "", timeoutOf(astutils.MethodMeta{
+		Name:   "PostUser",
+		Params: []astutils.FieldMeta{ctx},
+	}))
+	assert.Equal(t, "1500 * time.Millisecond", timeoutOf(astutils.MethodMeta{
+		Name:     "PostUser",
+		Params:   []astutils.FieldMeta{ctx},
+		Comments: []string{"@timeout(1.5s)"},
+	}))
+	assert.Equal(t, `ddhttp.WithTimeout(ctx, "Usersvc.PostUser", 2 * time.Second)`, clientCtxOf("Usersvc", astutils.MethodMeta{
+		Name:     "PostUser",
+		Params:   []astutils.FieldMeta{ctx},
+		Comments: []string{"@timeout(2s)"},
+	}, "ctx"))
+	assert.Panics(t, func() {
+		timeoutOf(astutils.MethodMeta{
+			Name:     "PostUser",
+			Comments: []string{"@timeout(2s)"},
+		})
+	})
+	assert.Panics(t, func() {
+		timeoutOf(astutils.MethodMeta{
+			Name:     "PostUser",
+			Params:   []astutils.FieldMeta{ctx},
+			Comments: []string{"@timeout(forever)"},
+		})
+	})
+}
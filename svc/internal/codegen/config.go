@@ -2,6 +2,8 @@ package codegen
 
 import (
 	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"os"
 	"path/filepath"
 	"text/template"
@@ -40,27 +42,31 @@ func LoadFromEnv() *Config {
 }
 `
 
+func init() {
+	templateutils.Register("svc/config.go.tmpl", configTmpl, `No data.`)
+}
+
 //GenConfig generates config file
 func GenConfig(dir string) {
 	var (
 		err        error
 		configfile string
-		f          *os.File
+		f          fileutils.File
 		tpl        *template.Template
 		configDir  string
 	)
 	configDir = filepath.Join(dir, "config")
-	if err = os.MkdirAll(configDir, os.ModePerm); err != nil {
+	if err = fileutils.MkdirAll(configDir, os.ModePerm); err != nil {
 		panic(err)
 	}
 
 	configfile = filepath.Join(configDir, "config.go")
-	if _, err = os.Stat(configfile); os.IsNotExist(err) {
-		if f, err = os.Create(configfile); err != nil {
+	if _, err = fileutils.Stat(configfile); os.IsNotExist(err) {
+		if f, err = fileutils.Create(configfile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
-		tpl, _ = template.New("config.go.tmpl").Parse(configTmpl)
+		tpl, _ = template.New("config.go.tmpl").Parse(templateutils.Lookup("svc/config.go.tmpl"))
 		_ = tpl.Execute(f, nil)
 	} else {
 		logrus.Warnf("file %s already exists", configfile)
@@ -3,6 +3,8 @@ package codegen
 import (
 	"bufio"
 	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"os"
 	"path/filepath"
 	"strings"
@@ -39,12 +41,16 @@ func NewDb(conf config.DbConfig) (*sqlx.DB, error) {
 }
 `
 
+func init() {
+	templateutils.Register("svc/db.go.tmpl", dbTmpl, `ConfigPackage string: import path of config package of the project`)
+}
+
 // GenDb generates db connection code
 func GenDb(dir string) {
 	var (
 		err       error
 		dbfile    string
-		f         *os.File
+		f         fileutils.File
 		tpl       *template.Template
 		dbDir     string
 		modfile   string
@@ -52,14 +58,14 @@ func GenDb(dir string) {
 		firstLine string
 	)
 	dbDir = filepath.Join(dir, "db")
-	if err = os.MkdirAll(dbDir, os.ModePerm); err != nil {
+	if err = fileutils.MkdirAll(dbDir, os.ModePerm); err != nil {
 		panic(err)
 	}
 
 	dbfile = filepath.Join(dbDir, "db.go")
-	if _, err = os.Stat(dbfile); os.IsNotExist(err) {
+	if _, err = fileutils.Stat(dbfile); os.IsNotExist(err) {
 		modfile = filepath.Join(dir, "go.mod")
-		if f, err = os.Open(modfile); err != nil {
+		if f, err = fileutils.Open(modfile); err != nil {
 			panic(err)
 		}
 		reader := bufio.NewReader(f)
@@ -68,12 +74,12 @@ func GenDb(dir string) {
 		}
 		modName = strings.TrimSpace(strings.TrimPrefix(firstLine, "module"))
 
-		if f, err = os.Create(dbfile); err != nil {
+		if f, err = fileutils.Create(dbfile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
 
-		if tpl, err = template.New("db.go.tmpl").Parse(dbTmpl); err != nil {
+		if tpl, err = template.New("db.go.tmpl").Parse(templateutils.Lookup("svc/db.go.tmpl")); err != nil {
 			panic(err)
 		}
 		if err = tpl.Execute(f, struct {
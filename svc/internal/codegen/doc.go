@@ -8,12 +8,13 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/astutils"
 	"github.com/unionj-cloud/go-doudou/constants"
+	"github.com/unionj-cloud/go-doudou/fileutils"
 	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
 	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"go/ast"
 	"go/parser"
 	"go/token"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -65,7 +66,9 @@ func operationOf(method astutils.MethodMeta, httpMethod string) v3.Operation {
 	var ret v3.Operation
 	var params []v3.Parameter
 
-	ret.Description = strings.Join(method.Comments, "\n")
+	annotations, comments := parseAnnotations(method.Comments)
+	ret.Description = strings.Join(comments, "\n")
+	ret.Security = securityOf(annotations)
 
 	// If http method is "POST" and each parameters' type is one of v3.Int, v3.Int64, v3.Bool, v3.String, v3.Float32, v3.Float64,
 	// then we use application/x-www-form-urlencoded as Content-type and we make one ref schema from them as request body.
@@ -251,6 +254,33 @@ func pathOf(method astutils.MethodMeta) v3.Path {
 	return ret
 }
 
+// loadVoSchemas parses all structs in vo package of the project in dir into v3.Schemas
+func loadVoSchemas(dir string) {
+	var vos []v3.Schema
+	vodir := filepath.Join(dir, "vo")
+	var files []string
+	err := filepath.Walk(vodir, astutils.Visit(&files))
+	if err != nil {
+		logrus.Panicln(err)
+	}
+	for _, file := range files {
+		v3.SchemaNames = append(v3.SchemaNames, getSchemaNames(file)...)
+	}
+	for _, file := range files {
+		vos = append(vos, schemasOf(file)...)
+	}
+	for _, item := range vos {
+		v3.Schemas[item.Title] = item
+	}
+}
+
+func endpointOf(inter astutils.InterfaceMeta, method astutils.MethodMeta, routePatternStrategy int) string {
+	if routePatternStrategy == 1 {
+		return fmt.Sprintf("/%s/%s", strings.ToLower(inter.Name), noSplitPattern(method.Name))
+	}
+	return fmt.Sprintf("/%s", pattern(method.Name))
+}
+
 func pathsOf(ic astutils.InterfaceCollector, routePatternStrategy int) map[string]v3.Path {
 	if len(ic.Interfaces) == 0 {
 		return nil
@@ -258,12 +288,7 @@ func pathsOf(ic astutils.InterfaceCollector, routePatternStrategy int) map[strin
 	pathmap := make(map[string]v3.Path)
 	inter := ic.Interfaces[0]
 	for _, method := range inter.Methods {
-		v3path := pathOf(method)
-		endpoint := fmt.Sprintf("/%s", pattern(method.Name))
-		if routePatternStrategy == 1 {
-			endpoint = fmt.Sprintf("/%s/%s", strings.ToLower(inter.Name), noSplitPattern(method.Name))
-		}
-		pathmap[endpoint] = v3path
+		pathmap[endpointOf(inter, method, routePatternStrategy)] = pathOf(method)
 	}
 	return pathmap
 }
@@ -277,6 +302,35 @@ func init() {
 }
 `
 
+func init() {
+	templateutils.Register("svc/doc.go.tmpl", gofileTmpl, `SvcPackage string: package name of service package
+Doc string: OpenAPI 3.0 description json`)
+}
+
+// apiOf builds OpenAPI 3.0 description of the service in dir
+func apiOf(dir string, ic astutils.InterfaceCollector, routePatternStrategy int) v3.API {
+	v3.Schemas = make(map[string]v3.Schema)
+	loadVoSchemas(dir)
+	_, comments := parseAnnotations(ic.Interfaces[0].Comments)
+	securitySchemes, security := securitySchemesOf(ic.Interfaces[0])
+	api := v3.API{
+		Openapi: "3.0.2",
+		Info: &v3.Info{
+			Title:       ic.Interfaces[0].Name,
+			Description: strings.Join(comments, "\n"),
+			Version:     fmt.Sprintf("v%s", time.Now().Local().Format(constants.FORMAT10)),
+		},
+		Paths: pathsOf(ic, routePatternStrategy),
+		Components: &v3.Components{
+			Schemas:         v3.Schemas,
+			SecuritySchemes: securitySchemes,
+		},
+		Security: security,
+	}
+	checkSecurity(api)
+	return api
+}
+
 // GenDoc generates OpenAPI 3.0 description json file.
 // Not support alias type in vo file.
 func GenDoc(dir string, ic astutils.InterfaceCollector, routePatternStrategy int) {
@@ -288,16 +342,13 @@ func GenDoc(dir string, ic astutils.InterfaceCollector, routePatternStrategy int
 		fi      os.FileInfo
 		api     v3.API
 		data    []byte
-		vos     []v3.Schema
-		paths   map[string]v3.Path
 		tpl     *template.Template
 		sqlBuf  bytes.Buffer
 		source  string
 	)
-	v3.Schemas = make(map[string]v3.Schema)
 	svcname = ic.Interfaces[0].Name
 	docfile = filepath.Join(dir, strings.ToLower(svcname)+"_openapi3.json")
-	fi, err = os.Stat(docfile)
+	fi, err = fileutils.Stat(docfile)
 	if err != nil && !os.IsNotExist(err) {
 		panic(err)
 	}
@@ -305,48 +356,21 @@ func GenDoc(dir string, ic astutils.InterfaceCollector, routePatternStrategy int
 		logrus.Warningln("file " + docfile + " will be overwrited")
 	}
 	gofile = filepath.Join(dir, strings.ToLower(svcname)+"_openapi3.go")
-	fi, err = os.Stat(gofile)
+	fi, err = fileutils.Stat(gofile)
 	if err != nil && !os.IsNotExist(err) {
 		panic(err)
 	}
 	if fi != nil {
 		logrus.Warningln("file " + gofile + " will be overwrited")
 	}
-	vodir := filepath.Join(dir, "vo")
-	var files []string
-	err = filepath.Walk(vodir, astutils.Visit(&files))
-	if err != nil {
-		logrus.Panicln(err)
-	}
-	for _, file := range files {
-		v3.SchemaNames = append(v3.SchemaNames, getSchemaNames(file)...)
-	}
-	for _, file := range files {
-		vos = append(vos, schemasOf(file)...)
-	}
-	for _, item := range vos {
-		v3.Schemas[item.Title] = item
-	}
-	paths = pathsOf(ic, routePatternStrategy)
-	api = v3.API{
-		Openapi: "3.0.2",
-		Info: &v3.Info{
-			Title:       svcname,
-			Description: strings.Join(ic.Interfaces[0].Comments, "\n"),
-			Version:     fmt.Sprintf("v%s", time.Now().Local().Format(constants.FORMAT10)),
-		},
-		Paths: paths,
-		Components: &v3.Components{
-			Schemas: v3.Schemas,
-		},
-	}
+	api = apiOf(dir, ic, routePatternStrategy)
 	data, err = json.Marshal(api)
-	err = ioutil.WriteFile(docfile, data, os.ModePerm)
+	err = fileutils.WriteFile(docfile, data, os.ModePerm)
 	if err != nil {
 		panic(err)
 	}
 
-	if tpl, err = template.New("doc.go.tmpl").Parse(gofileTmpl); err != nil {
+	if tpl, err = template.New("doc.go.tmpl").Parse(templateutils.Lookup("svc/doc.go.tmpl")); err != nil {
 		panic(err)
 	}
 	if err = tpl.Execute(&sqlBuf, struct {
@@ -0,0 +1,128 @@
+package codegen
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenGrpcProto(t *testing.T) {
+	dir := testDir + "grpcproto"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	ic := astutils.BuildInterfaceCollector(filepath.Join(dir, "svc.go"), astutils.ExprString)
+	protofile, err := GenGrpcProto(dir, ic)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "transport", "grpcsrv", "testdatagrpcproto.proto"), protofile)
+
+	content, err := ioutil.ReadFile(protofile)
+	assert.NoError(t, err)
+	proto := string(content)
+	assert.Contains(t, proto, `option go_package = "testdatagrpcproto/transport/grpcsrv/pb";`)
+	assert.Contains(t, proto, `import "google/protobuf/struct.proto";`)
+	assert.Contains(t, proto, "rpc PageUsers(PageUsersRequest) returns (PageUsersResponse);")
+	assert.Contains(t, proto, "  PageQuery query = 1;")
+	assert.Contains(t, proto, "  int64 code = 1;\n  PageRet data = 2;")
+	assert.Contains(t, proto, "  google.protobuf.Value items = 1;")
+}
+
+func TestGenGrpcProtoTestdata(t *testing.T) {
+	defer os.RemoveAll(filepath.Join(testDir, "transport"))
+	ic := astutils.BuildInterfaceCollector(filepath.Join(testDir, "svc.go"), astutils.ExprString)
+	protofile, err := GenGrpcProto(testDir, ic)
+	assert.NoError(t, err)
+
+	content, err := ioutil.ReadFile(protofile)
+	assert.NoError(t, err)
+	proto := string(content)
+	assert.Contains(t, proto, "message PageQuery {")
+	assert.Contains(t, proto, "message PageFilter {")
+	assert.Contains(t, proto, "message UserVo {")
+	// structs unreachable from service methods are not emitted
+	assert.NotContains(t, proto, "message TestAlias {")
+	assert.NotContains(t, proto, "message Event {")
+
+	assert.NoError(t, GenGrpcSrv(testDir, ic))
+}
+
+func TestGenGrpcSrv(t *testing.T) {
+	dir := testDir + "grpcsrv"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	ic := astutils.BuildInterfaceCollector(filepath.Join(dir, "svc.go"), astutils.ExprString)
+
+	assert.NoError(t, GenGrpcSrv(dir, ic))
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "transport", "grpcsrv", "handler.go"))
+	assert.NoError(t, err)
+	handler := string(content)
+	assert.Contains(t, handler, "pb.UnimplementedTestdatagrpcsrvServer")
+	assert.Contains(t, handler, "receiver.testdatagrpcsrv.PageUsers(ctx, fromPbPageQuery(in.Query))")
+	assert.Contains(t, handler, "Data: toPbPageRet(_data),")
+	assert.Contains(t, handler, "func Register(srv *grpc.Server, testdatagrpcsrv service.Testdatagrpcsrv)")
+
+	content, err = ioutil.ReadFile(filepath.Join(dir, "transport", "grpcsrv", "convert.go"))
+	assert.NoError(t, err)
+	convert := string(content)
+	assert.Contains(t, convert, "func toPbPageRet(in vo.PageRet) *pb.PageRet")
+	assert.Contains(t, convert, "out.Items = toPbValue(in.Items)")
+	assert.NotContains(t, convert, "func sendFile")
+}
+
+func TestGenGrpcEnv(t *testing.T) {
+	dir := testDir + "grpcenv"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	GenGrpcEnv(dir)
+	GenGrpcEnv(dir)
+	content, err := ioutil.ReadFile(filepath.Join(dir, ".env"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(content), "GDD_GRPC_PORT=50051"))
+}
+
+func Test_pbName(t *testing.T) {
+	assert.Equal(t, "UserId", pbName("user_id"))
+	assert.Equal(t, "Pf_2", pbName("pf_2"))
+	assert.Equal(t, "XFoo", pbName("_foo"))
+	assert.Equal(t, "HasNext", pbName("has_next"))
+}
+
+func Test_protoTypeOf(t *testing.T) {
+	gt := newGrpcTypes([]astutils.StructMeta{{Name: "UserVo"}}, map[string]string{"age": "int", "Ids": ""})
+	tests := []struct {
+		goType   string
+		want     string
+		repeated bool
+	}{
+		{"int", "int64", false},
+		{"[]byte", "bytes", false},
+		{"[]*vo.UserVo", "UserVo", true},
+		{"map[string]int", "map<string, int64>", false},
+		{"map[string]interface{}", "google.protobuf.Struct", false},
+		{"*time.Time", "google.protobuf.Timestamp", false},
+		{"[]*multipart.FileHeader", "File", true},
+		{"age", "int64", false},
+		{"[]vo.age", "int64", true},
+	}
+	for _, tt := range tests {
+		got, repeated, err := gt.protoTypeOf(tt.goType)
+		assert.NoError(t, err, tt.goType)
+		assert.Equal(t, tt.want, got, tt.goType)
+		assert.Equal(t, tt.repeated, repeated, tt.goType)
+	}
+	assert.Equal(t, []string{"UserVo"}, gt.used)
+	for _, goType := range []string{"[][]int", "map[float64]string", "Ids", "chan int", `[]anonystruct«{"Name":""}»`} {
+		_, _, err := gt.protoTypeOf(goType)
+		assert.Error(t, err, goType)
+	}
+}
+
+func Test_checkGoType(t *testing.T) {
+	gt := newGrpcTypes(nil, map[string]string{"age": "int", "Age": "int"})
+	assert.NoError(t, gt.checkGoType("map[string][]vo.Age"))
+	assert.Error(t, gt.checkGoType("[]*age"))
+}
@@ -0,0 +1,515 @@
+package codegen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/iancoleman/strcase"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
+	"go/ast"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// protoScalars maps go built-in types to protobuf scalar types
+var protoScalars = map[string]string{
+	"bool":    "bool",
+	"string":  "string",
+	"int":     "int64",
+	"int64":   "int64",
+	"int8":    "int32",
+	"int16":   "int32",
+	"int32":   "int32",
+	"rune":    "int32",
+	"uint":    "uint64",
+	"uint64":  "uint64",
+	"uint8":   "uint32",
+	"byte":    "uint32",
+	"uint16":  "uint32",
+	"uint32":  "uint32",
+	"float32": "float",
+	"float64": "double",
+}
+
+// pbScalars maps protobuf scalar types to go types in code generated by protoc-gen-go
+var pbScalars = map[string]string{
+	"bool":   "bool",
+	"string": "string",
+	"int64":  "int64",
+	"int32":  "int32",
+	"uint64": "uint64",
+	"uint32": "uint32",
+	"float":  "float32",
+	"double": "float64",
+}
+
+const (
+	timestampProto = "google/protobuf/timestamp.proto"
+	structProto    = "google/protobuf/struct.proto"
+	// fileMessage is the message for *v3.FileModel and *multipart.FileHeader parameters
+	// and chunks streamed by methods returning *os.File
+	fileMessage = "File"
+)
+
+func isFile(t string) bool {
+	return t == "v3.FileModel" || t == "multipart.FileHeader"
+}
+
+// splitMap splits map type t into key type and value type
+func splitMap(t string) (string, string) {
+	depth := 0
+	for i := len("map["); i < len(t); i++ {
+		switch t[i] {
+		case '[':
+			depth++
+		case ']':
+			if depth == 0 {
+				return t[len("map["):i], t[i+1:]
+			}
+			depth--
+		}
+	}
+	panic(fmt.Errorf("invalid map type %s", t))
+}
+
+// grpcTypes maps go types in svc.go and vo package to protobuf types
+type grpcTypes struct {
+	// structs holds exported structs in vo package by name
+	structs map[string]astutils.StructMeta
+	// named holds underlying built-in types of named non-struct types in vo package by name,
+	// empty string if underlying type is not built-in
+	named   map[string]string
+	imports map[string]bool
+	file    bool
+	// used holds names of structs reachable from service methods in the order they are found
+	used []string
+}
+
+func newGrpcTypes(vos []astutils.StructMeta, named map[string]string) *grpcTypes {
+	gt := &grpcTypes{
+		structs: make(map[string]astutils.StructMeta),
+		named:   named,
+		imports: make(map[string]bool),
+	}
+	if gt.named == nil {
+		gt.named = make(map[string]string)
+	}
+	for _, item := range vos {
+		gt.structs[item.Name] = item
+	}
+	return gt
+}
+
+// voName returns struct name if t is a struct from vo package
+func (gt *grpcTypes) voName(t string) (string, bool) {
+	name := strings.TrimPrefix(t, "vo.")
+	_, ok := gt.structs[name]
+	return name, ok
+}
+
+// namedOf returns name and underlying built-in type if t is a named non-struct type from vo package
+func (gt *grpcTypes) namedOf(t string) (string, string, bool) {
+	name := strings.TrimPrefix(t, "vo.")
+	underlying, ok := gt.named[name]
+	return name, underlying, ok
+}
+
+// use marks struct name as reachable from service methods
+func (gt *grpcTypes) use(name string) {
+	for _, item := range gt.used {
+		if item == name {
+			return
+		}
+	}
+	gt.used = append(gt.used, name)
+}
+
+// protoTypeOf returns protobuf type of go type t, repeated is true for slices.
+// Structs from vo package are marked as used.
+func (gt *grpcTypes) protoTypeOf(t string) (string, bool, error) {
+	switch {
+	case t == "[]byte":
+		return "bytes", false, nil
+	case t == "map[string]interface{}":
+		gt.imports[structProto] = true
+		return "google.protobuf.Struct", false, nil
+	case strings.HasPrefix(t, "[]"):
+		elem, repeated, err := gt.protoTypeOf(t[2:])
+		if err != nil {
+			return "", false, err
+		}
+		if repeated || strings.HasPrefix(elem, "map<") {
+			return "", false, errors.Errorf("not support nested slice or slice of map %s in grpc", t)
+		}
+		return elem, true, nil
+	case strings.HasPrefix(t, "map["):
+		k, v := splitMap(t)
+		kt, _, err := gt.protoTypeOf(k)
+		if err != nil {
+			return "", false, err
+		}
+		if _, ok := pbScalars[kt]; !ok || kt == "float" || kt == "double" {
+			return "", false, errors.Errorf("not support map key type %s in grpc", k)
+		}
+		vt, repeated, err := gt.protoTypeOf(v)
+		if err != nil {
+			return "", false, err
+		}
+		if repeated || strings.HasPrefix(vt, "map<") {
+			return "", false, errors.Errorf("not support map of slice or map %s in grpc", t)
+		}
+		return fmt.Sprintf("map<%s, %s>", kt, vt), false, nil
+	case strings.HasPrefix(t, "*"):
+		return gt.protoTypeOf(t[1:])
+	case t == "time.Time":
+		gt.imports[timestampProto] = true
+		return "google.protobuf.Timestamp", false, nil
+	case t == "interface{}":
+		gt.imports[structProto] = true
+		return "google.protobuf.Value", false, nil
+	case isFile(t):
+		gt.file = true
+		return fileMessage, false, nil
+	case strings.HasPrefix(t, "anonystruct"):
+		return "", false, errors.New("not support anonymous struct in grpc")
+	}
+	if pt, ok := protoScalars[t]; ok {
+		return pt, false, nil
+	}
+	if name, ok := gt.voName(t); ok {
+		gt.use(name)
+		return name, false, nil
+	}
+	if name, underlying, ok := gt.namedOf(t); ok {
+		if pt, ok := protoScalars[underlying]; ok {
+			return pt, false, nil
+		}
+		return "", false, errors.Errorf("not support type %s in grpc, only named types of built-in types are supported", name)
+	}
+	return "", false, errors.Errorf("not support type %s in grpc", t)
+}
+
+type protoField struct {
+	Name     string
+	Type     string
+	Repeated bool
+	Number   int
+	Comments []string
+}
+
+type protoMessage struct {
+	Name     string
+	Comments []string
+	Fields   []protoField
+}
+
+type protoMethod struct {
+	Name     string
+	Comments []string
+	Request  string
+	Response string
+	Stream   bool
+}
+
+func (gt *grpcTypes) protoFieldsOf(fields []astutils.FieldMeta) ([]protoField, error) {
+	var ret []protoField
+	for _, field := range fields {
+		pt, repeated, err := gt.protoTypeOf(field.Type)
+		if err != nil {
+			return nil, errors.Wrapf(err, "field %s", field.Name)
+		}
+		ret = append(ret, protoField{
+			Name:     strcase.ToSnake(field.Name),
+			Type:     pt,
+			Repeated: repeated,
+			Number:   len(ret) + 1,
+			Comments: field.Comments,
+		})
+	}
+	return ret, nil
+}
+
+// usedVos resolves fields of structs reachable from service methods recursively,
+// and returns them in the order they are declared in vo package.
+// It should be called after parameters and results of all methods are resolved.
+func (gt *grpcTypes) usedVos(vos []astutils.StructMeta) ([]astutils.StructMeta, error) {
+	for i := 0; i < len(gt.used); i++ {
+		if _, err := gt.protoFieldsOf(gt.structs[gt.used[i]].Fields); err != nil {
+			return nil, errors.Wrapf(err, "struct %s", gt.used[i])
+		}
+	}
+	var ret []astutils.StructMeta
+	for _, item := range vos {
+		for _, name := range gt.used {
+			if name == item.Name {
+				ret = append(ret, item)
+				break
+			}
+		}
+	}
+	return ret, nil
+}
+
+// grpcParams returns parameters of method except context.Context
+func grpcParams(method astutils.MethodMeta) ([]astutils.FieldMeta, error) {
+	var ret []astutils.FieldMeta
+	for _, param := range method.Params {
+		if param.Type == "context.Context" {
+			continue
+		}
+		if param.Type == "*os.File" {
+			return nil, errors.Errorf("not support *os.File as parameter of method %s in grpc", method.Name)
+		}
+		ret = append(ret, param)
+	}
+	return ret, nil
+}
+
+// grpcResults returns results of method except error, stream is true if method returns *os.File
+func grpcResults(method astutils.MethodMeta) ([]astutils.FieldMeta, bool, error) {
+	var (
+		ret    []astutils.FieldMeta
+		stream bool
+	)
+	for _, result := range method.Results {
+		switch result.Type {
+		case "error":
+			continue
+		case "*os.File":
+			stream = true
+			continue
+		}
+		ret = append(ret, result)
+	}
+	if stream && len(ret) > 0 {
+		return nil, false, errors.Errorf("not support other results than *os.File and error of method %s in grpc", method.Name)
+	}
+	return ret, stream, nil
+}
+
+// grpcVosOf parses all exported structs in vo package of the project in dir with embedded fields flattened,
+// and underlying built-in types of named non-struct types
+func grpcVosOf(dir string) ([]astutils.StructMeta, map[string]string) {
+	var (
+		files []string
+		vos   []astutils.StructMeta
+	)
+	named := make(map[string]string)
+	if err := filepath.Walk(filepath.Join(dir, "vo"), astutils.Visit(&files)); err != nil {
+		logrus.Panicln(err)
+	}
+	for _, file := range files {
+		sc := astutils.BuildStructCollector(file, astutils.ExprString)
+		for _, item := range sc.DocFlatEmbed() {
+			var fields []astutils.FieldMeta
+			for _, field := range item.Fields {
+				if field.IsExport {
+					fields = append(fields, field)
+				}
+			}
+			item.Fields = fields
+			vos = append(vos, item)
+		}
+		for name, expr := range sc.NonStructTypeMap {
+			var underlying string
+			if ident, ok := expr.(*ast.Ident); ok {
+				if _, ok = protoScalars[ident.Name]; ok {
+					underlying = ident.Name
+				}
+			}
+			named[name] = underlying
+		}
+	}
+	return vos, named
+}
+
+var protoTmpl = `syntax = "proto3";
+
+package {{.Package}};
+{{ if .Imports }}
+{{ range .Imports }}import "{{.}}";
+{{ end }}{{ end }}
+option go_package = "{{.GoPackage}}";
+
+{{ range .Comments }}// {{.}}
+{{ end }}service {{.Name}} {
+{{- range .Methods }}
+{{- range .Comments }}
+  // {{.}}
+{{- end }}
+  rpc {{.Name}}({{.Request}}) returns ({{if .Stream}}stream {{end}}{{.Response}});
+{{- end }}
+}
+{{- range .Messages }}
+
+{{ range .Comments }}// {{.}}
+{{ end }}message {{.Name}} {
+{{- range .Fields }}
+{{- range .Comments }}
+  // {{.}}
+{{- end }}
+  {{if .Repeated}}repeated {{end}}{{.Type}} {{.Name}} = {{.Number}};
+{{- end }}
+}
+{{- end }}
+`
+
+func init() {
+	templateutils.Register("svc/grpc.proto.tmpl", protoTmpl, `Package string: protobuf package, lowercase service name
+GoPackage string: import path of go package generated by protoc, transport/grpcsrv/pb
+Name string: name of service interface
+Comments []string: comments of service interface
+Imports []string: imported well-known protobuf files
+Methods []protoMethod: Name, Request, Response string, Stream bool for methods returning *os.File, Comments []string
+Messages []protoMessage: Name string, Comments []string, Fields []protoField
+protoField: Name, Type string, Repeated bool, Number int, Comments []string`)
+}
+
+// GenGrpcProto generates .proto file into transport/grpcsrv from service interface in svc.go and structs in vo package.
+// Each method gets its own request and response message. Methods returning *os.File stream the file in chunks.
+// Only structs reachable from service methods become messages, named types of built-in types are mapped to
+// underlying scalar types. It returns path of the .proto file, or error if any type is not supported.
+func GenGrpcProto(dir string, ic astutils.InterfaceCollector) (string, error) {
+	var (
+		err       error
+		modfile   string
+		modName   string
+		firstLine string
+		modf      fileutils.File
+		grpcDir   string
+		protofile string
+		fi        os.FileInfo
+		tpl       *template.Template
+		buf       bytes.Buffer
+		methods   []protoMethod
+		messages  []protoMessage
+		imports   []string
+	)
+	grpcDir = filepath.Join(dir, "transport", "grpcsrv")
+	if err = fileutils.MkdirAll(grpcDir, os.ModePerm); err != nil {
+		return "", errors.WithStack(err)
+	}
+	svcname := ic.Interfaces[0].Name
+	protofile = filepath.Join(grpcDir, strings.ToLower(svcname)+".proto")
+	fi, err = fileutils.Stat(protofile)
+	if err != nil && !os.IsNotExist(err) {
+		return "", errors.WithStack(err)
+	}
+	if fi != nil {
+		logrus.Warningln("file " + protofile + " will be overwrited")
+	}
+
+	modfile = filepath.Join(dir, "go.mod")
+	if modf, err = fileutils.Open(modfile); err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer modf.Close()
+	reader := bufio.NewReader(modf)
+	if firstLine, err = reader.ReadString('\n'); err != nil {
+		return "", errors.WithStack(err)
+	}
+	modName = strings.TrimSpace(strings.TrimPrefix(firstLine, "module"))
+
+	vos, named := grpcVosOf(dir)
+	gt := newGrpcTypes(vos, named)
+	for _, method := range ic.Interfaces[0].Methods {
+		var (
+			params  []astutils.FieldMeta
+			results []astutils.FieldMeta
+			stream  bool
+			fields  []protoField
+		)
+		if params, err = grpcParams(method); err != nil {
+			return "", err
+		}
+		if results, stream, err = grpcResults(method); err != nil {
+			return "", err
+		}
+		pm := protoMethod{
+			Name:     method.Name,
+			Comments: method.Comments,
+			Request:  method.Name + "Request",
+			Response: method.Name + "Response",
+			Stream:   stream,
+		}
+		if fields, err = gt.protoFieldsOf(params); err != nil {
+			return "", errors.Wrapf(err, "method %s", method.Name)
+		}
+		messages = append(messages, protoMessage{
+			Name:   pm.Request,
+			Fields: fields,
+		})
+		if stream {
+			gt.file = true
+			pm.Response = fileMessage
+		} else {
+			if fields, err = gt.protoFieldsOf(results); err != nil {
+				return "", errors.Wrapf(err, "method %s", method.Name)
+			}
+			messages = append(messages, protoMessage{
+				Name:   pm.Response,
+				Fields: fields,
+			})
+		}
+		methods = append(methods, pm)
+	}
+	if vos, err = gt.usedVos(vos); err != nil {
+		return "", err
+	}
+	for _, item := range vos {
+		fields, _ := gt.protoFieldsOf(item.Fields)
+		messages = append(messages, protoMessage{
+			Name:     item.Name,
+			Comments: item.Comments,
+			Fields:   fields,
+		})
+	}
+	if gt.file {
+		messages = append(messages, protoMessage{
+			Name:     fileMessage,
+			Comments: []string{"File is file parameter, or chunk of file streamed by method returning *os.File"},
+			Fields: []protoField{
+				{Name: "filename", Type: "string", Number: 1},
+				{Name: "content", Type: "bytes", Number: 2},
+			},
+		})
+	}
+	for k := range gt.imports {
+		imports = append(imports, k)
+	}
+	sort.Strings(imports)
+
+	if tpl, err = template.New("grpc.proto.tmpl").Parse(templateutils.Lookup("svc/grpc.proto.tmpl")); err != nil {
+		return "", errors.WithStack(err)
+	}
+	if err = tpl.Execute(&buf, struct {
+		Package   string
+		GoPackage string
+		Name      string
+		Comments  []string
+		Imports   []string
+		Methods   []protoMethod
+		Messages  []protoMessage
+	}{
+		Package:   strings.ToLower(svcname),
+		GoPackage: modName + "/transport/grpcsrv/pb",
+		Name:      svcname,
+		Comments:  ic.Interfaces[0].Comments,
+		Imports:   imports,
+		Methods:   methods,
+		Messages:  messages,
+	}); err != nil {
+		return "", errors.WithStack(err)
+	}
+	if err = fileutils.WriteFile(protofile, buf.Bytes(), os.ModePerm); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return protofile, nil
+}
@@ -0,0 +1,741 @@
+package codegen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/iancoleman/strcase"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// pbName returns name of go struct field generated by protoc-gen-go for protobuf field name
+func pbName(name string) string {
+	isLower := func(c byte) bool { return 'a' <= c && c <= 'z' }
+	isDigit := func(c byte) bool { return '0' <= c && c <= '9' }
+	var b []byte
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_' && i == 0:
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(name) && isLower(name[i+1]):
+		case isDigit(c):
+			b = append(b, c)
+		default:
+			if isLower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(name) && isLower(name[i+1]); i++ {
+				b = append(b, name[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+// voGoType returns go type t qualified with vo package name
+func (gt *grpcTypes) voGoType(t string) string {
+	switch {
+	case strings.HasPrefix(t, "[]"):
+		return "[]" + gt.voGoType(t[2:])
+	case strings.HasPrefix(t, "*"):
+		return "*" + gt.voGoType(t[1:])
+	case strings.HasPrefix(t, "map["):
+		k, v := splitMap(t)
+		return "map[" + gt.voGoType(k) + "]" + gt.voGoType(v)
+	}
+	if name, ok := gt.voName(t); ok {
+		return "vo." + name
+	}
+	if name, _, ok := gt.namedOf(t); ok {
+		return "vo." + name
+	}
+	return t
+}
+
+// checkGoType returns error if go type t cannot be referred to outside vo package
+func (gt *grpcTypes) checkGoType(t string) error {
+	switch {
+	case strings.HasPrefix(t, "[]"):
+		return gt.checkGoType(t[2:])
+	case strings.HasPrefix(t, "*"):
+		return gt.checkGoType(t[1:])
+	case strings.HasPrefix(t, "map["):
+		k, v := splitMap(t)
+		if err := gt.checkGoType(k); err != nil {
+			return err
+		}
+		return gt.checkGoType(v)
+	}
+	if name, _, ok := gt.namedOf(t); ok && !unicode.IsUpper(rune(name[0])) {
+		return errors.Errorf("not support unexported type %s in grpc, it cannot be converted outside vo package", name)
+	}
+	return nil
+}
+
+// pbGoType returns go type generated by protoc-gen-go for go type t
+func (gt *grpcTypes) pbGoType(t string) string {
+	switch {
+	case t == "[]byte":
+		return t
+	case t == "map[string]interface{}":
+		return "*structpb.Struct"
+	case strings.HasPrefix(t, "[]"):
+		return "[]" + gt.pbGoType(t[2:])
+	case strings.HasPrefix(t, "map["):
+		k, v := splitMap(t)
+		return "map[" + gt.pbGoType(k) + "]" + gt.pbGoType(v)
+	case strings.HasPrefix(t, "*"):
+		return gt.pbGoType(t[1:])
+	case t == "time.Time":
+		return "*timestamppb.Timestamp"
+	case t == "interface{}":
+		return "*structpb.Value"
+	case isFile(t):
+		return "*pb." + fileMessage
+	}
+	if pt, ok := protoScalars[t]; ok {
+		return pbScalars[pt]
+	}
+	if name, ok := gt.voName(t); ok {
+		return "*pb." + name
+	}
+	if _, underlying, ok := gt.namedOf(t); ok {
+		return pbScalars[protoScalars[underlying]]
+	}
+	panic(fmt.Errorf("not support type %s in grpc", t))
+}
+
+// toPb returns go expression converting expr of go type t to go type generated by protoc-gen-go
+func (gt *grpcTypes) toPb(t, expr string) string {
+	if (strings.HasPrefix(t, "[]") || strings.HasPrefix(t, "map[")) && gt.voGoType(t) == gt.pbGoType(t) {
+		return expr
+	}
+	switch {
+	case t == "[]byte":
+		return expr
+	case t == "map[string]interface{}":
+		return fmt.Sprintf("toPbStruct(%s)", expr)
+	case strings.HasPrefix(t, "[]"):
+		elem := t[2:]
+		return fmt.Sprintf(`func(in %s) %s {
+	if in == nil {
+		return nil
+	}
+	out := make(%s, 0, len(in))
+	for _, item := range in {
+		out = append(out, %s)
+	}
+	return out
+}(%s)`, gt.voGoType(t), gt.pbGoType(t), gt.pbGoType(t), gt.toPb(elem, "item"), expr)
+	case strings.HasPrefix(t, "map["):
+		k, v := splitMap(t)
+		return fmt.Sprintf(`func(in %s) %s {
+	if in == nil {
+		return nil
+	}
+	out := make(%s, len(in))
+	for k, v := range in {
+		out[%s] = %s
+	}
+	return out
+}(%s)`, gt.voGoType(t), gt.pbGoType(t), gt.pbGoType(t), gt.toPb(k, "k"), gt.toPb(v, "v"), expr)
+	case t == "*v3.FileModel":
+		return fmt.Sprintf("toPbFileModel(%s)", expr)
+	case t == "*multipart.FileHeader":
+		return fmt.Sprintf("toPbFileHeader(%s)", expr)
+	case strings.HasPrefix(t, "*"):
+		return fmt.Sprintf(`func(in %s) %s {
+	if in == nil {
+		var zero %s
+		return zero
+	}
+	return %s
+}(%s)`, gt.voGoType(t), gt.pbGoType(t), gt.pbGoType(t), gt.toPb(t[1:], "*in"), expr)
+	case t == "time.Time":
+		return fmt.Sprintf("timestamppb.New(%s)", expr)
+	case t == "interface{}":
+		return fmt.Sprintf("toPbValue(%s)", expr)
+	case isFile(t):
+		panic(fmt.Errorf("not support %s in grpc, use *%s instead", t, t))
+	}
+	if pt, ok := protoScalars[t]; ok {
+		if pbScalars[pt] == t {
+			return expr
+		}
+		return fmt.Sprintf("%s(%s)", pbScalars[pt], expr)
+	}
+	if name, ok := gt.voName(t); ok {
+		return fmt.Sprintf("toPb%s(%s)", name, expr)
+	}
+	if _, underlying, ok := gt.namedOf(t); ok {
+		return fmt.Sprintf("%s(%s)", pbScalars[protoScalars[underlying]], expr)
+	}
+	panic(fmt.Errorf("not support type %s in grpc", t))
+}
+
+// fromPb returns go expression converting expr of go type generated by protoc-gen-go to go type t
+func (gt *grpcTypes) fromPb(t, expr string) string {
+	if (strings.HasPrefix(t, "[]") || strings.HasPrefix(t, "map[")) && gt.voGoType(t) == gt.pbGoType(t) {
+		return expr
+	}
+	switch {
+	case t == "[]byte":
+		return expr
+	case t == "map[string]interface{}":
+		return fmt.Sprintf("fromPbStruct(%s)", expr)
+	case strings.HasPrefix(t, "[]"):
+		elem := t[2:]
+		return fmt.Sprintf(`func(in %s) %s {
+	if in == nil {
+		return nil
+	}
+	out := make(%s, 0, len(in))
+	for _, item := range in {
+		out = append(out, %s)
+	}
+	return out
+}(%s)`, gt.pbGoType(t), gt.voGoType(t), gt.voGoType(t), gt.fromPb(elem, "item"), expr)
+	case strings.HasPrefix(t, "map["):
+		k, v := splitMap(t)
+		return fmt.Sprintf(`func(in %s) %s {
+	if in == nil {
+		return nil
+	}
+	out := make(%s, len(in))
+	for k, v := range in {
+		out[%s] = %s
+	}
+	return out
+}(%s)`, gt.pbGoType(t), gt.voGoType(t), gt.voGoType(t), gt.fromPb(k, "k"), gt.fromPb(v, "v"), expr)
+	case t == "*v3.FileModel":
+		return fmt.Sprintf("fromPbFileModel(%s)", expr)
+	case t == "*multipart.FileHeader":
+		return fmt.Sprintf("fromPbFileHeader(%s)", expr)
+	case strings.HasPrefix(t, "*"):
+		var nilCheck string
+		if strings.HasPrefix(gt.pbGoType(t), "*") {
+			nilCheck = `
+	if in == nil {
+		return nil
+	}`
+		}
+		return fmt.Sprintf(`func(in %s) %s {%s
+	out := %s
+	return &out
+}(%s)`, gt.pbGoType(t), gt.voGoType(t), nilCheck, gt.fromPb(t[1:], "in"), expr)
+	case t == "time.Time":
+		return fmt.Sprintf("fromPbTime(%s)", expr)
+	case t == "interface{}":
+		return fmt.Sprintf("fromPbValue(%s)", expr)
+	case isFile(t):
+		panic(fmt.Errorf("not support %s in grpc, use *%s instead", t, t))
+	}
+	if pt, ok := protoScalars[t]; ok {
+		if pbScalars[pt] == t {
+			return expr
+		}
+		return fmt.Sprintf("%s(%s)", t, expr)
+	}
+	if name, ok := gt.voName(t); ok {
+		return fmt.Sprintf("fromPb%s(%s)", name, expr)
+	}
+	if name, _, ok := gt.namedOf(t); ok {
+		return fmt.Sprintf("vo.%s(%s)", name, expr)
+	}
+	panic(fmt.Errorf("not support type %s in grpc", t))
+}
+
+type grpcAssign struct {
+	// Name is field name of the struct assigned to
+	Name string
+	Expr string
+}
+
+type grpcStruct struct {
+	Name   string
+	ToPb   []grpcAssign
+	FromPb []grpcAssign
+}
+
+type grpcMethod struct {
+	Name   string
+	Stream bool
+	// Args are expressions passed to service method
+	Args []string
+	// Results are variable names receiving results of service method
+	Results []string
+	ErrVar  string
+	FileVar string
+	// Fields are fields of response message
+	Fields []grpcAssign
+}
+
+var grpcHandlerTmpl = `package grpcsrv
+
+import (
+	"context"
+	"mime/multipart"
+	"os"
+	"time"
+
+	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	{{.ServiceAlias}} "{{.ServicePackage}}"
+	"{{.VoPackage}}"
+	pb "{{.PbPackage}}"
+)
+
+// {{.SvcName}}GrpcServer adapts {{.ServiceAlias}}.{{.SvcName}} to pb.{{.SvcName}}Server
+type {{.SvcName}}GrpcServer struct {
+	pb.Unimplemented{{.SvcName}}Server
+	{{.SvcName | toLowerCamel}} {{.ServiceAlias}}.{{.SvcName}}
+}
+{{- range $m := .Methods }}
+{{ if $m.Stream }}
+func (receiver *{{$.SvcName}}GrpcServer) {{$m.Name}}(in *pb.{{$m.Name}}Request, stream pb.{{$.SvcName}}_{{$m.Name}}Server) error {
+	{{ join $m.Results ", " }} := receiver.{{$.SvcName | toLowerCamel}}.{{$m.Name}}({{ join $m.Args ", " }})
+	{{- if $m.ErrVar }}
+	if {{$m.ErrVar}} != nil {
+		return {{$m.ErrVar}}
+	}
+	{{- end }}
+	return sendFile({{$m.FileVar}}, stream)
+}
+{{- else }}
+func (receiver *{{$.SvcName}}GrpcServer) {{$m.Name}}(ctx context.Context, in *pb.{{$m.Name}}Request) (*pb.{{$m.Name}}Response, error) {
+	{{ if $m.Results }}{{ join $m.Results ", " }} := {{ end }}receiver.{{$.SvcName | toLowerCamel}}.{{$m.Name}}({{ join $m.Args ", " }})
+	{{- if $m.ErrVar }}
+	if {{$m.ErrVar}} != nil {
+		return nil, {{$m.ErrVar}}
+	}
+	{{- end }}
+	return &pb.{{$m.Name}}Response{
+		{{- range $f := $m.Fields }}
+		{{$f.Name}}: {{$f.Expr}},
+		{{- end }}
+	}, nil
+}
+{{- end }}
+{{- end }}
+
+// New{{.SvcName}}GrpcServer creates grpc server calling {{.SvcName | toLowerCamel}}
+func New{{.SvcName}}GrpcServer({{.SvcName | toLowerCamel}} {{.ServiceAlias}}.{{.SvcName}}) *{{.SvcName}}GrpcServer {
+	return &{{.SvcName}}GrpcServer{
+		{{.SvcName | toLowerCamel}}: {{.SvcName | toLowerCamel}},
+	}
+}
+
+// Register registers {{.SvcName | toLowerCamel}} into srv
+func Register(srv *grpc.Server, {{.SvcName | toLowerCamel}} {{.ServiceAlias}}.{{.SvcName}}) {
+	pb.Register{{.SvcName}}Server(srv, New{{.SvcName}}GrpcServer({{.SvcName | toLowerCamel}}))
+}
+`
+
+var grpcConvertTmpl = `package grpcsrv
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"time"
+
+	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"{{.VoPackage}}"
+	pb "{{.PbPackage}}"
+)
+{{- range $s := .Structs }}
+
+// toPb{{$s.Name}} converts vo.{{$s.Name}} to pb.{{$s.Name}}
+func toPb{{$s.Name}}(in vo.{{$s.Name}}) *pb.{{$s.Name}} {
+	out := &pb.{{$s.Name}}{}
+	{{- range $f := $s.ToPb }}
+	out.{{$f.Name}} = {{$f.Expr}}
+	{{- end }}
+	return out
+}
+
+// fromPb{{$s.Name}} converts pb.{{$s.Name}} to vo.{{$s.Name}}
+func fromPb{{$s.Name}}(in *pb.{{$s.Name}}) vo.{{$s.Name}} {
+	var out vo.{{$s.Name}}
+	if in == nil {
+		return out
+	}
+	{{- range $f := $s.FromPb }}
+	out.{{$f.Name}} = {{$f.Expr}}
+	{{- end }}
+	return out
+}
+{{- end }}
+
+// toPbValue converts in to structpb.Value through json
+func toPbValue(in interface{}) *structpb.Value {
+	var v interface{}
+	if data, err := json.Marshal(in); err == nil {
+		_ = json.Unmarshal(data, &v)
+	}
+	out, _ := structpb.NewValue(v)
+	return out
+}
+
+func fromPbValue(in *structpb.Value) interface{} {
+	if in == nil {
+		return nil
+	}
+	return in.AsInterface()
+}
+
+// toPbStruct converts in to structpb.Struct through json
+func toPbStruct(in map[string]interface{}) *structpb.Struct {
+	if in == nil {
+		return nil
+	}
+	var v map[string]interface{}
+	if data, err := json.Marshal(in); err == nil {
+		_ = json.Unmarshal(data, &v)
+	}
+	out, _ := structpb.NewStruct(v)
+	return out
+}
+
+func fromPbStruct(in *structpb.Struct) map[string]interface{} {
+	if in == nil {
+		return nil
+	}
+	return in.AsMap()
+}
+
+func fromPbTime(in *timestamppb.Timestamp) time.Time {
+	if in == nil {
+		return time.Time{}
+	}
+	return in.AsTime()
+}
+{{- if .File }}
+
+func toPbFileModel(in *v3.FileModel) *pb.File {
+	if in == nil {
+		return nil
+	}
+	content, _ := ioutil.ReadAll(in.Reader)
+	return &pb.File{
+		Filename: in.Filename,
+		Content:  content,
+	}
+}
+
+func fromPbFileModel(in *pb.File) *v3.FileModel {
+	if in == nil {
+		return nil
+	}
+	return &v3.FileModel{
+		Filename: in.Filename,
+		Reader:   ioutil.NopCloser(bytes.NewReader(in.Content)),
+	}
+}
+
+func toPbFileHeader(in *multipart.FileHeader) *pb.File {
+	if in == nil {
+		return nil
+	}
+	f, err := in.Open()
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	content, _ := ioutil.ReadAll(f)
+	return &pb.File{
+		Filename: in.Filename,
+		Content:  content,
+	}
+}
+
+// fromPbFileHeader builds multipart.FileHeader by parsing in as multipart form in memory
+func fromPbFileHeader(in *pb.File) *multipart.FileHeader {
+	if in == nil {
+		return nil
+	}
+	filename := in.Filename
+	if filename == "" {
+		// part without filename is parsed as form value rather than file
+		filename = "file"
+	}
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	part, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		return nil
+	}
+	_, _ = part.Write(in.Content)
+	_ = w.Close()
+	form, err := multipart.NewReader(&buf, w.Boundary()).ReadForm(int64(len(in.Content)) + 1024)
+	if err != nil {
+		return nil
+	}
+	if len(form.File["file"]) == 0 {
+		return nil
+	}
+	return form.File["file"][0]
+}
+
+// sendFile sends content of f to stream in chunks and closes f
+func sendFile(f *os.File, stream interface{ Send(*pb.File) error }) error {
+	if f == nil {
+		return nil
+	}
+	defer f.Close()
+	filename := filepath.Base(f.Name())
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			if err := stream.Send(&pb.File{
+				Filename: filename,
+				Content:  buf[:n],
+			}); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+{{- end }}
+`
+
+func init() {
+	templateutils.Register("svc/grpchandler.go.tmpl", grpcHandlerTmpl, `ServicePackage string: import path of service package, the module name
+ServiceAlias string: package name of service package
+VoPackage string: import path of vo package
+PbPackage string: import path of go package generated by protoc
+SvcName string: name of service interface
+Methods []grpcMethod: Name string, Stream bool for methods returning *os.File, Args []string: expressions passed to service method,
+Results []string: variables receiving results, ErrVar string: variable receiving error, FileVar string: variable receiving *os.File,
+Fields []grpcAssign: Name of response message field and Expr assigned to it
+Functions: toLowerCamel, join`)
+	templateutils.Register("svc/grpcconvert.go.tmpl", grpcConvertTmpl, `VoPackage string: import path of vo package
+PbPackage string: import path of go package generated by protoc
+Structs []grpcStruct: structs in vo package, Name string, ToPb []grpcAssign: fields of protobuf message assigned from vo struct,
+FromPb []grpcAssign: fields of vo struct assigned from protobuf message. grpcAssign has Name and Expr
+File bool: whether file message is used`)
+}
+
+// GenGrpcSrv generates grpc server adapter calling service implementation into transport/grpcsrv/handler.go,
+// and functions converting between vo structs and protobuf messages into transport/grpcsrv/convert.go.
+// Both files are overwritten because they are derived from svc.go and vo package completely.
+// It returns error if any type is not supported.
+func GenGrpcSrv(dir string, ic astutils.InterfaceCollector) error {
+	var (
+		err         error
+		modfile     string
+		modName     string
+		firstLine   string
+		modf        fileutils.File
+		grpcDir     string
+		handlerfile string
+		convertfile string
+		tpl         *template.Template
+		methods     []grpcMethod
+		structs     []grpcStruct
+	)
+	grpcDir = filepath.Join(dir, "transport", "grpcsrv")
+	if err = fileutils.MkdirAll(grpcDir, os.ModePerm); err != nil {
+		return errors.WithStack(err)
+	}
+	handlerfile = filepath.Join(grpcDir, "handler.go")
+	convertfile = filepath.Join(grpcDir, "convert.go")
+	for _, file := range []string{handlerfile, convertfile} {
+		if _, err = fileutils.Stat(file); err == nil {
+			logrus.Warningln("file " + file + " will be overwrited")
+		}
+	}
+
+	modfile = filepath.Join(dir, "go.mod")
+	if modf, err = fileutils.Open(modfile); err != nil {
+		return errors.WithStack(err)
+	}
+	defer modf.Close()
+	reader := bufio.NewReader(modf)
+	if firstLine, err = reader.ReadString('\n'); err != nil {
+		return errors.WithStack(err)
+	}
+	modName = strings.TrimSpace(strings.TrimPrefix(firstLine, "module"))
+
+	vos, named := grpcVosOf(dir)
+	gt := newGrpcTypes(vos, named)
+	for _, method := range ic.Interfaces[0].Methods {
+		var (
+			params  []astutils.FieldMeta
+			results []astutils.FieldMeta
+			stream  bool
+		)
+		if params, err = grpcParams(method); err != nil {
+			return err
+		}
+		if results, stream, err = grpcResults(method); err != nil {
+			return err
+		}
+		// validates types and collects messages used the same way as GenGrpcProto
+		for _, fields := range [][]astutils.FieldMeta{params, results} {
+			if _, err = gt.protoFieldsOf(fields); err != nil {
+				return errors.Wrapf(err, "method %s", method.Name)
+			}
+			for _, field := range fields {
+				if err = gt.checkGoType(field.Type); err != nil {
+					return errors.Wrapf(err, "method %s", method.Name)
+				}
+			}
+		}
+		gm := grpcMethod{
+			Name:   method.Name,
+			Stream: stream,
+		}
+		for _, param := range method.Params {
+			if param.Type == "context.Context" {
+				if stream {
+					gm.Args = append(gm.Args, "stream.Context()")
+				} else {
+					gm.Args = append(gm.Args, "ctx")
+				}
+				continue
+			}
+			gm.Args = append(gm.Args, gt.fromPb(param.Type, "in."+pbName(strcase.ToSnake(param.Name))))
+		}
+		for _, result := range method.Results {
+			v := "_" + result.Name
+			gm.Results = append(gm.Results, v)
+			switch result.Type {
+			case "error":
+				gm.ErrVar = v
+			case "*os.File":
+				gm.FileVar = v
+			}
+		}
+		for _, result := range results {
+			gm.Fields = append(gm.Fields, grpcAssign{
+				Name: pbName(strcase.ToSnake(result.Name)),
+				Expr: gt.toPb(result.Type, "_"+result.Name),
+			})
+		}
+		if stream {
+			gt.file = true
+		}
+		methods = append(methods, gm)
+	}
+	if vos, err = gt.usedVos(vos); err != nil {
+		return err
+	}
+	for _, item := range vos {
+		gs := grpcStruct{
+			Name: item.Name,
+		}
+		for _, field := range item.Fields {
+			if err = gt.checkGoType(field.Type); err != nil {
+				return errors.Wrapf(err, "struct %s field %s", item.Name, field.Name)
+			}
+			name := pbName(strcase.ToSnake(field.Name))
+			gs.ToPb = append(gs.ToPb, grpcAssign{
+				Name: name,
+				Expr: gt.toPb(field.Type, "in."+field.Name),
+			})
+			gs.FromPb = append(gs.FromPb, grpcAssign{
+				Name: field.Name,
+				Expr: gt.fromPb(field.Type, "in."+name),
+			})
+		}
+		structs = append(structs, gs)
+	}
+
+	funcMap := make(map[string]interface{})
+	funcMap["toLowerCamel"] = strcase.ToLowerCamel
+	funcMap["join"] = strings.Join
+	var buf bytes.Buffer
+	if tpl, err = template.New("grpchandler.go.tmpl").Funcs(funcMap).Parse(templateutils.Lookup("svc/grpchandler.go.tmpl")); err != nil {
+		return errors.WithStack(err)
+	}
+	if err = tpl.Execute(&buf, struct {
+		ServicePackage string
+		ServiceAlias   string
+		VoPackage      string
+		PbPackage      string
+		SvcName        string
+		Methods        []grpcMethod
+	}{
+		ServicePackage: modName,
+		ServiceAlias:   ic.Package.Name,
+		VoPackage:      modName + "/vo",
+		PbPackage:      modName + "/transport/grpcsrv/pb",
+		SvcName:        ic.Interfaces[0].Name,
+		Methods:        methods,
+	}); err != nil {
+		return errors.WithStack(err)
+	}
+	astutils.FixImport(buf.Bytes(), handlerfile)
+
+	buf.Reset()
+	if tpl, err = template.New("grpcconvert.go.tmpl").Parse(templateutils.Lookup("svc/grpcconvert.go.tmpl")); err != nil {
+		return errors.WithStack(err)
+	}
+	if err = tpl.Execute(&buf, struct {
+		VoPackage string
+		PbPackage string
+		Structs   []grpcStruct
+		File      bool
+	}{
+		VoPackage: modName + "/vo",
+		PbPackage: modName + "/transport/grpcsrv/pb",
+		Structs:   structs,
+		File:      gt.file,
+	}); err != nil {
+		return errors.WithStack(err)
+	}
+	astutils.FixImport(buf.Bytes(), convertfile)
+	return nil
+}
+
+// GenGrpcEnv appends GDD_GRPC_PORT to .env file if it is not configured yet
+func GenGrpcEnv(dir string) {
+	envfile := filepath.Join(dir, ".env")
+	content, err := fileutils.ReadFile(envfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return
+		}
+		panic(err)
+	}
+	if bytes.Contains(content, []byte("GDD_GRPC_PORT")) {
+		return
+	}
+	if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+		content = append(content, '\n')
+	}
+	content = append(content, []byte(`
+# GDD_GRPC_PORT port of grpc server, registered into memberlist meta so clients can choose the protocol
+GDD_GRPC_PORT=50051
+`)...)
+	if err = fileutils.WriteFile(envfile, content, os.ModePerm); err != nil {
+		panic(err)
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/astutils"
 	"github.com/unionj-cloud/go-doudou/copier"
+	"github.com/unionj-cloud/go-doudou/fileutils"
 	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"os"
 	"path/filepath"
 	"strings"
@@ -35,6 +37,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 	"{{.VoPackage}}"
 )
 
@@ -98,7 +101,7 @@ func (receiver *{{.Meta.Name}}Client) SetClient(client *resty.Client) {
 		_req.SetFileReader("{{$p.Name}}", {{$p.Name}}.Filename, {{$p.Name}}.Reader)
 		{{- end}}
 		{{- else if eq $p.Type "context.Context" }}
-		_req.SetContext({{$p.Name}})
+		_req.SetContext({{ clientCtx $.Meta.Name $m $p.Name }})
 		{{- else if not (isBuiltin $p)}}
 		_req.SetBody({{$p.Name}})
 		{{- else if contains $p.Type "["}}
@@ -241,7 +244,7 @@ func New{{.Meta.Name}}(opts ...ddhttp.DdClientOption) *{{.Meta.Name}}Client {
 	}
 
 	svcClient.client.OnBeforeRequest(func(_ *resty.Client, request *resty.Request) error {
-		request.URL = svcClient.provider.SelectServer() + request.URL
+		request.URL = ddhttp.SelectServer(request.Context(), svcClient.provider) + request.URL
 		return nil
 	})
 
@@ -265,12 +268,20 @@ func restyMethod(method string) string {
 	return strings.Title(strings.ToLower(httpMethod(method)))
 }
 
+func init() {
+	templateutils.Register("svc/client.go.tmpl", clientTmpl, `VoPackage string: import path of vo package
+Meta astutils.InterfaceMeta: service interface
+Env string: name of environment variable of service base url
+RoutePatternStrategy int: 1 for routes without splitting method names by slashes, otherwise 0
+Functions: toLowerCamel, toCamel, httpMethod, pattern, lower, contains, isBuiltin, restyMethod, toUpper, noSplitPattern, clientCtx`)
+}
+
 // GenGoClient generates golang http client code from result of parsing svc.go file in project root path
 func GenGoClient(dir string, ic astutils.InterfaceCollector, env string, routePatternStrategy int) {
 	var (
 		err        error
 		clientfile string
-		f          *os.File
+		f          fileutils.File
 		tpl        *template.Template
 		sqlBuf     bytes.Buffer
 		clientDir  string
@@ -279,23 +290,23 @@ func GenGoClient(dir string, ic astutils.InterfaceCollector, env string, routePa
 		modfile    string
 		modName    string
 		firstLine  string
-		modf       *os.File
+		modf       fileutils.File
 		meta       astutils.InterfaceMeta
 	)
 	clientDir = filepath.Join(dir, "client")
-	if err = os.MkdirAll(clientDir, os.ModePerm); err != nil {
+	if err = fileutils.MkdirAll(clientDir, os.ModePerm); err != nil {
 		panic(err)
 	}
 
 	clientfile = filepath.Join(clientDir, "client.go")
-	fi, err = os.Stat(clientfile)
+	fi, err = fileutils.Stat(clientfile)
 	if err != nil && !os.IsNotExist(err) {
 		panic(err)
 	}
 	if fi != nil {
 		logrus.Warningln("file client.go will be overwrited")
 	}
-	if f, err = os.Create(clientfile); err != nil {
+	if f, err = fileutils.Create(clientfile); err != nil {
 		panic(err)
 	}
 	defer f.Close()
@@ -306,7 +317,7 @@ func GenGoClient(dir string, ic astutils.InterfaceCollector, env string, routePa
 	}
 
 	modfile = filepath.Join(dir, "go.mod")
-	if modf, err = os.Open(modfile); err != nil {
+	if modf, err = fileutils.Open(modfile); err != nil {
 		panic(err)
 	}
 	reader := bufio.NewReader(modf)
@@ -326,7 +337,8 @@ func GenGoClient(dir string, ic astutils.InterfaceCollector, env string, routePa
 	funcMap["restyMethod"] = restyMethod
 	funcMap["toUpper"] = strings.ToUpper
 	funcMap["noSplitPattern"] = noSplitPattern
-	if tpl, err = template.New("client.go.tmpl").Funcs(funcMap).Parse(clientTmpl); err != nil {
+	funcMap["clientCtx"] = clientCtxOf
+	if tpl, err = template.New("client.go.tmpl").Funcs(funcMap).Parse(templateutils.Lookup("svc/client.go.tmpl")); err != nil {
 		panic(err)
 	}
 	if err = tpl.Execute(&sqlBuf, struct {
@@ -1,7 +1,9 @@
 package codegen
 
 import (
+	"github.com/stretchr/testify/assert"
 	"github.com/unionj-cloud/go-doudou/astutils"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
@@ -63,3 +65,67 @@ func TestGenGoClient2(t *testing.T) {
 		})
 	}
 }
+
+func TestGenGoClientHedge(t *testing.T) {
+	dir := testDir + "clienthedge"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	svcfile := filepath.Join(dir, "svc.go")
+	err := ioutil.WriteFile(svcfile, []byte(`package service
+
+import (
+	"context"
+)
+
+type Clienthedge interface {
+	// GetUser returns user name
+	// @hedge(p95, 100ms)
+	GetUser(ctx context.Context, id int) (name string, err error)
+	GetOrder(ctx context.Context, id int) (no string, err error)
+}
+`), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic := astutils.BuildInterfaceCollector(svcfile, astutils.ExprString)
+	GenGoClient(dir, ic, "", 1)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "client", "client.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := string(data)
+	assert.Contains(t, source, `_req.SetContext(ddhttp.WithHedging(ctx, ddhttp.HedgePolicy{Name: "Clienthedge.GetUser", Percentile: 95, Delay: 100 * time.Millisecond}))`)
+	assert.Contains(t, source, "_req.SetContext(ctx)")
+}
+
+func TestGenGoClientCache(t *testing.T) {
+	dir := testDir + "clientcache"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	svcfile := filepath.Join(dir, "svc.go")
+	err := ioutil.WriteFile(svcfile, []byte(`package service
+
+import (
+	"context"
+)
+
+type Clientcache interface {
+	// GetUser returns user name
+	// @cache()
+	GetUser(ctx context.Context, id int) (name string, err error)
+	GetOrder(ctx context.Context, id int) (no string, err error)
+}
+`), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic := astutils.BuildInterfaceCollector(svcfile, astutils.ExprString)
+	GenGoClient(dir, ic, "", 1)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "client", "client.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := string(data)
+	assert.Contains(t, source, `_req.SetContext(ddhttp.WithCaching(ctx, "Clientcache.GetUser"))`)
+	assert.Contains(t, source, "_req.SetContext(ctx)")
+}
@@ -6,17 +6,29 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/astutils"
 	"github.com/unionj-cloud/go-doudou/copier"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"go/ast"
 	"go/parser"
 	"go/token"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"text/template"
 )
 
+const (
+	clientProxyTmplName        = "svc/clientproxy.go.tmpl"
+	clientProxyMethodsTmplName = "svc/clientproxy_methods.go.tmpl"
+)
+
 var appendTmpl = `
+{{- define "fallbackParams" }}
+	{{- range $p := .Params }}{{ $p.Name }} {{ $p.Type }}, {{ end }}_err error
+{{- end }}
+{{- define "fallbackResults" }}
+	{{- range $i, $r := .Results }}{{ if $i }}, {{ end }}{{ $r.Name }} {{ $r.Type }}{{ end }}
+{{- end }}
 {{- range $m := .Meta.Methods }}
 	func (receiver *{{$.SvcName}}ClientProxy) {{$m.Name}}({{- range $i, $p := $m.Params}}
     {{- if $i}},{{end}}
@@ -30,14 +42,14 @@ var appendTmpl = `
 		{{- range $p := $m.Params }}
 			{{- if not $ctxSet }}
 				{{- if eq $p.Type "context.Context" }}
-		if _err := receiver.runner.Run({{$p.Name}}, func(ctx context.Context) error {
+		if _err := receiver.runner.Run(ddhttp.WithResilienceMethod({{$p.Name}}, "{{$m.Name}}"), func(ctx context.Context) error {
 				{{- $ctxSet = true }}
 				{{- end }}
 			{{- end }}
 		{{- end }}
 		
 		{{- if not $ctxSet }}
-		if _err := receiver.runner.Run(context.Background(), func(ctx context.Context) error {
+		if _err := receiver.runner.Run(ddhttp.WithResilienceMethod(context.Background(), "{{$m.Name}}"), func(ctx context.Context) error {
 		{{- end }}
 			{{ range $i, $r := $m.Results }}{{- if $i}},{{- end}}{{- $r.Name }}{{- end }} = receiver.client.{{$m.Name}}(
 				{{- range $p := $m.Params }}
@@ -53,10 +65,22 @@ var appendTmpl = `
 			{{- end }}
 			return nil
 		}); _err != nil {
-			// you can implement your fallback logic here
 			if errors.Is(_err, rerrors.ErrCircuitOpen) {
 				receiver.logger.Error(_err)
 			}
+			{{- if $.Fallback }}
+			if _fallback := receiver.fallback; _fallback != nil {
+				{{ if $m.Results }}return {{ end }}_fallback.{{$m.Name}}(
+					{{- range $p := $m.Params }}
+					{{ $p.Name }},
+					{{- end }}
+					_err,
+				)
+				{{- if not $m.Results }}
+				return
+				{{- end }}
+			}
+			{{- end }}
 			{{- range $r := $m.Results }}
 				{{- if eq $r.Type "error" }}
 					{{ $r.Name }} = errors.Wrap(_err, "call {{$m.Name}} fail")
@@ -73,18 +97,12 @@ var baseTmpl = `package client
 import (
 	"context"
 	"github.com/pkg/errors"
-	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"github.com/slok/goresilience"
-	"github.com/slok/goresilience/circuitbreaker"
 	rerrors "github.com/slok/goresilience/errors"
-	"github.com/slok/goresilience/metrics"
-	"github.com/slok/goresilience/retry"
-	"github.com/slok/goresilience/timeout"
 	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
-	"github.com/unionj-cloud/go-doudou/svc/config"
+	ddhttp "github.com/unionj-cloud/go-doudou/svc/http"
 	"os"
-	"time"
 	{{.ServiceAlias}} "{{.ServicePackage}}"
 	"{{.VoPackage}}"
 )
@@ -93,9 +111,18 @@ type {{.SvcName}}ClientProxy struct {
 	client {{.ServiceAlias}}.{{.SvcName}}
 	logger *logrus.Logger
 	runner goresilience.Runner
+	fallback {{.SvcName}}Fallback
+}
+
+// {{.SvcName}}Fallback provides fallback logic for {{.SvcName}}ClientProxy. When a call fails or the circuit is open,
+// the method with the same name is called with the original arguments and the error, and its results are returned.
+type {{.SvcName}}Fallback interface {
+{{- range $m := .Meta.Methods }}
+	{{$m.Name}}({{ template "fallbackParams" $m }}) ({{ template "fallbackResults" $m }})
+{{- end }}
 }
 
-` + appendTmpl + `
+{{- template "svc/clientproxy_methods.go.tmpl" . }}
 
 type ProxyOption func(*{{.SvcName}}ClientProxy)
 
@@ -111,6 +138,12 @@ func WithLogger(logger *logrus.Logger) ProxyOption {
 	}
 }
 
+func WithFallback(fallback {{.SvcName}}Fallback) ProxyOption {
+	return func(proxy *{{.SvcName}}ClientProxy) {
+		proxy.fallback = fallback
+	}
+}
+
 func New{{.SvcName}}ClientProxy(client {{.ServiceAlias}}.{{.SvcName}}, opts ...ProxyOption) *{{.SvcName}}ClientProxy {
 	cp := &{{.SvcName}}ClientProxy{
 		client: client,
@@ -122,47 +155,66 @@ func New{{.SvcName}}ClientProxy(client {{.ServiceAlias}}.{{.SvcName}}, opts ...P
 	}
 
 	if cp.runner == nil {
-		var mid []goresilience.Middleware
-
-		if config.GddManage.Load() == "true" {
-			mid = append(mid, metrics.NewMiddleware("{{.ServicePackage}}_client", metrics.NewPrometheusRecorder(prometheus.DefaultRegisterer)))
-		}
-
-		mid = append(mid, circuitbreaker.NewMiddleware(circuitbreaker.Config{
-			ErrorPercentThresholdToOpen:        50,
-			MinimumRequestToOpen:               6,
-			SuccessfulRequiredOnHalfOpen:       1,
-			WaitDurationInOpenState:            5 * time.Second,
-			MetricsSlidingWindowBucketQuantity: 10,
-			MetricsBucketDuration:              1 * time.Second,
-		}),
-			timeout.NewMiddleware(timeout.Config{
-				Timeout: 3 * time.Minute,
-			}),
-			retry.NewMiddleware(retry.Config{
-				Times: 3,
-			}))
-
-		cp.runner = goresilience.RunnerChain(mid...)
+		// circuit breaker, timeout, retry and bulkhead can be configured by environment variables
+		// such as GDD_CLIENT_{{ toUpper .SvcName }}_CB_ERROR_PERCENT for all methods
+		// or GDD_CLIENT_{{ toUpper .SvcName }}_<METHOD>_CB_ERROR_PERCENT for a single method
+		cp.runner = ddhttp.NewResilienceRunner("{{.SvcName}}", ddhttp.DefaultResiliencePolicy(), ddhttp.WithMetricsID("{{.ServicePackage}}_client"))
 	}
 
 	return cp
 }
 `
 
-func unimplementedSvcMethods(meta *astutils.InterfaceMeta, clientfile string) {
+func init() {
+	templateutils.Register(clientProxyTmplName, baseTmpl, `VoPackage string: import path of vo package
+Meta astutils.InterfaceMeta: service interface
+ServicePackage string: import path of service package
+ServiceAlias string: package name of service package
+SvcName string: name of service interface
+Fallback bool: whether the client proxy has fallback field
+Append bool: whether appending to existing clientproxy.go
+Functions: toUpper
+Calls svc/clientproxy_methods.go.tmpl to generate methods, and templates fallbackParams and fallbackResults defined in it to generate XXXFallback interface`)
+	templateutils.Register(clientProxyMethodsTmplName, appendTmpl, `VoPackage string: import path of vo package
+Meta astutils.InterfaceMeta: service interface, only with missing or stale methods when appending to existing clientproxy.go
+ServicePackage string: import path of service package
+ServiceAlias string: package name of service package
+SvcName string: name of service interface
+Fallback bool: whether the client proxy has fallback field
+Append bool: whether appending to existing clientproxy.go
+Functions: toUpper
+Defines templates fallbackParams and fallbackResults taking astutils.MethodMeta`)
+}
+
+// fallbackSignatureOf formats signature of method of XXXFallback interface for method of service
+func fallbackSignatureOf(method astutils.MethodMeta) string {
+	method.Params = append(append([]astutils.FieldMeta{}, method.Params...), astutils.FieldMeta{
+		Name: "_err",
+		Type: "error",
+	})
+	return signatureOf(method)
+}
+
+// unimplementedSvcMethods removes methods already implemented by client proxy from meta, except those with stale
+// signatures, reports whether the client proxy has fallback field and returns methods with stale signatures
+func unimplementedSvcMethods(meta *astutils.InterfaceMeta, clientfile string, src []byte) (bool, []astutils.MethodMeta) {
 	fset := token.NewFileSet()
-	root, err := parser.ParseFile(fset, clientfile, nil, parser.ParseComments)
+	root, err := parser.ParseFile(fset, clientfile, src, parser.ParseComments)
 	if err != nil {
 		panic(err)
 	}
 	sc := astutils.NewStructCollector(astutils.ExprString)
 	ast.Walk(sc, root)
+	var changed []astutils.MethodMeta
 	if handlers, exists := sc.Methods[meta.Name+"ClientProxy"]; exists {
 		var notimplemented []astutils.MethodMeta
 		for _, item := range meta.Methods {
 			for _, handler := range handlers {
 				if item.Name == handler.Name {
+					if !sameSignature(item, handler) {
+						notimplemented = append(notimplemented, item)
+						changed = append(changed, item)
+					}
 					goto L
 				}
 			}
@@ -173,6 +225,17 @@ func unimplementedSvcMethods(meta *astutils.InterfaceMeta, clientfile string) {
 
 		meta.Methods = notimplemented
 	}
+	for _, item := range sc.Structs {
+		if item.Name != meta.Name+"ClientProxy" {
+			continue
+		}
+		for _, field := range item.Fields {
+			if field.Name == "fallback" {
+				return true, changed
+			}
+		}
+	}
+	return false, changed
 }
 
 // GenGoClientProxy wraps client with resiliency features
@@ -180,7 +243,7 @@ func GenGoClientProxy(dir string, ic astutils.InterfaceCollector) {
 	var (
 		err             error
 		clientfile      string
-		f               *os.File
+		f               fileutils.File
 		tpl             *template.Template
 		buf             bytes.Buffer
 		clientDir       string
@@ -188,17 +251,21 @@ func GenGoClientProxy(dir string, ic astutils.InterfaceCollector) {
 		modfile         string
 		modName         string
 		firstLine       string
-		modf            *os.File
+		modf            fileutils.File
 		meta            astutils.InterfaceMeta
 		clientProxyTmpl string
+		appendMode      bool
+		fallback        bool
+		changed         []astutils.MethodMeta
+		original        []byte
 	)
 	clientDir = filepath.Join(dir, "client")
-	if err = os.MkdirAll(clientDir, os.ModePerm); err != nil {
+	if err = fileutils.MkdirAll(clientDir, os.ModePerm); err != nil {
 		panic(err)
 	}
 
 	clientfile = filepath.Join(clientDir, "clientproxy.go")
-	fi, err = os.Stat(clientfile)
+	fi, err = fileutils.Stat(clientfile)
 	if err != nil && !os.IsNotExist(err) {
 		panic(err)
 	}
@@ -208,23 +275,29 @@ func GenGoClientProxy(dir string, ic astutils.InterfaceCollector) {
 	}
 	if fi != nil {
 		logrus.Warningln("New content will be append to clientproxy.go file")
-		if f, err = os.OpenFile(clientfile, os.O_APPEND, os.ModePerm); err != nil {
+		if original, err = fileutils.ReadFile(clientfile); err != nil {
 			panic(err)
 		}
-		defer f.Close()
-		clientProxyTmpl = appendTmpl
+		clientProxyTmpl = clientProxyMethodsTmplName
 
-		unimplementedSvcMethods(&meta, clientfile)
+		appendMode = true
+		fallback, changed = unimplementedSvcMethods(&meta, clientfile, original)
+		reportChanged(clientfile, changed)
+		if fallback && len(meta.Methods) > 0 {
+			logrus.Warnf("please update implementations of %sFallback interface for new methods or methods with changed signatures",
+				meta.Name)
+		}
 	} else {
-		if f, err = os.Create(clientfile); err != nil {
+		if f, err = fileutils.Create(clientfile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
-		clientProxyTmpl = baseTmpl
+		clientProxyTmpl = clientProxyTmplName
+		fallback = true
 	}
 
 	modfile = filepath.Join(dir, "go.mod")
-	if modf, err = os.Open(modfile); err != nil {
+	if modf, err = fileutils.Open(modfile); err != nil {
 		panic(err)
 	}
 	reader := bufio.NewReader(modf)
@@ -233,30 +306,53 @@ func GenGoClientProxy(dir string, ic astutils.InterfaceCollector) {
 	}
 	modName = strings.TrimSpace(strings.TrimPrefix(firstLine, "module"))
 
-	if tpl, err = template.New("clientproxy.go.tmpl").Parse(clientProxyTmpl); err != nil {
+	funcMap := make(map[string]interface{})
+	funcMap["toUpper"] = strings.ToUpper
+	if tpl, err = template.New(clientProxyTmpl).Funcs(funcMap).Parse(templateutils.Lookup(clientProxyTmpl)); err != nil {
 		panic(err)
 	}
+	if tpl.Lookup(clientProxyMethodsTmplName) == nil {
+		if _, err = tpl.New(clientProxyMethodsTmplName).Parse(templateutils.Lookup(clientProxyMethodsTmplName)); err != nil {
+			panic(err)
+		}
+	}
 	if err = tpl.Execute(&buf, struct {
 		VoPackage      string
 		Meta           astutils.InterfaceMeta
 		ServicePackage string
 		ServiceAlias   string
 		SvcName        string
+		Fallback       bool
+		Append         bool
 	}{
 		VoPackage:      modName + "/vo",
 		Meta:           meta,
 		ServicePackage: modName,
 		ServiceAlias:   ic.Package.Name,
 		SvcName:        ic.Interfaces[0].Name,
+		Fallback:       fallback,
+		Append:         appendMode,
 	}); err != nil {
 		panic(err)
 	}
 
-	original, err := ioutil.ReadAll(f)
-	if err != nil {
-		panic(err)
+	stale := make(map[string]bool)
+	fallbacks := make(map[string]string)
+	for _, method := range changed {
+		stale[meta.Name+"ClientProxy."+method.Name] = true
+		fallbacks[method.Name] = fallbackSignatureOf(method)
 	}
-
+	var added []string
+	if appendMode && fallback {
+		for _, method := range meta.Methods {
+			if _, exists := fallbacks[method.Name]; !exists {
+				added = append(added, fallbackSignatureOf(method))
+			}
+		}
+	}
+	original = removeFuncs(original, stale)
+	original = replaceInterfaceMethods(original, meta.Name+"Fallback", fallbacks)
+	original = appendInterfaceMethods(original, meta.Name+"Fallback", added)
 	original = append(original, buf.Bytes()...)
 	astutils.FixImport(original, clientfile)
 }
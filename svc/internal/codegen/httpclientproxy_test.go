@@ -1,9 +1,12 @@
 package codegen
 
 import (
+	"github.com/stretchr/testify/assert"
 	"github.com/unionj-cloud/go-doudou/astutils"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -63,3 +66,86 @@ func TestGenGoClientProxy1(t *testing.T) {
 		})
 	}
 }
+
+func TestGenGoClientProxyResilience(t *testing.T) {
+	dir := testDir + "clientproxyresilience"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	svcfile := filepath.Join(testDir, "svc.go")
+	ic := astutils.BuildInterfaceCollector(svcfile, astutils.ExprString)
+	GenGoClientProxy(dir, ic)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "client", "clientproxy.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := string(data)
+	assert.Contains(t, source, `cp.runner = ddhttp.NewResilienceRunner("Usersvc", ddhttp.DefaultResiliencePolicy(), ddhttp.WithMetricsID("testdataclientproxyresilience_client"))`)
+	assert.Contains(t, source, `receiver.runner.Run(ddhttp.WithResilienceMethod(ctx, "GetUser"), func(ctx context.Context) error {`)
+	assert.Contains(t, source, "GDD_CLIENT_USERSVC_CB_ERROR_PERCENT")
+}
+
+func TestGenGoClientProxyFallback(t *testing.T) {
+	dir := testDir + "clientproxyfallback"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	svcfile := filepath.Join(testDir, "svc.go")
+	GenGoClientProxy(dir, astutils.BuildInterfaceCollector(svcfile, astutils.ExprString))
+	clientfile := filepath.Join(dir, "client", "clientproxy.go")
+	data, err := ioutil.ReadFile(clientfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := string(data)
+	assert.Contains(t, source, "GetUser(ctx context.Context, userId string, photo string, _err error) (code int, data string, msg error)")
+	assert.Contains(t, source, "func WithFallback(fallback UsersvcFallback) ProxyOption {")
+	assert.Contains(t, source, "if _fallback := receiver.fallback; _fallback != nil {")
+
+	data, err = ioutil.ReadFile(svcfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newsvcfile := filepath.Join(dir, "svc.go")
+	err = ioutil.WriteFile(newsvcfile, []byte(strings.Replace(string(data), "type Usersvc interface {",
+		"type Usersvc interface {\n\tPing(ctx context.Context) (pong string, err error)\n", 1)), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	GenGoClientProxy(dir, astutils.BuildInterfaceCollector(newsvcfile, astutils.ExprString))
+	data, err = ioutil.ReadFile(clientfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source = string(data)
+	// appended methods are added to the fallback interface and call it like the other methods
+	assert.Contains(t, source, "\tPing(ctx context.Context, _err error) (pong string, err error)\n}")
+	assert.Contains(t, source, "return _fallback.Ping(")
+	assert.NotContains(t, source, "_ok")
+}
+
+func TestGenGoClientProxySignatureChanged(t *testing.T) {
+	dir := testDir + "clientproxysignature"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	svcfile := filepath.Join(testDir, "svc.go")
+	GenGoClientProxy(dir, astutils.BuildInterfaceCollector(svcfile, astutils.ExprString))
+
+	data, err := ioutil.ReadFile(svcfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newsvcfile := filepath.Join(dir, "svc.go")
+	err = ioutil.WriteFile(newsvcfile, []byte(strings.Replace(string(data), "password int", "password string", 1)), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	GenGoClientProxy(dir, astutils.BuildInterfaceCollector(newsvcfile, astutils.ExprString))
+	data, err = ioutil.ReadFile(filepath.Join(dir, "client", "clientproxy.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := string(data)
+	assert.Equal(t, 1, strings.Count(source, "func (receiver *UsersvcClientProxy) SignUp("))
+	assert.Contains(t, source, "func (receiver *UsersvcClientProxy) SignUp(ctx context.Context, username string, password string,")
+	assert.Contains(t, source, "\tSignUp(ctx context.Context, username string, password string, actived bool, score []int, _err error) (code int, data string, msg error)\n")
+	assert.NotContains(t, source, "password int")
+}
@@ -2,8 +2,10 @@ package codegen
 
 import (
 	"bytes"
+	"github.com/unionj-cloud/go-doudou/fileutils"
 	"github.com/unionj-cloud/go-doudou/sliceutils"
 	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"os"
 	"path/filepath"
 	"strings"
@@ -100,12 +102,18 @@ func httpMethod(method string) string {
 	return "POST"
 }
 
+func init() {
+	templateutils.Register("svc/handler.go.tmpl", httpHandlerTmpl, `RoutePatternStrategy int: 1 for routes without splitting method names by slashes, otherwise 0
+Meta astutils.InterfaceMeta: service interface
+Functions: httpMethod, routeName, pattern, noSplitPattern taking method name, lower`)
+}
+
 // GenHttpHandler generates http handler interface and routes
 func GenHttpHandler(dir string, ic astutils.InterfaceCollector, routePatternStrategy int) {
 	var (
 		err         error
 		handlerfile string
-		f           *os.File
+		f           fileutils.File
 		tpl         *template.Template
 		httpDir     string
 		source      string
@@ -113,19 +121,19 @@ func GenHttpHandler(dir string, ic astutils.InterfaceCollector, routePatternStra
 		fi          os.FileInfo
 	)
 	httpDir = filepath.Join(dir, "transport/httpsrv")
-	if err = os.MkdirAll(httpDir, os.ModePerm); err != nil {
+	if err = fileutils.MkdirAll(httpDir, os.ModePerm); err != nil {
 		panic(err)
 	}
 
 	handlerfile = filepath.Join(httpDir, "handler.go")
-	fi, err = os.Stat(handlerfile)
+	fi, err = fileutils.Stat(handlerfile)
 	if err != nil && !os.IsNotExist(err) {
 		panic(err)
 	}
 	if fi != nil {
 		logrus.Warningln("file handler.go will be overwrited")
 	}
-	if f, err = os.Create(handlerfile); err != nil {
+	if f, err = fileutils.Create(handlerfile); err != nil {
 		panic(err)
 	}
 	defer f.Close()
@@ -136,7 +144,7 @@ func GenHttpHandler(dir string, ic astutils.InterfaceCollector, routePatternStra
 	funcMap["pattern"] = pattern
 	funcMap["noSplitPattern"] = noSplitPattern
 	funcMap["lower"] = strings.ToLower
-	if tpl, err = template.New("handler.go.tmpl").Funcs(funcMap).Parse(httpHandlerTmpl); err != nil {
+	if tpl, err = template.New("handler.go.tmpl").Funcs(funcMap).Parse(templateutils.Lookup("svc/handler.go.tmpl")); err != nil {
 		panic(err)
 	}
 	if err = tpl.Execute(&sqlBuf, struct {
@@ -7,17 +7,23 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/astutils"
 	"github.com/unionj-cloud/go-doudou/copier"
+	"github.com/unionj-cloud/go-doudou/fileutils"
 	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"go/ast"
 	"go/parser"
 	"go/token"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"text/template"
 )
 
+const (
+	handlerimplTmplName        = "svc/handlerimpl.go.tmpl"
+	handlerimplMethodsTmplName = "svc/handlerimpl_methods.go.tmpl"
+)
+
 var httpHandlerImpl = `package httpsrv
 
 import (
@@ -50,21 +56,21 @@ func GenHttpHandlerImpl(dir string, ic astutils.InterfaceCollector) {
 		modName         string
 		firstLine       string
 		handlerimplfile string
-		f               *os.File
+		f               fileutils.File
 		tpl             *template.Template
 		source          string
 		buf             bytes.Buffer
 		httpDir         string
 	)
 	httpDir = filepath.Join(dir, "transport/httpsrv")
-	if err = os.MkdirAll(httpDir, os.ModePerm); err != nil {
+	if err = fileutils.MkdirAll(httpDir, os.ModePerm); err != nil {
 		panic(err)
 	}
 
 	handlerimplfile = filepath.Join(httpDir, "handlerimpl.go")
-	if _, err = os.Stat(handlerimplfile); os.IsNotExist(err) {
+	if _, err = fileutils.Stat(handlerimplfile); os.IsNotExist(err) {
 		modfile = filepath.Join(dir, "go.mod")
-		if f, err = os.Open(modfile); err != nil {
+		if f, err = fileutils.Open(modfile); err != nil {
 			panic(err)
 		}
 		reader := bufio.NewReader(f)
@@ -73,7 +79,7 @@ func GenHttpHandlerImpl(dir string, ic astutils.InterfaceCollector) {
 		}
 		modName = strings.TrimSpace(strings.TrimPrefix(firstLine, "module"))
 
-		if f, err = os.Create(handlerimplfile); err != nil {
+		if f, err = fileutils.Create(handlerimplfile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
@@ -81,7 +87,7 @@ func GenHttpHandlerImpl(dir string, ic astutils.InterfaceCollector) {
 		funcMap := make(map[string]interface{})
 		funcMap["toLowerCamel"] = strcase.ToLowerCamel
 		funcMap["toCamel"] = strcase.ToCamel
-		if tpl, err = template.New("handlerimpl.go.tmpl").Funcs(funcMap).Parse(httpHandlerImpl); err != nil {
+		if tpl, err = template.New("handlerimpl.go.tmpl").Funcs(funcMap).Parse(templateutils.Lookup("svc/handlerimpl_unimplemented.go.tmpl")); err != nil {
 			panic(err)
 		}
 		if err = tpl.Execute(&buf, struct {
@@ -311,7 +317,7 @@ type {{.Meta.Name}}HandlerImpl struct{
 	{{.Meta.Name | toLowerCamel}} {{.ServiceAlias}}.{{.Meta.Name}}
 }
 
-` + appendHttpHandlerImplTmpl + `
+{{- template "svc/handlerimpl_methods.go.tmpl" . }}
 
 func New{{.Meta.Name}}Handler({{.Meta.Name | toLowerCamel}} {{.ServiceAlias}}.{{.Meta.Name}}) {{.Meta.Name}}Handler {
 	return &{{.Meta.Name}}HandlerImpl{
@@ -339,6 +345,27 @@ var castFuncMap = map[string]string{
 	"[]int":         "ToIntSlice",
 }
 
+func init() {
+	templateutils.Register("svc/handlerimpl_unimplemented.go.tmpl", httpHandlerImpl, `ServicePackage string: import path of service package
+ServiceAlias string: package name of service package
+VoPackage string: import path of vo package
+Meta astutils.InterfaceMeta: service interface
+Functions: toLowerCamel, toCamel`)
+	templateutils.Register(handlerimplTmplName, initHttpHandlerImplTmpl, `ServicePackage string: import path of service package
+ServiceAlias string: package name of service package
+VoPackage string: import path of vo package
+Meta astutils.InterfaceMeta: service interface
+Omitempty bool: whether to add omitempty to json tags of response structs
+Functions: toLowerCamel, toCamel, contains, isBuiltin, isSupport reporting whether a type can be parsed from string, castFunc returning name of function parsing a type from string, convertCase converting case of json property names
+Calls svc/handlerimpl_methods.go.tmpl to generate handlers`)
+	templateutils.Register(handlerimplMethodsTmplName, appendHttpHandlerImplTmpl, `ServicePackage string: import path of service package
+ServiceAlias string: package name of service package
+VoPackage string: import path of vo package
+Meta astutils.InterfaceMeta: service interface, only with methods whose handlers are missing or stale when appending to existing handlerimpl.go
+Omitempty bool: whether to add omitempty to json tags of response structs
+Functions: toLowerCamel, toCamel, contains, isBuiltin, isSupport reporting whether a type can be parsed from string, castFunc returning name of function parsing a type from string, convertCase converting case of json property names`)
+}
+
 func isSupport(t string) bool {
 	_, exists := castFuncMap[t]
 	return exists
@@ -358,22 +385,24 @@ func GenHttpHandlerImplWithImpl(dir string, ic astutils.InterfaceCollector, omit
 		modName         string
 		firstLine       string
 		handlerimplfile string
-		f               *os.File
-		modf            *os.File
+		f               fileutils.File
+		modf            fileutils.File
 		tpl             *template.Template
 		buf             bytes.Buffer
 		httpDir         string
 		fi              os.FileInfo
 		tmpl            string
 		meta            astutils.InterfaceMeta
+		changed         []astutils.MethodMeta
+		original        []byte
 	)
 	httpDir = filepath.Join(dir, "transport/httpsrv")
-	if err = os.MkdirAll(httpDir, os.ModePerm); err != nil {
+	if err = fileutils.MkdirAll(httpDir, os.ModePerm); err != nil {
 		panic(err)
 	}
 
 	handlerimplfile = filepath.Join(httpDir, "handlerimpl.go")
-	fi, err = os.Stat(handlerimplfile)
+	fi, err = fileutils.Stat(handlerimplfile)
 	if err != nil && !os.IsNotExist(err) {
 		panic(err)
 	}
@@ -384,23 +413,23 @@ func GenHttpHandlerImplWithImpl(dir string, ic astutils.InterfaceCollector, omit
 	}
 	if fi != nil {
 		logrus.Warningln("New content will be append to handlerimpl.go file")
-		if f, err = os.OpenFile(handlerimplfile, os.O_APPEND, os.ModePerm); err != nil {
+		if original, err = fileutils.ReadFile(handlerimplfile); err != nil {
 			panic(err)
 		}
-		defer f.Close()
-		tmpl = appendHttpHandlerImplTmpl
+		tmpl = handlerimplMethodsTmplName
 
-		unimplementedMethods(&meta, handlerimplfile)
+		changed = unimplementedMethods(&meta, handlerimplfile, original)
+		reportChanged(handlerimplfile, changed)
 	} else {
-		if f, err = os.Create(handlerimplfile); err != nil {
+		if f, err = fileutils.Create(handlerimplfile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
-		tmpl = initHttpHandlerImplTmpl
+		tmpl = handlerimplTmplName
 	}
 
 	modfile = filepath.Join(dir, "go.mod")
-	if modf, err = os.Open(modfile); err != nil {
+	if modf, err = fileutils.Open(modfile); err != nil {
 		panic(err)
 	}
 	reader := bufio.NewReader(modf)
@@ -417,9 +446,14 @@ func GenHttpHandlerImplWithImpl(dir string, ic astutils.InterfaceCollector, omit
 	funcMap["isSupport"] = isSupport
 	funcMap["castFunc"] = castFunc
 	funcMap["convertCase"] = caseconvertor
-	if tpl, err = template.New("handlerimpl.go.tmpl").Funcs(funcMap).Parse(tmpl); err != nil {
+	if tpl, err = template.New(tmpl).Funcs(funcMap).Parse(templateutils.Lookup(tmpl)); err != nil {
 		panic(err)
 	}
+	if tpl.Lookup(handlerimplMethodsTmplName) == nil {
+		if _, err = tpl.New(handlerimplMethodsTmplName).Parse(templateutils.Lookup(handlerimplMethodsTmplName)); err != nil {
+			panic(err)
+		}
+	}
 	if err = tpl.Execute(&buf, struct {
 		ServicePackage string
 		ServiceAlias   string
@@ -436,36 +470,47 @@ func GenHttpHandlerImplWithImpl(dir string, ic astutils.InterfaceCollector, omit
 		panic(err)
 	}
 
-	original, err := ioutil.ReadAll(f)
-	if err != nil {
-		panic(err)
+	stale := make(map[string]bool)
+	for _, method := range changed {
+		stale[meta.Name+"HandlerImpl."+method.Name] = true
 	}
-
+	original = removeFuncs(original, stale)
 	original = append(original, buf.Bytes()...)
 	astutils.FixImport(original, handlerimplfile)
 }
 
-func unimplementedMethods(meta *astutils.InterfaceMeta, handlerimplfile string) {
+// unimplementedMethods removes methods already implemented by handlers from meta, except those whose handlers are
+// generated from stale signatures of service methods, and returns the stale ones
+func unimplementedMethods(meta *astutils.InterfaceMeta, handlerimplfile string, src []byte) []astutils.MethodMeta {
 	fset := token.NewFileSet()
-	root, err := parser.ParseFile(fset, handlerimplfile, nil, parser.ParseComments)
+	root, err := parser.ParseFile(fset, handlerimplfile, src, parser.ParseComments)
 	if err != nil {
 		panic(err)
 	}
-	sc := astutils.NewStructCollector(astutils.ExprString)
-	ast.Walk(sc, root)
-	if handlers, exists := sc.Methods[meta.Name+"HandlerImpl"]; exists {
-		var notimplemented []astutils.MethodMeta
-		for _, item := range meta.Methods {
-			for _, handler := range handlers {
-				if item.Name == handler.Name {
-					goto L
-				}
-			}
+	handlers := make(map[string]*ast.FuncDecl)
+	for _, decl := range root.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && funcKey(fn) == meta.Name+"HandlerImpl."+fn.Name.Name {
+			handlers[fn.Name.Name] = fn
+		}
+	}
+	if len(handlers) == 0 {
+		return nil
+	}
+	var (
+		notimplemented []astutils.MethodMeta
+		changed        []astutils.MethodMeta
+	)
+	for _, item := range meta.Methods {
+		handler, exists := handlers[item.Name]
+		if !exists {
 			notimplemented = append(notimplemented, item)
-
-		L:
+			continue
+		}
+		if called, ok := handlerCallOf(handler, strcase.ToLowerCamel(meta.Name)); ok && !sameSignature(called, item) {
+			notimplemented = append(notimplemented, item)
+			changed = append(changed, item)
 		}
-
-		meta.Methods = notimplemented
 	}
+	meta.Methods = notimplemented
+	return changed
 }
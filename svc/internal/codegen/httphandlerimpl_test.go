@@ -1,11 +1,15 @@
 package codegen
 
 import (
+	"bytes"
 	"github.com/iancoleman/strcase"
+	"github.com/stretchr/testify/assert"
 	"github.com/unionj-cloud/go-doudou/astutils"
+	"github.com/unionj-cloud/go-doudou/fileutils"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -171,3 +175,56 @@ func TestGenHttpHandlerImplWithImpl2(t *testing.T) {
 		})
 	}
 }
+
+func TestGenHttpHandlerImplWithImpl_SignatureChanged(t *testing.T) {
+	dir := testDir + "handlerImplSignature"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	svcfile := filepath.Join(testDir, "svc.go")
+	GenHttpHandlerImplWithImpl(dir, astutils.BuildInterfaceCollector(svcfile, astutils.ExprString), false, strcase.ToLowerCamel)
+
+	data, err := ioutil.ReadFile(svcfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newsvcfile := filepath.Join(dir, "svc.go")
+	err = ioutil.WriteFile(newsvcfile, []byte(strings.Replace(string(data), "photo string,\n", "photo string,\n\t\tsize int,\n", 1)), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	GenHttpHandlerImplWithImpl(dir, astutils.BuildInterfaceCollector(newsvcfile, astutils.ExprString), false, strcase.ToLowerCamel)
+	data, err = ioutil.ReadFile(filepath.Join(dir, "transport", "httpsrv", "handlerimpl.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := string(data)
+	assert.Equal(t, 1, strings.Count(source, "func (receiver *UsersvcHandlerImpl) GetUser("))
+	assert.Contains(t, source, `cast.ToIntE(_req.FormValue("size"))`)
+	assert.Equal(t, 1, strings.Count(source, "func (receiver *UsersvcHandlerImpl) SignUp("))
+}
+
+func TestGenHttpHandlerImplWithImpl_DryRun(t *testing.T) {
+	dir := testDir + "handlerImplDryRun"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	fileutils.SetDryRun(true)
+	defer fileutils.SetDryRun(false)
+	ic := astutils.BuildInterfaceCollector(filepath.Join(testDir, "svc.go"), astutils.ExprString)
+	GenHttpHandlerImplWithImpl(dir, ic, false, strcase.ToLowerCamel)
+	GenHttpHandlerImplWithImpl(dir, ic, false, strcase.ToLowerCamel)
+
+	handlerimplfile := filepath.Join(dir, "transport", "httpsrv", "handlerimpl.go")
+	_, err := os.Stat(handlerimplfile)
+	assert.True(t, os.IsNotExist(err))
+	data, err := fileutils.ReadFile(handlerimplfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, strings.Count(string(data), "func (receiver *UsersvcHandlerImpl) GetUser("))
+
+	var buf bytes.Buffer
+	if err = fileutils.PrintDiff(&buf); err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, buf.String(), "--- /dev/null\n+++ "+handlerimplfile+"\n")
+}
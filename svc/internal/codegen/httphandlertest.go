@@ -0,0 +1,419 @@
+package codegen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/iancoleman/strcase"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	"github.com/unionj-cloud/go-doudou/copier"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+	"github.com/unionj-cloud/go-doudou/templateutils"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const (
+	handlerTestTmplName        = "svc/handler_test.go.tmpl"
+	handlerTestMethodsTmplName = "svc/handler_test_methods.go.tmpl"
+)
+
+var appendHttpHandlerTestTmpl = `
+{{- range $m := .Methods }}
+
+func (receiver *{{$.StubName}}) {{$m.Meta.Name}}({{- range $i, $p := $m.Meta.Params}}{{- if $i}}, {{end}}{{- $p.Name}} {{$p.Type}}{{- end }}) ({{- range $i, $r := $m.Meta.Results}}{{- if $i}}, {{end}}{{- $r.Name}} {{$r.Type}}{{- end }}) {
+	{{- range $r := $m.Meta.Results }}
+	{{- if eq $r.Type "*os.File" }}
+	{{$r.Name}}, _ = os.Open(os.DevNull)
+	{{- end }}
+	{{- end }}
+	return
+}
+
+func Test{{$.SvcName}}Handler_{{$m.Meta.Name}}(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  url.Values
+		body   string
+		files  []string
+		status int
+	}{
+		{{- range $c := $m.Cases }}
+		{
+			name: {{ printf "%q" $c.Name }},
+			{{- if $c.Query }}
+			query: url.Values{
+				{{- range $q := $c.Query }}
+				{{ printf "%q" $q.Name }}: { {{- range $i, $v := $q.Values }}{{ if $i }}, {{ end }}{{ printf "%q" $v }}{{ end -}} },
+				{{- end }}
+			},
+			{{- end }}
+			{{- if $c.Body }}
+			body: {{ printf "%q" $c.Body }},
+			{{- end }}
+			{{- if $c.Files }}
+			files: []string{ {{- range $i, $f := $c.Files }}{{ if $i }}, {{ end }}{{ printf "%q" $f }}{{ end -}} },
+			{{- end }}
+			status: {{ $c.Status }},
+		},
+		{{- end }}
+	}
+	router := new{{$.SvcName}}TestRouter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, newTestRequest(t, {{ printf "%q" $m.HttpMethod }}, {{ printf "%q" $m.Path }}, tt.query, tt.body, tt.files))
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+{{- end }}
+`
+
+var initHttpHandlerTestTmpl = `package httpsrv
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"github.com/gorilla/mux"
+	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+	{{.ServiceAlias}} "{{.ServicePackage}}"
+	"{{.VoPackage}}"
+)
+
+// {{.StubName}} is a stub implementation of {{.ServiceAlias}}.{{.SvcName}} returning zero values
+type {{.StubName}} struct {
+	{{.ServiceAlias}}.{{.SvcName}}
+}
+
+func new{{.SvcName}}TestRouter() *mux.Router {
+	router := mux.NewRouter()
+	for _, item := range Routes(New{{.SvcName}}Handler(&{{.StubName}}{})) {
+		router.Methods(item.Method).Path(item.Pattern).Name(item.Name).Handler(item.HandlerFunc)
+	}
+	return router
+}
+
+// newTestRequest creates a request with query, json body or multipart form containing files
+func newTestRequest(t *testing.T, method, target string, query url.Values, body string, files []string) *http.Request {
+	t.Helper()
+	var (
+		reader      io.Reader
+		contentType string
+	)
+	switch {
+	case len(files) > 0:
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		for _, field := range files {
+			part, err := writer.CreateFormFile(field, field+".txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			part.Write([]byte(field))
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		reader = &buf
+		contentType = writer.FormDataContentType()
+	case body != "":
+		reader = strings.NewReader(body)
+		contentType = "application/json"
+	}
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req
+}
+{{- template "svc/handler_test_methods.go.tmpl" . }}`
+
+type handlerTestQuery struct {
+	Name   string
+	Values []string
+}
+
+type handlerTestCase struct {
+	Name   string
+	Query  []handlerTestQuery
+	Body   string
+	Files  []string
+	Status int
+}
+
+type handlerTestMethod struct {
+	Meta       astutils.MethodMeta
+	HttpMethod string
+	Path       string
+	Cases      []handlerTestCase
+}
+
+func isFileType(t string) bool {
+	return strings.Contains(t, "*multipart.FileHeader") || strings.Contains(t, "*v3.FileModel")
+}
+
+// sampleQueryValues returns valid values of builtin param, and an invalid value if the param is parsed by cast
+func sampleQueryValues(t string) ([]string, string) {
+	elem := strings.TrimPrefix(t, "[]")
+	var valid, invalid string
+	switch elem {
+	case "bool":
+		valid, invalid = "true", "invalid"
+	case "float32", "float64":
+		valid, invalid = "1.5", "invalid"
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		valid, invalid = "1", "invalid"
+	default:
+		valid = "sample"
+	}
+	if !isSupport(t) {
+		invalid = ""
+	}
+	if strings.HasPrefix(t, "[") {
+		return []string{valid, valid}, invalid
+	}
+	return []string{valid}, invalid
+}
+
+// handlerTestOf builds sample requests for method, a valid one and invalid ones for each param parsed by cast,
+// json body and multipart form
+func handlerTestOf(svcName string, method astutils.MethodMeta, routePatternStrategy int) handlerTestMethod {
+	ret := handlerTestMethod{
+		Meta:       method,
+		HttpMethod: httpMethod(method.Name),
+	}
+	if routePatternStrategy == 1 {
+		ret.Path = "/" + strings.ToLower(svcName) + "/" + noSplitPattern(method.Name)
+	} else {
+		ret.Path = "/" + pattern(method.Name)
+	}
+	valid := handlerTestCase{
+		Name:   "valid",
+		Status: http.StatusOK,
+	}
+	invalids := make(map[string]string)
+	var invalidNames []string
+	for _, p := range method.Params {
+		switch {
+		case p.Type == "context.Context":
+		case isFileType(p.Type):
+			valid.Files = append(valid.Files, p.Name)
+		case !v3.IsBuiltin(p):
+			valid.Body = "{}"
+			if strings.HasPrefix(strings.TrimPrefix(p.Type, "*"), "[") {
+				valid.Body = "[]"
+			}
+		default:
+			values, invalid := sampleQueryValues(p.Type)
+			valid.Query = append(valid.Query, handlerTestQuery{Name: p.Name, Values: values})
+			if invalid != "" {
+				invalids[p.Name] = invalid
+				invalidNames = append(invalidNames, p.Name)
+			}
+		}
+	}
+	ret.Cases = append(ret.Cases, valid)
+	for _, name := range invalidNames {
+		c := handlerTestCase{
+			Name:   fmt.Sprintf("invalid %s", name),
+			Body:   valid.Body,
+			Files:  valid.Files,
+			Status: http.StatusBadRequest,
+		}
+		for _, q := range valid.Query {
+			if q.Name == name {
+				q = handlerTestQuery{Name: q.Name, Values: []string{invalids[name]}}
+			}
+			c.Query = append(c.Query, q)
+		}
+		ret.Cases = append(ret.Cases, c)
+	}
+	if valid.Body != "" {
+		ret.Cases = append(ret.Cases, handlerTestCase{
+			Name:   "invalid json body",
+			Query:  valid.Query,
+			Body:   "{",
+			Status: http.StatusBadRequest,
+		})
+	}
+	if len(valid.Files) > 0 {
+		ret.Cases = append(ret.Cases, handlerTestCase{
+			Name:   "not multipart form",
+			Query:  valid.Query,
+			Status: http.StatusBadRequest,
+		})
+	}
+	return ret
+}
+
+func init() {
+	templateutils.Register(handlerTestTmplName, initHttpHandlerTestTmpl, `VoPackage string: import path of vo package
+ServicePackage string: import path of service package
+ServiceAlias string: package name of service package
+SvcName string: name of service interface
+StubName string: name of stub type implementing service interface
+Methods []handlerTestMethod: methods to test, with fields Meta of astutils.MethodMeta, HttpMethod, Path and Cases. Cases is []handlerTestCase with fields Name, Query of []handlerTestQuery with fields Name and Values, Body, Files and Status
+Calls svc/handler_test_methods.go.tmpl to generate stub methods and tests`)
+	templateutils.Register(handlerTestMethodsTmplName, appendHttpHandlerTestTmpl, `VoPackage string: import path of vo package
+ServicePackage string: import path of service package
+ServiceAlias string: package name of service package
+SvcName string: name of service interface
+StubName string: name of stub type implementing service interface
+Methods []handlerTestMethod: methods to test, only untested or stale ones when appending to existing handler_test.go, with fields Meta of astutils.MethodMeta, HttpMethod, Path and Cases. Cases is []handlerTestCase with fields Name, Query of []handlerTestQuery with fields Name and Values, Body, Files and Status`)
+}
+
+// untestedMethods removes methods already tested in testfile from meta, except those whose stub methods have stale
+// signatures, and returns the stale ones
+func untestedMethods(meta *astutils.InterfaceMeta, stubName, testfile string, src []byte) []astutils.MethodMeta {
+	root, err := parser.ParseFile(token.NewFileSet(), testfile, src, parser.ParseComments)
+	if err != nil {
+		panic(err)
+	}
+	sc := astutils.NewStructCollector(astutils.ExprString)
+	ast.Walk(sc, root)
+	stubs := make(map[string]astutils.MethodMeta)
+	for _, stub := range sc.Methods[stubName] {
+		stubs[stub.Name] = stub
+	}
+	var (
+		untested []astutils.MethodMeta
+		changed  []astutils.MethodMeta
+	)
+	for _, method := range meta.Methods {
+		if root.Scope.Lookup("Test"+meta.Name+"Handler_"+method.Name) == nil {
+			untested = append(untested, method)
+			continue
+		}
+		if stub, exists := stubs[method.Name]; exists && !sameSignature(stub, method) {
+			untested = append(untested, method)
+			changed = append(changed, method)
+		}
+	}
+	meta.Methods = untested
+	return changed
+}
+
+// GenHttpHandlerTest generates table-driven tests for http handlers into transport/httpsrv/handler_test.go.
+// Each test drives routes with a stub service through httptest, and checks status codes of a valid request and
+// invalid ones. If the file exists, only tests of methods without tests will be appended, and tests of methods
+// whose signatures have changed will be regenerated.
+func GenHttpHandlerTest(dir string, ic astutils.InterfaceCollector, routePatternStrategy int) {
+	var (
+		err       error
+		testfile  string
+		f         fileutils.File
+		tpl       *template.Template
+		buf       bytes.Buffer
+		httpDir   string
+		fi        os.FileInfo
+		modfile   string
+		modName   string
+		firstLine string
+		modf      fileutils.File
+		meta      astutils.InterfaceMeta
+		tmpl      string
+		original  []byte
+		stubName  string
+	)
+	httpDir = filepath.Join(dir, "transport/httpsrv")
+	if err = fileutils.MkdirAll(httpDir, os.ModePerm); err != nil {
+		panic(err)
+	}
+
+	err = copier.DeepCopy(ic.Interfaces[0], &meta)
+	if err != nil {
+		panic(err)
+	}
+	stubName = strcase.ToLowerCamel(meta.Name) + "Stub"
+
+	testfile = filepath.Join(httpDir, "handler_test.go")
+	fi, err = fileutils.Stat(testfile)
+	if err != nil && !os.IsNotExist(err) {
+		panic(err)
+	}
+	tmpl = handlerTestTmplName
+	if fi != nil {
+		logrus.Warningln("New content will be append to handler_test.go file")
+		if original, err = fileutils.ReadFile(testfile); err != nil {
+			panic(err)
+		}
+		changed := untestedMethods(&meta, stubName, testfile, original)
+		reportChanged(testfile, changed)
+		stale := make(map[string]bool)
+		for _, method := range changed {
+			stale[stubName+"."+method.Name] = true
+			stale["Test"+meta.Name+"Handler_"+method.Name] = true
+		}
+		original = removeFuncs(original, stale)
+		tmpl = handlerTestMethodsTmplName
+	}
+	if f, err = fileutils.Create(testfile); err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	modfile = filepath.Join(dir, "go.mod")
+	if modf, err = fileutils.Open(modfile); err != nil {
+		panic(err)
+	}
+	defer modf.Close()
+	reader := bufio.NewReader(modf)
+	if firstLine, err = reader.ReadString('\n'); err != nil {
+		panic(err)
+	}
+	modName = strings.TrimSpace(strings.TrimPrefix(firstLine, "module"))
+
+	var methods []handlerTestMethod
+	for _, method := range meta.Methods {
+		methods = append(methods, handlerTestOf(meta.Name, method, routePatternStrategy))
+	}
+	if tpl, err = template.New(tmpl).Parse(templateutils.Lookup(tmpl)); err != nil {
+		panic(err)
+	}
+	if tpl.Lookup(handlerTestMethodsTmplName) == nil {
+		if _, err = tpl.New(handlerTestMethodsTmplName).Parse(templateutils.Lookup(handlerTestMethodsTmplName)); err != nil {
+			panic(err)
+		}
+	}
+	if err = tpl.Execute(&buf, struct {
+		VoPackage      string
+		ServicePackage string
+		ServiceAlias   string
+		SvcName        string
+		StubName       string
+		Methods        []handlerTestMethod
+	}{
+		VoPackage:      modName + "/vo",
+		ServicePackage: modName,
+		ServiceAlias:   ic.Package.Name,
+		SvcName:        meta.Name,
+		StubName:       stubName,
+		Methods:        methods,
+	}); err != nil {
+		panic(err)
+	}
+
+	original = append(original, buf.Bytes()...)
+	astutils.FixImport(original, testfile)
+}
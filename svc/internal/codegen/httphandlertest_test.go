@@ -0,0 +1,55 @@
+package codegen
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenHttpHandlerTest(t *testing.T) {
+	dir := testDir + "handlertest"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	svcfile := filepath.Join(testDir, "svc.go")
+	ic := astutils.BuildInterfaceCollector(svcfile, astutils.ExprString)
+	GenHttpHandlerTest(dir, ic, 0)
+	testfile := filepath.Join(dir, "transport", "httpsrv", "handler_test.go")
+	data, err := ioutil.ReadFile(testfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := string(data)
+	assert.Contains(t, source, "for _, item := range Routes(NewUsersvcHandler(&usersvcStub{})) {")
+	assert.Contains(t, source, "func TestUsersvcHandler_SignUp(t *testing.T) {")
+	assert.Contains(t, source, `router.ServeHTTP(rec, newTestRequest(t, "GET", "/user", tt.query, tt.body, tt.files))`)
+	assert.Contains(t, source, `"password": {"invalid"},`)
+	assert.Contains(t, source, `name:   "invalid json body",`)
+	assert.Contains(t, source, `"not multipart form"`)
+
+	GenHttpHandlerTest(dir, ic, 0)
+	data, err = ioutil.ReadFile(testfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, strings.Count(string(data), "func TestUsersvcHandler_SignUp(t *testing.T) {"))
+}
+
+func Test_handlerTestOf(t *testing.T) {
+	method := handlerTestOf("Usersvc", astutils.MethodMeta{
+		Name: "GetUser",
+		Params: []astutils.FieldMeta{
+			{Name: "ctx", Type: "context.Context"},
+			{Name: "userId", Type: "int"},
+		},
+	}, 1)
+	assert.Equal(t, "GET", method.HttpMethod)
+	assert.Equal(t, "/usersvc/user", method.Path)
+	assert.Len(t, method.Cases, 2)
+	assert.Equal(t, []string{"1"}, method.Cases[0].Query[0].Values)
+	assert.Equal(t, []string{"invalid"}, method.Cases[1].Query[0].Values)
+	assert.Equal(t, 400, method.Cases[1].Status)
+}
@@ -2,6 +2,8 @@ package codegen
 
 import (
 	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"os"
 	"path/filepath"
 	"text/template"
@@ -9,27 +11,31 @@ import (
 
 var httpMwTmpl = `package httpsrv`
 
+func init() {
+	templateutils.Register("svc/middleware.go.tmpl", httpMwTmpl, `No data.`)
+}
+
 // GenHttpMiddleware generates http middleware file
 func GenHttpMiddleware(dir string) {
 	var (
 		err     error
 		mwfile  string
-		f       *os.File
+		f       fileutils.File
 		tpl     *template.Template
 		httpDir string
 	)
 	httpDir = filepath.Join(dir, "transport/httpsrv")
-	if err = os.MkdirAll(httpDir, os.ModePerm); err != nil {
+	if err = fileutils.MkdirAll(httpDir, os.ModePerm); err != nil {
 		panic(err)
 	}
 
 	mwfile = filepath.Join(httpDir, "middleware.go")
-	if _, err = os.Stat(mwfile); os.IsNotExist(err) {
-		if f, err = os.Create(mwfile); err != nil {
+	if _, err = fileutils.Stat(mwfile); os.IsNotExist(err) {
+		if f, err = fileutils.Create(mwfile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
-		tpl, _ = template.New("middleware.go.tmpl").Parse(httpMwTmpl)
+		tpl, _ = template.New("middleware.go.tmpl").Parse(templateutils.Lookup("svc/middleware.go.tmpl"))
 		_ = tpl.Execute(f, nil)
 	} else {
 		logrus.Warnf("file %s already exists", mwfile)
@@ -9,8 +9,10 @@ import (
 	"github.com/go-git/go-git/v5/storage/filesystem"
 	"github.com/iancoleman/strcase"
 	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/fileutils"
 	"github.com/unionj-cloud/go-doudou/sliceutils"
 	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -194,6 +196,17 @@ CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -v -ldflags="-X 'github.com/union
 ENTRYPOINT ["/repo/api"]
 `
 
+func init() {
+	templateutils.Register("svc/svc.go.tmpl", svcTmpl, `VoPackage string: import path of vo package
+SvcName string: name of service interface in camel case of project directory name`)
+	templateutils.Register("svc/vo.go.tmpl", voTmpl, `No data.`)
+	templateutils.Register("svc/go.mod.tmpl", modTmpl, `ModName string: module name
+GoVersion string: go version such as 1.16`)
+	templateutils.Register("svc/.gitignore.tmpl", gitignoreTmpl, `No data.`)
+	templateutils.Register("svc/.env.tmpl", envTmpl, `SvcName string: module name`)
+	templateutils.Register("svc/Dockerfile.tmpl", dockerfileTmpl, `No data.`)
+}
+
 // InitProj inits a service project
 // dir is root path
 // modName is module name
@@ -207,14 +220,14 @@ func InitProj(dir string, modName string) {
 		vofile    string
 		goVersion string
 		firstLine string
-		f         *os.File
+		f         fileutils.File
 		tpl       *template.Template
 		envfile   string
 	)
 	if stringutils.IsEmpty(dir) {
 		dir, _ = os.Getwd()
 	}
-	_ = os.MkdirAll(dir, os.ModePerm)
+	_ = fileutils.MkdirAll(dir, os.ModePerm)
 
 	gitInit(dir)
 	gitIgnore(dir)
@@ -225,13 +238,13 @@ func InitProj(dir string, modName string) {
 		modName = filepath.Base(dir)
 	}
 	modfile = filepath.Join(dir, "go.mod")
-	if _, err = os.Stat(modfile); os.IsNotExist(err) {
-		if f, err = os.Create(modfile); err != nil {
+	if _, err = fileutils.Stat(modfile); os.IsNotExist(err) {
+		if f, err = fileutils.Create(modfile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
 
-		tpl, _ = template.New("go.mod.tmpl").Parse(modTmpl)
+		tpl, _ = template.New("go.mod.tmpl").Parse(templateutils.Lookup("svc/go.mod.tmpl"))
 		_ = tpl.Execute(f, struct {
 			ModName   string
 			GoVersion string
@@ -244,13 +257,13 @@ func InitProj(dir string, modName string) {
 	}
 
 	envfile = filepath.Join(dir, ".env")
-	if _, err = os.Stat(envfile); os.IsNotExist(err) {
-		if f, err = os.Create(envfile); err != nil {
+	if _, err = fileutils.Stat(envfile); os.IsNotExist(err) {
+		if f, err = fileutils.Create(envfile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
 
-		tpl, _ = template.New(".env.tmpl").Parse(envTmpl)
+		tpl, _ = template.New(".env.tmpl").Parse(templateutils.Lookup("svc/.env.tmpl"))
 		_ = tpl.Execute(f, struct {
 			SvcName string
 		}{
@@ -261,17 +274,17 @@ func InitProj(dir string, modName string) {
 	}
 
 	vodir = filepath.Join(dir, "vo")
-	if err = os.MkdirAll(vodir, os.ModePerm); err != nil {
+	if err = fileutils.MkdirAll(vodir, os.ModePerm); err != nil {
 		panic(err)
 	}
 	vofile = filepath.Join(vodir, "vo.go")
-	if _, err = os.Stat(vofile); os.IsNotExist(err) {
-		if f, err = os.Create(vofile); err != nil {
+	if _, err = fileutils.Stat(vofile); os.IsNotExist(err) {
+		if f, err = fileutils.Create(vofile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
 
-		tpl, _ = template.New("vo.go.tmpl").Parse(voTmpl)
+		tpl, _ = template.New("vo.go.tmpl").Parse(templateutils.Lookup("svc/vo.go.tmpl"))
 		_ = tpl.Execute(f, nil)
 	} else {
 		logrus.Warnf("file %s already exists", vofile)
@@ -279,8 +292,8 @@ func InitProj(dir string, modName string) {
 
 	svcName = strcase.ToCamel(filepath.Base(dir))
 	svcfile = filepath.Join(dir, "svc.go")
-	if _, err = os.Stat(svcfile); os.IsNotExist(err) {
-		if f, err = os.Open(modfile); err != nil {
+	if _, err = fileutils.Stat(svcfile); os.IsNotExist(err) {
+		if f, err = fileutils.Open(modfile); err != nil {
 			panic(err)
 		}
 		reader := bufio.NewReader(f)
@@ -289,12 +302,12 @@ func InitProj(dir string, modName string) {
 		}
 		modName = strings.TrimSpace(strings.TrimPrefix(firstLine, "module"))
 
-		if f, err = os.Create(svcfile); err != nil {
+		if f, err = fileutils.Create(svcfile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
 
-		tpl, _ = template.New("svc.go.tmpl").Parse(svcTmpl)
+		tpl, _ = template.New("svc.go.tmpl").Parse(templateutils.Lookup("svc/svc.go.tmpl"))
 		_ = tpl.Execute(f, struct {
 			VoPackage string
 			SvcName   string
@@ -307,13 +320,13 @@ func InitProj(dir string, modName string) {
 	}
 
 	dockerfile := filepath.Join(dir, "Dockerfile")
-	if _, err = os.Stat(dockerfile); os.IsNotExist(err) {
-		if f, err = os.Create(dockerfile); err != nil {
+	if _, err = fileutils.Stat(dockerfile); os.IsNotExist(err) {
+		if f, err = fileutils.Create(dockerfile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
 
-		tpl, _ = template.New("dockerfile.tmpl").Parse(dockerfileTmpl)
+		tpl, _ = template.New("dockerfile.tmpl").Parse(templateutils.Lookup("svc/Dockerfile.tmpl"))
 		_ = tpl.Execute(f, nil)
 	} else {
 		logrus.Warnf("file %s already exists", dockerfile)
@@ -332,14 +345,14 @@ func InitSvc(dir string) {
 		vofile    string
 		goVersion string
 		firstLine string
-		f         *os.File
+		f         fileutils.File
 		tpl       *template.Template
 		envfile   string
 	)
 	if stringutils.IsEmpty(dir) {
 		dir, _ = os.Getwd()
 	}
-	_ = os.MkdirAll(dir, os.ModePerm)
+	_ = fileutils.MkdirAll(dir, os.ModePerm)
 
 	gitInit(dir)
 	gitIgnore(dir)
@@ -348,13 +361,13 @@ func InitSvc(dir string) {
 	goVersion = fmt.Sprintf("%s.%s%.s", vnums...)
 	modName = filepath.Base(dir)
 	modfile = filepath.Join(dir, "go.mod")
-	if _, err = os.Stat(modfile); os.IsNotExist(err) {
-		if f, err = os.Create(modfile); err != nil {
+	if _, err = fileutils.Stat(modfile); os.IsNotExist(err) {
+		if f, err = fileutils.Create(modfile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
 
-		tpl, _ = template.New("go.mod.tmpl").Parse(modTmpl)
+		tpl, _ = template.New("go.mod.tmpl").Parse(templateutils.Lookup("svc/go.mod.tmpl"))
 		_ = tpl.Execute(f, struct {
 			ModName   string
 			GoVersion string
@@ -367,13 +380,13 @@ func InitSvc(dir string) {
 	}
 
 	envfile = filepath.Join(dir, ".env")
-	if _, err = os.Stat(envfile); os.IsNotExist(err) {
-		if f, err = os.Create(envfile); err != nil {
+	if _, err = fileutils.Stat(envfile); os.IsNotExist(err) {
+		if f, err = fileutils.Create(envfile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
 
-		tpl, _ = template.New(".env.tmpl").Parse(envTmpl)
+		tpl, _ = template.New(".env.tmpl").Parse(templateutils.Lookup("svc/.env.tmpl"))
 		_ = tpl.Execute(f, struct {
 			SvcName string
 		}{
@@ -384,17 +397,17 @@ func InitSvc(dir string) {
 	}
 
 	vodir = filepath.Join(dir, "vo")
-	if err = os.MkdirAll(vodir, os.ModePerm); err != nil {
+	if err = fileutils.MkdirAll(vodir, os.ModePerm); err != nil {
 		panic(err)
 	}
 	vofile = filepath.Join(vodir, "vo.go")
-	if _, err = os.Stat(vofile); os.IsNotExist(err) {
-		if f, err = os.Create(vofile); err != nil {
+	if _, err = fileutils.Stat(vofile); os.IsNotExist(err) {
+		if f, err = fileutils.Create(vofile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
 
-		tpl, _ = template.New("vo.go.tmpl").Parse(voTmpl)
+		tpl, _ = template.New("vo.go.tmpl").Parse(templateutils.Lookup("svc/vo.go.tmpl"))
 		_ = tpl.Execute(f, nil)
 	} else {
 		logrus.Warnf("file %s already exists", vofile)
@@ -402,8 +415,8 @@ func InitSvc(dir string) {
 
 	svcName = strcase.ToCamel(filepath.Base(dir))
 	svcfile = filepath.Join(dir, "svc.go")
-	if _, err = os.Stat(svcfile); os.IsNotExist(err) {
-		if f, err = os.Open(modfile); err != nil {
+	if _, err = fileutils.Stat(svcfile); os.IsNotExist(err) {
+		if f, err = fileutils.Open(modfile); err != nil {
 			panic(err)
 		}
 		reader := bufio.NewReader(f)
@@ -413,12 +426,12 @@ func InitSvc(dir string) {
 		modName = strings.TrimSpace(strings.TrimPrefix(firstLine, "module"))
 		fmt.Println(modName)
 
-		if f, err = os.Create(svcfile); err != nil {
+		if f, err = fileutils.Create(svcfile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
 
-		tpl, _ = template.New("svc.go.tmpl").Parse(svcTmpl)
+		tpl, _ = template.New("svc.go.tmpl").Parse(templateutils.Lookup("svc/svc.go.tmpl"))
 		_ = tpl.Execute(f, struct {
 			VoPackage string
 			SvcName   string
@@ -431,13 +444,13 @@ func InitSvc(dir string) {
 	}
 
 	dockerfile := filepath.Join(dir, "Dockerfile")
-	if _, err = os.Stat(dockerfile); os.IsNotExist(err) {
-		if f, err = os.Create(dockerfile); err != nil {
+	if _, err = fileutils.Stat(dockerfile); os.IsNotExist(err) {
+		if f, err = fileutils.Create(dockerfile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
 
-		tpl, _ = template.New("dockerfile.tmpl").Parse(dockerfileTmpl)
+		tpl, _ = template.New("dockerfile.tmpl").Parse(templateutils.Lookup("svc/Dockerfile.tmpl"))
 		_ = tpl.Execute(f, nil)
 	} else {
 		logrus.Warnf("file %s already exists", dockerfile)
@@ -449,17 +462,17 @@ func gitIgnore(dir string) {
 	var (
 		gitignorefile string
 		err           error
-		f             *os.File
+		f             fileutils.File
 		tpl           *template.Template
 	)
 	gitignorefile = filepath.Join(dir, ".gitignore")
-	if _, err = os.Stat(gitignorefile); os.IsNotExist(err) {
-		if f, err = os.Create(gitignorefile); err != nil {
+	if _, err = fileutils.Stat(gitignorefile); os.IsNotExist(err) {
+		if f, err = fileutils.Create(gitignorefile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
 
-		tpl, _ = template.New(".gitignore.tmpl").Parse(gitignoreTmpl)
+		tpl, _ = template.New(".gitignore.tmpl").Parse(templateutils.Lookup("svc/.gitignore.tmpl"))
 		_ = tpl.Execute(f, nil)
 	} else {
 		logrus.Warnf("file %s already exists", ".gitignore")
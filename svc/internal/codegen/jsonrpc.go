@@ -0,0 +1,339 @@
+package codegen
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/iancoleman/strcase"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+type jsonrpcMethod struct {
+	Meta astutils.MethodMeta
+	// Params are parameters except context.Context, with variadic types converted to slices
+	Params []astutils.FieldMeta
+	// Results are results except error
+	Results []astutils.FieldMeta
+	// Args are expressions passed to service method by server
+	Args []string
+	// Vars are variable names receiving results of service method by server
+	Vars   []string
+	ErrVar string
+	// Err is name of error result used by client
+	Err string
+	// Ctx is context passed to server by client
+	Ctx string
+}
+
+// jsonrpcSupported returns false for methods having file parameters or results which can't be encoded as json
+func jsonrpcSupported(method astutils.MethodMeta) bool {
+	fields := append([]astutils.FieldMeta{}, method.Params...)
+	for _, field := range append(fields, method.Results...) {
+		if strings.Contains(field.Type, "multipart.FileHeader") || strings.Contains(field.Type, "v3.FileModel") || strings.Contains(field.Type, "os.File") {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonrpcMethodsOf returns methods of service interface callable through json-rpc, other methods are skipped with warning
+func jsonrpcMethodsOf(meta astutils.InterfaceMeta) []jsonrpcMethod {
+	var methods []jsonrpcMethod
+	for _, method := range meta.Methods {
+		if !jsonrpcSupported(method) {
+			logrus.Warningf("method %s is skipped in json-rpc as it has file parameters or results", method.Name)
+			continue
+		}
+		jm := jsonrpcMethod{
+			Meta: method,
+			Ctx:  "context.Background()",
+		}
+		for _, param := range method.Params {
+			if param.Type == "context.Context" {
+				jm.Args = append(jm.Args, "_ctx")
+				jm.Ctx = clientCtxOf(meta.Name, method, param.Name)
+				continue
+			}
+			if strings.HasPrefix(param.Type, "...") {
+				param.Type = "[]" + strings.TrimPrefix(param.Type, "...")
+				jm.Args = append(jm.Args, param.Name+"...")
+			} else {
+				jm.Args = append(jm.Args, param.Name)
+			}
+			jm.Params = append(jm.Params, param)
+		}
+		for _, result := range method.Results {
+			v := "_" + result.Name
+			jm.Vars = append(jm.Vars, v)
+			if result.Type == "error" {
+				jm.ErrVar = v
+				jm.Err = result.Name
+				continue
+			}
+			jm.Results = append(jm.Results, result)
+		}
+		methods = append(methods, jm)
+	}
+	return methods
+}
+
+var jsonrpcTmpl = `package jsonrpc
+
+import (
+	"context"
+
+	ddmodel "github.com/unionj-cloud/go-doudou/svc/http/model"
+	ddjsonrpc "github.com/unionj-cloud/go-doudou/svc/jsonrpc"
+	{{.ServiceAlias}} "{{.ServicePackage}}"
+	"{{.VoPackage}}"
+)
+
+// New{{.SvcName}}Server creates json-rpc server dispatching method {{.SvcName}}.<Method> to {{.SvcName | toLowerCamel}}.
+// Params can be passed by-position in the same order as service method, or by-name with parameter names of service method.
+// Result is the only result of service method, or an object of results if there are many.
+func New{{.SvcName}}Server({{.SvcName | toLowerCamel}} {{.ServiceAlias}}.{{.SvcName}}) *ddjsonrpc.Server {
+	srv := ddjsonrpc.NewServer()
+	{{- range $m := .Methods }}
+	srv.Register("{{$.SvcName}}.{{$m.Meta.Name}}", func(_ctx context.Context, _params ddjsonrpc.Params) (interface{}, error) {
+		{{- if $m.Params }}
+		var (
+			{{- range $p := $m.Params }}
+			{{$p.Name}} {{$p.Type}}
+			{{- end }}
+		)
+		if _err := _params.Bind([]string{ {{- range $i, $p := $m.Params }}{{if $i}}, {{end}}"{{$p.Name}}"{{end -}} }, {{ range $i, $p := $m.Params }}{{if $i}}, {{end}}&{{$p.Name}}{{end}}); _err != nil {
+			return nil, _err
+		}
+		{{- end }}
+		{{ if $m.Vars }}{{ join $m.Vars ", " }} := {{ end }}{{$.SvcName | toLowerCamel}}.{{$m.Meta.Name}}({{ join $m.Args ", " }})
+		{{- if $m.ErrVar }}
+		if {{$m.ErrVar}} != nil {
+			return nil, {{$m.ErrVar}}
+		}
+		{{- end }}
+		{{- if eq (len $m.Results) 0 }}
+		return nil, nil
+		{{- else if eq (len $m.Results) 1 }}
+		return _{{(index $m.Results 0).Name}}, nil
+		{{- else }}
+		return struct {
+			{{- range $r := $m.Results }}
+			{{$r.Name | toCamel}} {{$r.Type}} ` + "`" + `json:"{{$r.Name | toLowerCamel}}"` + "`" + `
+			{{- end }}
+		}{
+			{{- range $r := $m.Results }}
+			{{$r.Name | toCamel}}: _{{$r.Name}},
+			{{- end }}
+		}, nil
+		{{- end }}
+	})
+	{{- end }}
+	return srv
+}
+
+// Routes returns route of the single json-rpc endpoint, add it to ddhttp.DefaultHttpSrv by srv.AddRoute
+func Routes({{.SvcName | toLowerCamel}} {{.ServiceAlias}}.{{.SvcName}}) []ddmodel.Route {
+	return []ddmodel.Route{
+		{
+			"Jsonrpc",
+			"POST",
+			ddjsonrpc.Path,
+			New{{.SvcName}}Server({{.SvcName | toLowerCamel}}).ServeHTTP,
+		},
+	}
+}
+`
+
+var jsonrpcClientTmpl = `package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	"github.com/opentracing/opentracing-go"
+	ddhttp "github.com/unionj-cloud/go-doudou/svc/http"
+	ddjsonrpc "github.com/unionj-cloud/go-doudou/svc/jsonrpc"
+	"github.com/unionj-cloud/go-doudou/svc/registry"
+	"{{.VoPackage}}"
+)
+
+type {{.SvcName}}JsonrpcClient struct {
+	provider registry.IServiceProvider
+	client   *resty.Client
+}
+
+func (receiver *{{.SvcName}}JsonrpcClient) SetProvider(provider registry.IServiceProvider) {
+	receiver.provider = provider
+}
+
+func (receiver *{{.SvcName}}JsonrpcClient) SetClient(client *resty.Client) {
+	receiver.client = client
+}
+{{- range $m := .Methods }}
+
+func (receiver *{{$.SvcName}}JsonrpcClient) {{$m.Meta.Name}}({{- range $i, $p := $m.Meta.Params }}{{if $i}}, {{end}}{{$p.Name}} {{$p.Type}}{{- end }}) ({{- range $i, $r := $m.Meta.Results }}{{if $i}}, {{end}}{{$r.Name}} {{$r.Type}}{{- end }}) {
+	{{- if gt (len $m.Results) 1 }}
+	var _result struct {
+		{{- range $r := $m.Results }}
+		{{$r.Name | toCamel}} {{$r.Type}} ` + "`" + `json:"{{$r.Name | toLowerCamel}}"` + "`" + `
+		{{- end }}
+	}
+	{{- end }}
+	{{ if $m.Err }}if _err := {{ else }}_ = {{ end }}ddjsonrpc.Call({{$m.Ctx}}, receiver.client, ddjsonrpc.Path, "{{$.SvcName}}.{{$m.Meta.Name}}", {{ if $m.Params }}map[string]interface{}{
+		{{- range $p := $m.Params }}
+		"{{$p.Name}}": {{$p.Name}},
+		{{- end }}
+	}{{ else }}nil{{ end }}, {{ if eq (len $m.Results) 0 }}nil{{ else if eq (len $m.Results) 1 }}&{{(index $m.Results 0).Name}}{{ else }}&_result{{ end }}){{ if $m.Err }}; _err != nil {
+		{{$m.Err}} = _err
+		return
+	}{{ end }}
+	{{- if gt (len $m.Results) 1 }}
+	{{- range $r := $m.Results }}
+	{{$r.Name}} = _result.{{$r.Name | toCamel}}
+	{{- end }}
+	{{- end }}
+	return
+}
+{{- end }}
+
+func New{{.SvcName}}JsonrpcClient(opts ...ddhttp.DdClientOption) *{{.SvcName}}JsonrpcClient {
+	{{- if .Env }}
+	defaultProvider := ddhttp.NewServiceProvider("{{.Env}}")
+	{{- else }}
+	defaultProvider := ddhttp.NewServiceProvider("{{.SvcName | toUpper}}")
+	{{- end }}
+	defaultClient := ddhttp.NewClient()
+
+	svcClient := &{{.SvcName}}JsonrpcClient{
+		provider: defaultProvider,
+		client:   defaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(svcClient)
+	}
+
+	svcClient.client.OnBeforeRequest(func(_ *resty.Client, request *resty.Request) error {
+		request.URL = ddhttp.SelectServer(request.Context(), svcClient.provider) + request.URL
+		return nil
+	})
+
+	svcClient.client.SetPreRequestHook(func(_ *resty.Client, request *http.Request) error {
+		traceReq, _ := nethttp.TraceRequest(opentracing.GlobalTracer(), request,
+			nethttp.OperationName(fmt.Sprintf("HTTP %s: %s", request.Method, request.RequestURI)))
+		*request = *traceReq
+		return nil
+	})
+
+	svcClient.client.OnAfterResponse(func(_ *resty.Client, response *resty.Response) error {
+		nethttp.TracerFromRequest(response.Request.RawRequest).Finish()
+		return nil
+	})
+
+	return svcClient
+}
+`
+
+func init() {
+	templateutils.Register("svc/jsonrpc.go.tmpl", jsonrpcTmpl, `ServicePackage string: import path of service package, the module name
+ServiceAlias string: package name of service package
+VoPackage string: import path of vo package
+SvcName string: name of service interface
+Methods []jsonrpcMethod: Meta astutils.MethodMeta, Params and Results []astutils.FieldMeta without context and error, Args, Vars []string, ErrVar string
+Functions: toLowerCamel, toCamel, join`)
+	templateutils.Register("svc/jsonrpcclient.go.tmpl", jsonrpcClientTmpl, `VoPackage string: import path of vo package
+SvcName string: name of service interface
+Env string: name of environment variable of service base url
+Methods []jsonrpcMethod: Meta astutils.MethodMeta, Params and Results []astutils.FieldMeta without context and error, Err string, Ctx string
+Functions: toLowerCamel, toCamel, toUpper`)
+}
+
+// genJsonrpc executes template name with data into file
+func genJsonrpc(file, name string, data interface{}) {
+	var (
+		err error
+		tpl *template.Template
+		buf bytes.Buffer
+	)
+	if _, err = fileutils.Stat(file); err == nil {
+		logrus.Warningln("file " + file + " will be overwrited")
+	}
+	funcMap := make(map[string]interface{})
+	funcMap["toLowerCamel"] = strcase.ToLowerCamel
+	funcMap["toCamel"] = strcase.ToCamel
+	funcMap["toUpper"] = strings.ToUpper
+	funcMap["join"] = strings.Join
+	if tpl, err = template.New(filepath.Base(name)).Funcs(funcMap).Parse(templateutils.Lookup(name)); err != nil {
+		panic(err)
+	}
+	if err = tpl.Execute(&buf, data); err != nil {
+		panic(err)
+	}
+	astutils.FixImport(buf.Bytes(), file)
+}
+
+// GenJsonrpc generates json-rpc 2.0 handler dispatching <Svc>.<Method> to service implementation into transport/jsonrpc/handler.go,
+// and golang json-rpc client into client/jsonrpcclient.go. Both files are overwritten because they are derived from svc.go completely.
+// Methods having file parameters or results are skipped.
+func GenJsonrpc(dir string, ic astutils.InterfaceCollector, env string) {
+	var (
+		err        error
+		modf       fileutils.File
+		firstLine  string
+		jsonrpcDir string
+		clientDir  string
+	)
+	jsonrpcDir = filepath.Join(dir, "transport", "jsonrpc")
+	clientDir = filepath.Join(dir, "client")
+	for _, item := range []string{jsonrpcDir, clientDir} {
+		if err = fileutils.MkdirAll(item, os.ModePerm); err != nil {
+			panic(err)
+		}
+	}
+
+	if modf, err = fileutils.Open(filepath.Join(dir, "go.mod")); err != nil {
+		panic(err)
+	}
+	defer modf.Close()
+	reader := bufio.NewReader(modf)
+	if firstLine, err = reader.ReadString('\n'); err != nil {
+		panic(err)
+	}
+	modName := strings.TrimSpace(strings.TrimPrefix(firstLine, "module"))
+
+	svcName := ic.Interfaces[0].Name
+	methods := jsonrpcMethodsOf(ic.Interfaces[0])
+	genJsonrpc(filepath.Join(jsonrpcDir, "handler.go"), "svc/jsonrpc.go.tmpl", struct {
+		ServicePackage string
+		ServiceAlias   string
+		VoPackage      string
+		SvcName        string
+		Methods        []jsonrpcMethod
+	}{
+		ServicePackage: modName,
+		ServiceAlias:   ic.Package.Name,
+		VoPackage:      modName + "/vo",
+		SvcName:        svcName,
+		Methods:        methods,
+	})
+	genJsonrpc(filepath.Join(clientDir, "jsonrpcclient.go"), "svc/jsonrpcclient.go.tmpl", struct {
+		VoPackage string
+		SvcName   string
+		Env       string
+		Methods   []jsonrpcMethod
+	}{
+		VoPackage: modName + "/vo",
+		SvcName:   svcName,
+		Env:       env,
+		Methods:   methods,
+	})
+}
@@ -0,0 +1,65 @@
+package codegen
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenJsonrpc(t *testing.T) {
+	dir := testDir + "jsonrpc"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	ic := astutils.BuildInterfaceCollector(filepath.Join(dir, "svc.go"), astutils.ExprString)
+	GenJsonrpc(dir, ic, "")
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "transport", "jsonrpc", "handler.go"))
+	assert.NoError(t, err)
+	handler := string(content)
+	assert.Contains(t, handler, `srv.Register("Testdatajsonrpc.PageUsers", func(_ctx context.Context, _params ddjsonrpc.Params) (interface{}, error) {`)
+	assert.Contains(t, handler, `if _err := _params.Bind([]string{"query"}, &query); _err != nil {`)
+	assert.Contains(t, handler, "_code, _data, _err := testdatajsonrpc.PageUsers(_ctx, query)")
+	assert.Contains(t, handler, "ddjsonrpc.Path,")
+
+	content, err = ioutil.ReadFile(filepath.Join(dir, "client", "jsonrpcclient.go"))
+	assert.NoError(t, err)
+	client := string(content)
+	assert.Contains(t, client, "func (receiver *TestdatajsonrpcJsonrpcClient) PageUsers(ctx context.Context, query vo.PageQuery) (code int, data vo.PageRet, err error) {")
+	assert.Contains(t, client, `ddjsonrpc.Call(ctx, receiver.client, ddjsonrpc.Path, "Testdatajsonrpc.PageUsers", map[string]interface{}{`)
+	assert.Contains(t, client, `ddhttp.NewServiceProvider("TESTDATAJSONRPC")`)
+}
+
+func Test_jsonrpcMethodsOf(t *testing.T) {
+	ctx := astutils.FieldMeta{Name: "ctx", Type: "context.Context"}
+	methods := jsonrpcMethodsOf(astutils.InterfaceMeta{
+		Name: "Usersvc",
+		Methods: []astutils.MethodMeta{
+			{
+				Name:    "Upload",
+				Params:  []astutils.FieldMeta{ctx, {Name: "file", Type: "*multipart.FileHeader"}},
+				Results: []astutils.FieldMeta{{Name: "err", Type: "error"}},
+			},
+			{
+				Name:     "Tag",
+				Params:   []astutils.FieldMeta{ctx, {Name: "id", Type: "int"}, {Name: "tags", Type: "...string"}},
+				Results:  []astutils.FieldMeta{{Name: "count", Type: "int"}, {Name: "err", Type: "error"}},
+				Comments: []string{"@timeout(1s)"},
+			},
+			{
+				Name: "Ping",
+			},
+		},
+	})
+	assert.Len(t, methods, 2)
+	assert.Equal(t, []string{"_ctx", "id", "tags..."}, methods[0].Args)
+	assert.Equal(t, "[]string", methods[0].Params[1].Type)
+	assert.Equal(t, []string{"_count", "_err"}, methods[0].Vars)
+	assert.Equal(t, "_err", methods[0].ErrVar)
+	assert.Equal(t, "err", methods[0].Err)
+	assert.Equal(t, `ddhttp.WithTimeout(ctx, "Usersvc.Tag", 1 * time.Second)`, methods[0].Ctx)
+	assert.Equal(t, "context.Background()", methods[1].Ctx)
+	assert.Empty(t, methods[1].Vars)
+}
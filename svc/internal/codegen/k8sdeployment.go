@@ -4,6 +4,8 @@ import (
 	"github.com/Jeffail/gabs/v2"
 	"github.com/goccy/go-yaml"
 	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -54,19 +56,24 @@ spec:
       targetPort: 6060`
 
 // GenK8sDeployment generates deployment kind yaml file for kubernetes deploy.
+func init() {
+	templateutils.Register("svc/deployment.yaml.tmpl", deploymentTmpl, `SvcName string: lower case name of service interface
+Image string: docker image`)
+}
+
 func GenK8sDeployment(dir string, svcname, image string) {
 	var (
-		f   *os.File
+		f   fileutils.File
 		tpl *template.Template
 	)
 	file := filepath.Join(dir, svcname+"_deployment.yaml")
-	if _, err := os.Stat(file); os.IsNotExist(err) {
-		if f, err = os.Create(file); err != nil {
+	if _, err := fileutils.Stat(file); os.IsNotExist(err) {
+		if f, err = fileutils.Create(file); err != nil {
 			panic(err)
 		}
 		defer f.Close()
 
-		if tpl, err = template.New("deployment.tmpl").Parse(deploymentTmpl); err != nil {
+		if tpl, err = template.New("deployment.tmpl").Parse(templateutils.Lookup("svc/deployment.yaml.tmpl")); err != nil {
 			panic(err)
 		}
 		if err = tpl.Execute(f, struct {
@@ -80,7 +87,7 @@ func GenK8sDeployment(dir string, svcname, image string) {
 		}
 	} else {
 		logrus.Warnf("image version will be modified in file %s", file)
-		err = ioutil.WriteFile(file, modifyVersion(file, image), os.ModePerm)
+		err = fileutils.WriteFile(file, modifyVersion(file, image), os.ModePerm)
 		if err != nil {
 			panic(err)
 		}
@@ -89,12 +96,12 @@ func GenK8sDeployment(dir string, svcname, image string) {
 
 func modifyVersion(yfile string, image string) []byte {
 	var (
-		f                             *os.File
+		f                             fileutils.File
 		err                           error
 		raw, jdeployment, ddeployment []byte
 		deployment                    string
 	)
-	if f, err = os.Open(yfile); err != nil {
+	if f, err = fileutils.Open(yfile); err != nil {
 		panic(err)
 	}
 	defer f.Close()
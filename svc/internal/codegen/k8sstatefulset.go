@@ -2,7 +2,8 @@ package codegen
 
 import (
 	"github.com/sirupsen/logrus"
-	"io/ioutil"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"os"
 	"path/filepath"
 	"text/template"
@@ -52,19 +53,24 @@ spec:
   clusterIP: None`
 
 // GenK8sStatefulset generates statefulset kind yaml file for kubernetes deploy
+func init() {
+	templateutils.Register("svc/statefulset.yaml.tmpl", statefulsetTmpl, `SvcName string: lower case name of service interface
+Image string: docker image`)
+}
+
 func GenK8sStatefulset(dir string, svcname, image string) {
 	var (
-		f   *os.File
+		f   fileutils.File
 		tpl *template.Template
 	)
 	file := filepath.Join(dir, svcname+"_statefulset.yaml")
-	if _, err := os.Stat(file); os.IsNotExist(err) {
-		if f, err = os.Create(file); err != nil {
+	if _, err := fileutils.Stat(file); os.IsNotExist(err) {
+		if f, err = fileutils.Create(file); err != nil {
 			panic(err)
 		}
 		defer f.Close()
 
-		if tpl, err = template.New("statefulset.tmpl").Parse(statefulsetTmpl); err != nil {
+		if tpl, err = template.New("statefulset.tmpl").Parse(templateutils.Lookup("svc/statefulset.yaml.tmpl")); err != nil {
 			panic(err)
 		}
 		if err = tpl.Execute(f, struct {
@@ -78,7 +84,7 @@ func GenK8sStatefulset(dir string, svcname, image string) {
 		}
 	} else {
 		logrus.Warnf("image version will be modified in file %s", file)
-		err = ioutil.WriteFile(file, modifyVersion(file, image), os.ModePerm)
+		err = fileutils.WriteFile(file, modifyVersion(file, image), os.ModePerm)
 		if err != nil {
 			panic(err)
 		}
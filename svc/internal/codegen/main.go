@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/astutils"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"os"
 	"path/filepath"
 	"strings"
@@ -72,6 +74,15 @@ func main() {
 }
 `
 
+func init() {
+	templateutils.Register("svc/main.go.tmpl", mainTmpl, `ServicePackage string: import path of service package, the module name
+ConfigPackage string: import path of config package
+DbPackage string: import path of db package
+HttpPackage string: import path of transport/httpsrv package
+SvcName string: name of service interface
+ServiceAlias string: package name of service package`)
+}
+
 // GenMain generates main function
 func GenMain(dir string, ic astutils.InterfaceCollector) {
 	var (
@@ -80,23 +91,23 @@ func GenMain(dir string, ic astutils.InterfaceCollector) {
 		modName   string
 		mainfile  string
 		firstLine string
-		f         *os.File
+		f         fileutils.File
 		tpl       *template.Template
 		cmdDir    string
 		svcName   string
 		alias     string
 	)
 	cmdDir = filepath.Join(dir, "cmd")
-	if err = os.MkdirAll(cmdDir, os.ModePerm); err != nil {
+	if err = fileutils.MkdirAll(cmdDir, os.ModePerm); err != nil {
 		panic(err)
 	}
 
 	svcName = ic.Interfaces[0].Name
 	alias = ic.Package.Name
 	mainfile = filepath.Join(cmdDir, "main.go")
-	if _, err = os.Stat(mainfile); os.IsNotExist(err) {
+	if _, err = fileutils.Stat(mainfile); os.IsNotExist(err) {
 		modfile = filepath.Join(dir, "go.mod")
-		if f, err = os.Open(modfile); err != nil {
+		if f, err = fileutils.Open(modfile); err != nil {
 			panic(err)
 		}
 		reader := bufio.NewReader(f)
@@ -105,12 +116,12 @@ func GenMain(dir string, ic astutils.InterfaceCollector) {
 		}
 		modName = strings.TrimSpace(strings.TrimPrefix(firstLine, "module"))
 
-		if f, err = os.Create(mainfile); err != nil {
+		if f, err = fileutils.Create(mainfile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
 
-		if tpl, err = template.New("main.go.tmpl").Parse(mainTmpl); err != nil {
+		if tpl, err = template.New("main.go.tmpl").Parse(templateutils.Lookup("svc/main.go.tmpl")); err != nil {
 			panic(err)
 		}
 		if err = tpl.Execute(f, struct {
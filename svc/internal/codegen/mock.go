@@ -0,0 +1,281 @@
+package codegen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/iancoleman/strcase"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	"github.com/unionj-cloud/go-doudou/copier"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+var mockTmpl = `package mock
+
+import (
+	"context"
+	"fmt"
+	"mime/multipart"
+	"os"
+	"reflect"
+	"sync"
+	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+	{{.ServiceAlias}} "{{.ServicePackage}}"
+	"{{.VoPackage}}"
+)
+
+// TestingT is the subset of *testing.T used by assertions
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// argEqual compares arguments, files are compared by name
+func argEqual(expected, actual interface{}) bool {
+	switch e := expected.(type) {
+	case *multipart.FileHeader:
+		a, ok := actual.(*multipart.FileHeader)
+		if !ok || e == nil || a == nil {
+			return ok && e == a
+		}
+		return e.Filename == a.Filename && e.Size == a.Size
+	case []*multipart.FileHeader:
+		a, ok := actual.([]*multipart.FileHeader)
+		if !ok || len(e) != len(a) {
+			return false
+		}
+		for i := range e {
+			if !argEqual(e[i], a[i]) {
+				return false
+			}
+		}
+		return true
+	case *v3.FileModel:
+		a, ok := actual.(*v3.FileModel)
+		if !ok || e == nil || a == nil {
+			return ok && e == a
+		}
+		return e.Filename == a.Filename
+	case []*v3.FileModel:
+		a, ok := actual.([]*v3.FileModel)
+		if !ok || len(e) != len(a) {
+			return false
+		}
+		for i := range e {
+			if !argEqual(e[i], a[i]) {
+				return false
+			}
+		}
+		return true
+	case *os.File:
+		a, ok := actual.(*os.File)
+		if !ok || e == nil || a == nil {
+			return ok && e == a
+		}
+		return e.Name() == a.Name()
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+{{- range $m := .Meta.Methods }}
+
+// {{$.SvcName}}{{$m.Name}}Call records arguments of a call to {{$m.Name}}
+type {{$.SvcName}}{{$m.Name}}Call struct {
+	{{- range $p := $m.Params }}
+	{{ toCamel $p.Name }} {{ $p.Type }}
+	{{- end }}
+}
+{{- end }}
+
+// {{.SvcName}}Mock is a mock implementation of {{.ServiceAlias}}.{{.SvcName}} for unit tests. It records calls,
+// returns results programmed by On and Return methods, or zero values if not programmed.
+type {{.SvcName}}Mock struct {
+	lock sync.Mutex
+	{{- range $m := .Meta.Methods }}
+	{{ toLowerCamel $m.Name }}Calls []{{$.SvcName}}{{$m.Name}}Call
+	{{ toLowerCamel $m.Name }}Func func({{- range $i, $p := $m.Params}}{{- if $i}}, {{end}}{{- $p.Name}} {{$p.Type}}{{- end }}) ({{- range $i, $r := $m.Results}}{{- if $i}}, {{end}}{{- $r.Name}} {{$r.Type}}{{- end }})
+	{{- end }}
+}
+
+var _ {{.ServiceAlias}}.{{.SvcName}} = (*{{.SvcName}}Mock)(nil)
+
+// New{{.SvcName}}Mock creates a new {{.SvcName}}Mock instance
+func New{{.SvcName}}Mock() *{{.SvcName}}Mock {
+	return &{{.SvcName}}Mock{}
+}
+
+{{- range $m := .Meta.Methods }}
+{{- $call := print $.SvcName $m.Name "Call" }}
+{{- $calls := print (toLowerCamel $m.Name) "Calls" }}
+{{- $func := print (toLowerCamel $m.Name) "Func" }}
+
+// On{{$m.Name}} makes {{$m.Name}} call fn with the arguments and return its results
+func (receiver *{{$.SvcName}}Mock) On{{$m.Name}}(fn func({{- range $i, $p := $m.Params}}{{- if $i}}, {{end}}{{- $p.Name}} {{$p.Type}}{{- end }}) ({{- range $i, $r := $m.Results}}{{- if $i}}, {{end}}{{- $r.Name}} {{$r.Type}}{{- end }})) *{{$.SvcName}}Mock {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	receiver.{{$func}} = fn
+	return receiver
+}
+
+// Return{{$m.Name}} makes {{$m.Name}} return the results
+func (receiver *{{$.SvcName}}Mock) Return{{$m.Name}}({{- range $i, $r := $m.Results}}{{- if $i}}, {{end}}{{- $r.Name}} {{$r.Type}}{{- end }}) *{{$.SvcName}}Mock {
+	return receiver.On{{$m.Name}}(func({{- range $i, $p := $m.Params}}{{- if $i}}, {{end}}_ {{$p.Type}}{{- end }}) ({{- range $i, $r := $m.Results}}{{- if $i}}, {{end}}{{- $r.Type}}{{- end }}) {
+		return {{ range $i, $r := $m.Results }}{{- if $i}}, {{end}}{{- $r.Name }}{{- end }}
+	})
+}
+
+// {{$m.Name}} implements {{$.ServiceAlias}}.{{$.SvcName}}
+func (receiver *{{$.SvcName}}Mock) {{$m.Name}}({{- range $i, $p := $m.Params}}{{- if $i}}, {{end}}{{- $p.Name}} {{$p.Type}}{{- end }}) ({{- range $i, $r := $m.Results}}{{- if $i}}, {{end}}{{- $r.Name}} {{$r.Type}}{{- end }}) {
+	receiver.lock.Lock()
+	receiver.{{$calls}} = append(receiver.{{$calls}}, {{$call}}{
+		{{- range $p := $m.Params }}
+		{{ toCamel $p.Name }}: {{ $p.Name }},
+		{{- end }}
+	})
+	_fn := receiver.{{$func}}
+	receiver.lock.Unlock()
+	if _fn != nil {
+		{{ if $m.Results }}return {{ end }}_fn({{- range $i, $p := $m.Params}}{{- if $i}}, {{end}}{{- $p.Name}}{{- end }})
+	}
+	return
+}
+
+// {{$m.Name}}Calls returns recorded calls to {{$m.Name}}
+func (receiver *{{$.SvcName}}Mock) {{$m.Name}}Calls() []{{$call}} {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	return append([]{{$call}}{}, receiver.{{$calls}}...)
+}
+
+// Assert{{$m.Name}}Called asserts that {{$m.Name}} has been called times times
+func (receiver *{{$.SvcName}}Mock) Assert{{$m.Name}}Called(t TestingT, times int) bool {
+	t.Helper()
+	if calls := receiver.{{$m.Name}}Calls(); len(calls) != times {
+		t.Errorf("expected {{$m.Name}} to be called %d times, but called %d times", times, len(calls))
+		return false
+	}
+	return true
+}
+
+// Assert{{$m.Name}}CalledWith asserts that {{$m.Name}} has been called with the arguments at least once,
+// context.Context arguments are ignored and files are compared by name
+func (receiver *{{$.SvcName}}Mock) Assert{{$m.Name}}CalledWith(_t TestingT{{- range $p := $m.Params}}, {{ $p.Name}} {{$p.Type}}{{- end }}) bool {
+	_t.Helper()
+	_calls := receiver.{{$m.Name}}Calls()
+	for _, _call := range _calls {
+		if {{ matchArgs $m.Params }} {
+			return true
+		}
+	}
+	_t.Errorf("expected {{$m.Name}} to be called with %s, but got calls %s", fmt.Sprint({{- range $i, $p := $m.Params }}{{- if $i}}, {{end}}{{ $p.Name }}{{- end }}), fmt.Sprint(_calls))
+	return false
+}
+{{- end }}
+`
+
+func init() {
+	templateutils.Register("svc/mock.go.tmpl", mockTmpl, `VoPackage string: import path of vo package
+Meta astutils.InterfaceMeta: service interface
+ServicePackage string: import path of service package
+ServiceAlias string: package name of service package
+SvcName string: name of service interface
+Functions: toLowerCamel, toCamel, matchArgs returning go expression comparing params with fields of recorded call _call`)
+}
+
+// matchArgs returns go expression comparing params with fields of recorded call _call, context.Context params are skipped
+func matchArgs(params []astutils.FieldMeta) string {
+	var conds []string
+	for _, p := range params {
+		if p.Type == "context.Context" {
+			continue
+		}
+		conds = append(conds, fmt.Sprintf("argEqual(%s, _call.%s)", p.Name, strcase.ToCamel(p.Name)))
+	}
+	if len(conds) == 0 {
+		return "true"
+	}
+	return strings.Join(conds, " && ")
+}
+
+// GenGoMock generates mock implementation of service interface into mock package for unit tests.
+// The file mock/mock.go will be overwritten each time.
+func GenGoMock(dir string, ic astutils.InterfaceCollector) {
+	var (
+		err       error
+		mockfile  string
+		f         fileutils.File
+		tpl       *template.Template
+		buf       bytes.Buffer
+		mockDir   string
+		fi        os.FileInfo
+		modfile   string
+		modName   string
+		firstLine string
+		modf      fileutils.File
+		meta      astutils.InterfaceMeta
+	)
+	mockDir = filepath.Join(dir, "mock")
+	if err = fileutils.MkdirAll(mockDir, os.ModePerm); err != nil {
+		panic(err)
+	}
+
+	mockfile = filepath.Join(mockDir, "mock.go")
+	fi, err = fileutils.Stat(mockfile)
+	if err != nil && !os.IsNotExist(err) {
+		panic(err)
+	}
+	if fi != nil {
+		logrus.Warningln("file mock.go will be overwrited")
+	}
+	if f, err = fileutils.Create(mockfile); err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	err = copier.DeepCopy(ic.Interfaces[0], &meta)
+	if err != nil {
+		panic(err)
+	}
+
+	modfile = filepath.Join(dir, "go.mod")
+	if modf, err = fileutils.Open(modfile); err != nil {
+		panic(err)
+	}
+	defer modf.Close()
+	reader := bufio.NewReader(modf)
+	if firstLine, err = reader.ReadString('\n'); err != nil {
+		panic(err)
+	}
+	modName = strings.TrimSpace(strings.TrimPrefix(firstLine, "module"))
+
+	funcMap := make(map[string]interface{})
+	funcMap["toLowerCamel"] = strcase.ToLowerCamel
+	funcMap["toCamel"] = strcase.ToCamel
+	funcMap["matchArgs"] = matchArgs
+	if tpl, err = template.New("mock.go.tmpl").Funcs(funcMap).Parse(templateutils.Lookup("svc/mock.go.tmpl")); err != nil {
+		panic(err)
+	}
+	if err = tpl.Execute(&buf, struct {
+		VoPackage      string
+		Meta           astutils.InterfaceMeta
+		ServicePackage string
+		ServiceAlias   string
+		SvcName        string
+	}{
+		VoPackage:      modName + "/vo",
+		Meta:           meta,
+		ServicePackage: modName,
+		ServiceAlias:   ic.Package.Name,
+		SvcName:        ic.Interfaces[0].Name,
+	}); err != nil {
+		panic(err)
+	}
+
+	astutils.FixImport(buf.Bytes(), mockfile)
+}
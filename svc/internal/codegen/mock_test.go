@@ -0,0 +1,77 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	"github.com/unionj-cloud/go-doudou/pathutils"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenGoMock(t *testing.T) {
+	dir := testDir + "mock"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	svcfile := filepath.Join(testDir, "svc.go")
+	ic := astutils.BuildInterfaceCollector(svcfile, astutils.ExprString)
+	GenGoMock(dir, ic)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "mock", "mock.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := string(data)
+	assert.Contains(t, source, "var _ service.Usersvc = (*UsersvcMock)(nil)")
+	assert.Contains(t, source, "func (receiver *UsersvcMock) ReturnGetUser(code int, data string, msg error) *UsersvcMock {")
+	assert.Contains(t, source, "func (receiver *UsersvcMock) AssertGetUserCalled(t TestingT, times int) bool {")
+	assert.Contains(t, source, "if argEqual(userId, _call.UserId) && argEqual(photo, _call.Photo) {")
+	assert.Contains(t, source, "Pf3 *multipart.FileHeader")
+}
+
+func TestGenGoMock_Build(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	dir, err := ioutil.TempDir("", "testbuildmock")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	// generated mocks use go-doudou of this repository
+	gomod := fmt.Sprintf(`module testbuildmock
+
+go 1.17
+
+require github.com/unionj-cloud/go-doudou v0.0.0
+
+replace github.com/unionj-cloud/go-doudou => %s
+`, pathutils.Abs("../../.."))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), os.ModePerm))
+	// params named like variables used by generated methods
+	svcfile := filepath.Join(dir, "svc.go")
+	assert.NoError(t, ioutil.WriteFile(svcfile, []byte(`package service
+
+import "context"
+
+type Testsvc interface {
+	Run(ctx context.Context, t string, fn int, call bool, calls []string) (data string, err error)
+}
+`), os.ModePerm))
+	GenGoMock(dir, astutils.BuildInterfaceCollector(svcfile, astutils.ExprString))
+	cmd := exec.Command("go", "build", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOSUMDB=off")
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(output))
+}
+
+func Test_matchArgs(t *testing.T) {
+	assert.Equal(t, "true", matchArgs([]astutils.FieldMeta{{Name: "ctx", Type: "context.Context"}}))
+	assert.Equal(t, "argEqual(userId, _call.UserId)", matchArgs([]astutils.FieldMeta{
+		{Name: "ctx", Type: "context.Context"},
+		{Name: "userId", Type: "int"},
+	}))
+}
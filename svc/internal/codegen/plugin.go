@@ -0,0 +1,99 @@
+package codegen
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/plugin"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// vosOf parses all structs in vo package of the project in dir
+func vosOf(dir string) []astutils.StructMeta {
+	var (
+		files []string
+		vos   []astutils.StructMeta
+	)
+	if err := filepath.Walk(filepath.Join(dir, "vo"), astutils.Visit(&files)); err != nil {
+		logrus.Panicln(err)
+	}
+	for _, file := range files {
+		sc := astutils.BuildStructCollector(file, ExprStringP)
+		vos = append(vos, sc.Structs...)
+	}
+	return vos
+}
+
+// GenPlugin runs executable go-doudou-gen-<name> found in PATH in dir, sends it plugin.Request as json through stdin,
+// and writes files of plugin.Response received from its stdout into dir
+func GenPlugin(dir string, ic astutils.InterfaceCollector, name string, routePatternStrategy int) {
+	var (
+		err        error
+		modName    string
+		firstLine  string
+		f          fileutils.File
+		req        []byte
+		stdout     bytes.Buffer
+		resp       plugin.Response
+		executable string
+	)
+	if f, err = fileutils.Open(filepath.Join(dir, "go.mod")); err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	if firstLine, err = reader.ReadString('\n'); err != nil {
+		panic(err)
+	}
+	modName = strings.TrimSpace(strings.TrimPrefix(firstLine, "module"))
+	if req, err = json.Marshal(plugin.Request{
+		Module:             modName,
+		InterfaceCollector: ic,
+		Vos:                vosOf(dir),
+		API:                apiOf(dir, ic, routePatternStrategy),
+	}); err != nil {
+		panic(err)
+	}
+	if executable, err = exec.LookPath(plugin.Prefix + name); err != nil {
+		panic(errors.Wrapf(err, "plugin %s not found", name))
+	}
+	cmd := exec.Command(executable)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		panic(errors.Wrapf(err, "plugin %s failed", name))
+	}
+	if err = json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		panic(errors.Wrapf(err, "invalid response from plugin %s", name))
+	}
+	if stringutils.IsNotEmpty(resp.Error) {
+		panic(fmt.Errorf("plugin %s failed: %s", name, resp.Error))
+	}
+	for _, file := range resp.Files {
+		rel := filepath.Clean(filepath.FromSlash(file.Path))
+		if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			panic(fmt.Errorf("plugin %s returned file %s out of %s", name, file.Path, dir))
+		}
+		target := filepath.Join(dir, rel)
+		if _, err = fileutils.Stat(target); err == nil {
+			logrus.Warningln("file " + target + " will be overwrited")
+		}
+		if err = fileutils.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			panic(err)
+		}
+		if err = fileutils.WriteFile(target, []byte(file.Content), os.ModePerm); err != nil {
+			panic(err)
+		}
+	}
+}
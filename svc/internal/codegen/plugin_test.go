@@ -0,0 +1,85 @@
+package codegen
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	"github.com/unionj-cloud/go-doudou/svc/plugin"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// installPlugin writes a shell script plugin which saves request into request.json and prints response
+func installPlugin(t *testing.T, bin, name, response string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script plugin is not supported on windows")
+	}
+	script := "#!/bin/sh\ncat > request.json\necho '" + response + "'\n"
+	assert.NoError(t, os.MkdirAll(bin, os.ModePerm))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(bin, plugin.Prefix+name), []byte(script), 0755))
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", bin+string(os.PathListSeparator)+path)
+	t.Cleanup(func() {
+		os.Setenv("PATH", path)
+	})
+}
+
+func TestGenPlugin(t *testing.T) {
+	dir := testDir + "plugin"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	installPlugin(t, filepath.Join(dir, "bin"), "sdk", `{"files":[{"path":"sdk/usersvc.txt","content":"hello"}]}`)
+	ic := astutils.BuildInterfaceCollector(filepath.Join(dir, "svc.go"), astutils.ExprString)
+	GenPlugin(dir, ic, "sdk", 0)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "sdk", "usersvc.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "request.json"))
+	assert.NoError(t, err)
+	var req plugin.Request
+	assert.NoError(t, json.Unmarshal(data, &req))
+	assert.Equal(t, "testdataplugin", req.Module)
+	assert.Equal(t, "Testdataplugin", req.InterfaceCollector.Interfaces[0].Name)
+	assert.Equal(t, "PageUsers", req.InterfaceCollector.Interfaces[0].Methods[0].Name)
+	assert.NotEmpty(t, req.Vos)
+	assert.Contains(t, req.API.Paths, "/page/users")
+}
+
+func TestGenPluginOutOfDir(t *testing.T) {
+	dir := testDir + "pluginoutofdir"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	installPlugin(t, filepath.Join(dir, "bin"), "evil", `{"files":[{"path":"../evil.txt","content":"hello"}]}`)
+	ic := astutils.BuildInterfaceCollector(filepath.Join(dir, "svc.go"), astutils.ExprString)
+	assert.Panics(t, func() {
+		GenPlugin(dir, ic, "evil", 0)
+	})
+	_, err := os.Stat(filepath.Join(testDir, "evil.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGenPluginError(t *testing.T) {
+	dir := testDir + "pluginerror"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	installPlugin(t, filepath.Join(dir, "bin"), "broken", `{"error":"unsupported type"}`)
+	ic := astutils.BuildInterfaceCollector(filepath.Join(dir, "svc.go"), astutils.ExprString)
+	assert.Panics(t, func() {
+		GenPlugin(dir, ic, "broken", 0)
+	})
+}
+
+func TestGenPluginNotFound(t *testing.T) {
+	dir := testDir + "pluginnotfound"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	ic := astutils.BuildInterfaceCollector(filepath.Join(dir, "svc.go"), astutils.ExprString)
+	assert.Panics(t, func() {
+		GenPlugin(dir, ic, "notexists", 0)
+	})
+}
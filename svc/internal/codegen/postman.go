@@ -0,0 +1,406 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+	"github.com/unionj-cloud/go-doudou/openapi/v3/mock"
+	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/go-doudou/templateutils"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+type postmanKV struct {
+	Key         string `json:"key"`
+	Value       string `json:"value,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Src         string `json:"src,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type postmanURL struct {
+	Raw   string      `json:"raw"`
+	Host  []string    `json:"host"`
+	Path  []string    `json:"path"`
+	Query []postmanKV `json:"query,omitempty"`
+}
+
+type postmanBody struct {
+	Mode       string                 `json:"mode"`
+	Raw        string                 `json:"raw,omitempty"`
+	URLEncoded []postmanKV            `json:"urlencoded,omitempty"`
+	FormData   []postmanKV            `json:"formdata,omitempty"`
+	Options    map[string]interface{} `json:"options,omitempty"`
+}
+
+type postmanRequest struct {
+	Method      string       `json:"method"`
+	Header      []postmanKV  `json:"header"`
+	URL         postmanURL   `json:"url"`
+	Body        *postmanBody `json:"body,omitempty"`
+	Description string       `json:"description,omitempty"`
+}
+
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item,omitempty"`
+	Request *postmanRequest `json:"request,omitempty"`
+}
+
+type postmanInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      string `json:"schema"`
+}
+
+// postmanCollection https://schema.getpostman.com/json/collection/v2.1.0/collection.json
+type postmanCollection struct {
+	Info     postmanInfo   `json:"info"`
+	Item     []postmanItem `json:"item"`
+	Variable []postmanKV   `json:"variable"`
+}
+
+// sampleRequest is sample request of an api shared by postman collection and .http file
+type sampleRequest struct {
+	Name        string
+	Method      string
+	Endpoint    string
+	Description string
+	Query       []postmanKV
+	ContentType string
+	Raw         string
+	Form        []postmanKV
+}
+
+// QueryString returns url encoded query string with leading question mark
+func (receiver sampleRequest) QueryString() string {
+	if len(receiver.Query) == 0 {
+		return ""
+	}
+	var pairs []string
+	for _, item := range receiver.Query {
+		pairs = append(pairs, url.QueryEscape(item.Key)+"="+url.QueryEscape(item.Value))
+	}
+	return "?" + strings.Join(pairs, "&")
+}
+
+func sampleString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func resolveSchema(schema *v3.Schema) *v3.Schema {
+	if schema != nil && stringutils.IsNotEmpty(schema.Ref) {
+		if ref, exists := v3.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]; exists {
+			return &ref
+		}
+	}
+	return schema
+}
+
+func isFileSchema(schema *v3.Schema) bool {
+	if schema.Type == v3.ArrayT && schema.Items != nil {
+		schema = schema.Items
+	}
+	return schema.Type == v3.StringT && schema.Format == v3.BinaryF
+}
+
+// formOf converts properties of object schema to form fields sorted by name
+func formOf(schema *v3.Schema) []postmanKV {
+	schema = resolveSchema(schema)
+	if schema == nil {
+		return nil
+	}
+	var keys []string
+	for k := range schema.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var ret []postmanKV
+	for _, k := range keys {
+		prop := schema.Properties[k]
+		if isFileSchema(prop) {
+			ret = append(ret, postmanKV{
+				Key:         k,
+				Type:        "file",
+				Src:         "file.txt",
+				Description: prop.Description,
+			})
+			continue
+		}
+		value := mock.ValueOf(prop, v3.Schemas)
+		if values, ok := value.([]interface{}); ok {
+			for _, item := range values {
+				ret = append(ret, postmanKV{
+					Key:         k,
+					Value:       sampleString(item),
+					Type:        "text",
+					Description: prop.Description,
+				})
+			}
+			continue
+		}
+		ret = append(ret, postmanKV{
+			Key:         k,
+			Value:       sampleString(value),
+			Type:        "text",
+			Description: prop.Description,
+		})
+	}
+	return ret
+}
+
+func sampleRequestOf(name, httpMethod, endpoint string, op *v3.Operation) sampleRequest {
+	req := sampleRequest{
+		Name:        name,
+		Method:      httpMethod,
+		Endpoint:    endpoint,
+		Description: op.Description,
+	}
+	for _, param := range op.Parameters {
+		if param.In != v3.InQuery {
+			continue
+		}
+		value := mock.ValueOf(param.Schema, v3.Schemas)
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+		for _, item := range values {
+			req.Query = append(req.Query, postmanKV{
+				Key:         param.Name,
+				Value:       sampleString(item),
+				Description: param.Description,
+			})
+		}
+	}
+	if op.RequestBody == nil || op.RequestBody.Content == nil {
+		return req
+	}
+	content := op.RequestBody.Content
+	switch {
+	case content.JSON != nil:
+		req.ContentType = "application/json"
+		data, _ := json.MarshalIndent(mock.ValueOf(content.JSON.Schema, v3.Schemas), "", "  ")
+		req.Raw = string(data)
+	case content.FormURL != nil:
+		req.ContentType = "application/x-www-form-urlencoded"
+		req.Form = formOf(content.FormURL.Schema)
+	case content.FormData != nil:
+		req.ContentType = "multipart/form-data"
+		req.Form = formOf(content.FormData.Schema)
+	}
+	return req
+}
+
+func postmanItemOf(req sampleRequest) postmanItem {
+	pr := &postmanRequest{
+		Method: req.Method,
+		Header: []postmanKV{},
+		URL: postmanURL{
+			Raw:   "{{baseUrl}}" + req.Endpoint + req.QueryString(),
+			Host:  []string{"{{baseUrl}}"},
+			Path:  strings.Split(strings.TrimPrefix(req.Endpoint, "/"), "/"),
+			Query: req.Query,
+		},
+		Description: req.Description,
+	}
+	switch req.ContentType {
+	case "application/json":
+		pr.Header = append(pr.Header, postmanKV{
+			Key:   "Content-Type",
+			Value: req.ContentType,
+		})
+		pr.Body = &postmanBody{
+			Mode: "raw",
+			Raw:  req.Raw,
+			Options: map[string]interface{}{
+				"raw": map[string]string{
+					"language": "json",
+				},
+			},
+		}
+	case "application/x-www-form-urlencoded":
+		pr.Body = &postmanBody{
+			Mode:       "urlencoded",
+			URLEncoded: req.Form,
+		}
+	case "multipart/form-data":
+		pr.Body = &postmanBody{
+			Mode:     "formdata",
+			FormData: req.Form,
+		}
+	}
+	return postmanItem{
+		Name:    req.Name,
+		Request: pr,
+	}
+}
+
+var httpFileTmpl = `@baseUrl = {{.BaseURL}}
+{{- range $r := .Requests }}
+
+### {{$r.Name}}
+{{- range $line := $r.Description | lines }}
+# {{$line}}
+{{- end }}
+{{$r.Method}} {{"{{"}}baseUrl{{"}}"}}{{$r.Endpoint}}{{$r.QueryString}}
+{{- if eq $r.ContentType "application/json" }}
+Content-Type: application/json
+
+{{$r.Raw}}
+{{- else if eq $r.ContentType "application/x-www-form-urlencoded" }}
+Content-Type: application/x-www-form-urlencoded
+
+{{ range $i, $f := $r.Form }}{{if $i}}&{{end}}{{$f.Key | escape}}={{$f.Value | escape}}{{end}}
+{{- else if eq $r.ContentType "multipart/form-data" }}
+Content-Type: multipart/form-data; boundary=boundary
+{{ range $f := $r.Form }}
+--boundary
+{{- if eq $f.Type "file" }}
+Content-Disposition: form-data; name="{{$f.Key}}"; filename="{{$f.Src}}"
+
+< ./{{$f.Src}}
+{{- else }}
+Content-Disposition: form-data; name="{{$f.Key}}"
+
+{{$f.Value}}
+{{- end }}
+{{- end }}
+--boundary--
+{{- end }}
+{{- end }}
+`
+
+// baseURLOf returns base url from GDD_PORT and GDD_ROUTE_ROOT_PATH in .env file of the project or environment variables
+func baseURLOf(dir string) string {
+	env, _ := godotenv.Read(filepath.Join(dir, ".env"))
+	port := env[string(config.GddPort)]
+	if stringutils.IsEmpty(port) {
+		port = config.GddPort.Load()
+	}
+	if stringutils.IsEmpty(port) {
+		port = "6060"
+	}
+	rootPath, exists := env[string(config.GddRouteRootPath)]
+	if !exists {
+		rootPath = config.GddRouteRootPath.Load()
+	}
+	return fmt.Sprintf("http://localhost:%s%s", port, strings.TrimSuffix(rootPath, "/"))
+}
+
+func init() {
+	templateutils.Register("svc/svc.http.tmpl", httpFileTmpl, `BaseURL string: base url of service from .env file
+Requests []sampleRequest: sample request of each api with fields Name, Method, Endpoint, Description, ContentType, Raw for json body, Query and Form of []postmanKV with fields Key, Value, Type and Src, and method QueryString
+Functions: lines splitting string by line breaks, escape for url query escaping`)
+}
+
+// GenPostman generates Postman v2.1 collection json file and .http file with sample requests for each method of service interface
+func GenPostman(dir string, ic astutils.InterfaceCollector, routePatternStrategy int) {
+	if len(ic.Interfaces) == 0 {
+		return
+	}
+	v3.Schemas = make(map[string]v3.Schema)
+	loadVoSchemas(dir)
+	inter := ic.Interfaces[0]
+	paths := pathsOf(ic, routePatternStrategy)
+	var requests []sampleRequest
+	folder := postmanItem{
+		Name: inter.Name,
+		Item: []postmanItem{},
+	}
+	for _, method := range inter.Methods {
+		endpoint := endpointOf(inter, method, routePatternStrategy)
+		path := paths[endpoint]
+		rv := reflect.ValueOf(path)
+		for _, hm := range []string{get, post, put, delete} {
+			op, ok := rv.FieldByName(strings.Title(strings.ToLower(hm))).Interface().(*v3.Operation)
+			if !ok || op == nil {
+				continue
+			}
+			req := sampleRequestOf(method.Name, hm, endpoint, op)
+			requests = append(requests, req)
+			folder.Item = append(folder.Item, postmanItemOf(req))
+		}
+	}
+	_, comments := parseAnnotations(inter.Comments)
+	baseURL := baseURLOf(dir)
+	collection := postmanCollection{
+		Info: postmanInfo{
+			Name:        inter.Name,
+			Description: strings.Join(comments, "\n"),
+			Schema:      postmanSchema,
+		},
+		Item: []postmanItem{folder},
+		Variable: []postmanKV{
+			{
+				Key:   "baseUrl",
+				Value: baseURL,
+			},
+		},
+	}
+	data, err := json.MarshalIndent(collection, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	collectionfile := filepath.Join(dir, strings.ToLower(inter.Name)+"_postman_collection.json")
+	if _, err = fileutils.Stat(collectionfile); err == nil {
+		logrus.Warningln("file " + collectionfile + " will be overwrited")
+	}
+	if err = fileutils.WriteFile(collectionfile, data, os.ModePerm); err != nil {
+		panic(err)
+	}
+
+	funcMap := make(map[string]interface{})
+	funcMap["lines"] = func(s string) []string {
+		if stringutils.IsEmpty(s) {
+			return nil
+		}
+		return strings.Split(s, "\n")
+	}
+	funcMap["escape"] = url.QueryEscape
+	tpl, err := template.New("http.tmpl").Funcs(funcMap).Parse(templateutils.Lookup("svc/svc.http.tmpl"))
+	if err != nil {
+		panic(err)
+	}
+	var buf bytes.Buffer
+	if err = tpl.Execute(&buf, struct {
+		BaseURL  string
+		Requests []sampleRequest
+	}{
+		BaseURL:  baseURL,
+		Requests: requests,
+	}); err != nil {
+		panic(err)
+	}
+	httpfile := filepath.Join(dir, strings.ToLower(inter.Name)+".http")
+	if _, err = fileutils.Stat(httpfile); err == nil {
+		logrus.Warningln("file " + httpfile + " will be overwrited")
+	}
+	if err = fileutils.WriteFile(httpfile, buf.Bytes(), os.ModePerm); err != nil {
+		panic(err)
+	}
+}
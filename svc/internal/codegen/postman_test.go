@@ -0,0 +1,59 @@
+package codegen
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenPostman(t *testing.T) {
+	svcfile := filepath.Join(testDir, "svc.go")
+	ic := astutils.BuildInterfaceCollector(svcfile, ExprStringP)
+	collectionfile := filepath.Join(testDir, "usersvc_postman_collection.json")
+	httpfile := filepath.Join(testDir, "usersvc.http")
+	defer os.Remove(collectionfile)
+	defer os.Remove(httpfile)
+	GenPostman(testDir, ic, 0)
+
+	data, err := ioutil.ReadFile(collectionfile)
+	assert.NoError(t, err)
+	var collection postmanCollection
+	assert.NoError(t, json.Unmarshal(data, &collection))
+	assert.Equal(t, postmanSchema, collection.Info.Schema)
+	assert.Equal(t, "baseUrl", collection.Variable[0].Key)
+	assert.Len(t, collection.Item, 1)
+	folder := collection.Item[0]
+	assert.Equal(t, "Usersvc", folder.Name)
+	assert.Len(t, folder.Item, len(ic.Interfaces[0].Methods))
+	for _, item := range folder.Item {
+		switch item.Name {
+		case "PageUsers":
+			assert.Equal(t, "raw", item.Request.Body.Mode)
+			assert.Contains(t, item.Request.Body.Raw, `"Page"`)
+		case "SignUp":
+			assert.Equal(t, "urlencoded", item.Request.Body.Mode)
+		case "UploadAvatar":
+			assert.Equal(t, "formdata", item.Request.Body.Mode)
+		case "GetUser":
+			assert.Nil(t, item.Request.Body)
+			assert.Equal(t, "{{baseUrl}}/user?userId=string&photo=string", item.Request.URL.Raw)
+		}
+	}
+
+	data, err = ioutil.ReadFile(httpfile)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "### GetUser\n# comment1\n# comment2\nGET {{baseUrl}}/user?userId=string&photo=string")
+	assert.Contains(t, string(data), "Content-Type: multipart/form-data; boundary=boundary")
+}
+
+func Test_baseURLOf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "postman")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".env"), []byte("GDD_PORT=8080\nGDD_ROUTE_ROOT_PATH=/api/\n"), os.ModePerm))
+	assert.Equal(t, "http://localhost:8080/api", baseURLOf(dir))
+}
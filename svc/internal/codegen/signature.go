@@ -0,0 +1,306 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+func fieldsString(fields []astutils.FieldMeta) string {
+	var items []string
+	for _, field := range fields {
+		items = append(items, strings.TrimSpace(field.Name+" "+field.Type))
+	}
+	return strings.Join(items, ", ")
+}
+
+// signatureOf formats signature of method, such as GetUser(ctx context.Context, userId int) (data string, err error)
+func signatureOf(method astutils.MethodMeta) string {
+	return fmt.Sprintf("%s(%s) (%s)", method.Name, fieldsString(method.Params), fieldsString(method.Results))
+}
+
+// sameSignature reports whether a and b have the same names and types of params and results.
+// Names matter because generated code uses them as query string keys, form fields and json properties.
+func sameSignature(a, b astutils.MethodMeta) bool {
+	return signatureOf(a) == signatureOf(b)
+}
+
+// reportChanged logs methods in file which will be regenerated because of signature changes
+func reportChanged(file string, changed []astutils.MethodMeta) {
+	for _, method := range changed {
+		logrus.Warnf("signature of %s has changed to %s, generated method in %s will be regenerated",
+			method.Name, signatureOf(method), file)
+	}
+}
+
+// funcKey returns Recv.Name for methods and Name for functions
+func funcKey(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	recv := astutils.ExprString(fn.Recv.List[0].Type)
+	return strings.TrimPrefix(recv, "*") + "." + fn.Name.Name
+}
+
+type span struct {
+	start, end int
+	text       string
+}
+
+// replaceSpans replaces spans of src by their texts
+func replaceSpans(src []byte, spans []span) []byte {
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start < spans[j].start
+	})
+	var ret []byte
+	var last int
+	for _, item := range spans {
+		ret = append(ret, src[last:item.start]...)
+		ret = append(ret, item.text...)
+		last = item.end
+	}
+	return append(ret, src[last:]...)
+}
+
+func spanOf(fset *token.FileSet, doc *ast.CommentGroup, node ast.Node) span {
+	start := node.Pos()
+	if doc != nil {
+		start = doc.Pos()
+	}
+	return span{
+		start: fset.Position(start).Offset,
+		end:   fset.Position(node.End()).Offset,
+	}
+}
+
+// removeFuncs removes declarations of functions and methods in keys from src together with their doc comments.
+// A key is Recv.Name for methods, such as UsersvcHandlerImpl.GetUser, and Name for functions.
+func removeFuncs(src []byte, keys map[string]bool) []byte {
+	if len(keys) == 0 {
+		return src
+	}
+	fset := token.NewFileSet()
+	root, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		panic(err)
+	}
+	var spans []span
+	for _, decl := range root.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && keys[funcKey(fn)] {
+			spans = append(spans, spanOf(fset, fn.Doc, fn))
+		}
+	}
+	return replaceSpans(src, spans)
+}
+
+// paramsResultsOf formats params and results of method, such as (ctx context.Context, userId int) (data string, err error)
+func paramsResultsOf(method astutils.MethodMeta) string {
+	ret := "(" + fieldsString(method.Params) + ")"
+	if len(method.Results) > 0 {
+		ret += " (" + fieldsString(method.Results) + ")"
+	}
+	return ret
+}
+
+// replaceSignatures replaces params and results of methods of recv in src by the ones of methods keyed by method name,
+// bodies and doc comments of the methods are kept
+func replaceSignatures(src []byte, recv string, methods map[string]astutils.MethodMeta) []byte {
+	if len(methods) == 0 {
+		return src
+	}
+	fset := token.NewFileSet()
+	root, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		panic(err)
+	}
+	var spans []span
+	for _, decl := range root.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || funcKey(fn) != recv+"."+fn.Name.Name {
+			continue
+		}
+		if method, exists := methods[fn.Name.Name]; exists {
+			spans = append(spans, span{
+				start: fset.Position(fn.Type.Params.Pos()).Offset,
+				end:   fset.Position(fn.Type.End()).Offset,
+				text:  paramsResultsOf(method),
+			})
+		}
+	}
+	return replaceSpans(src, spans)
+}
+
+// replaceInterfaceMethods replaces declarations of methods of interface iface in src by new declarations in methods
+// keyed by method name, methods not declared in the interface are ignored
+func replaceInterfaceMethods(src []byte, iface string, methods map[string]string) []byte {
+	if len(methods) == 0 {
+		return src
+	}
+	fset := token.NewFileSet()
+	root, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		panic(err)
+	}
+	var spans []span
+	ast.Inspect(root, func(n ast.Node) bool {
+		typeSpec, ok := n.(*ast.TypeSpec)
+		if !ok || typeSpec.Name.Name != iface {
+			return true
+		}
+		interfaceType, ok := typeSpec.Type.(*ast.InterfaceType)
+		if !ok {
+			return false
+		}
+		for _, field := range interfaceType.Methods.List {
+			if len(field.Names) == 0 {
+				continue
+			}
+			if text, exists := methods[field.Names[0].Name]; exists {
+				item := spanOf(fset, nil, field)
+				item.text = text
+				spans = append(spans, item)
+			}
+		}
+		return false
+	})
+	return replaceSpans(src, spans)
+}
+
+// appendInterfaceMethods appends declarations of methods to the end of interface iface in src
+func appendInterfaceMethods(src []byte, iface string, methods []string) []byte {
+	if len(methods) == 0 {
+		return src
+	}
+	fset := token.NewFileSet()
+	root, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		panic(err)
+	}
+	var spans []span
+	ast.Inspect(root, func(n ast.Node) bool {
+		typeSpec, ok := n.(*ast.TypeSpec)
+		if !ok || typeSpec.Name.Name != iface {
+			return true
+		}
+		interfaceType, ok := typeSpec.Type.(*ast.InterfaceType)
+		if !ok {
+			return false
+		}
+		closing := fset.Position(interfaceType.Methods.Closing).Offset
+		var text string
+		if src[closing-1] != '\n' {
+			text = "\n"
+		}
+		for _, method := range methods {
+			text += "\t" + method + "\n"
+		}
+		spans = append(spans, span{
+			start: closing,
+			end:   closing,
+			text:  text,
+		})
+		return false
+	})
+	return replaceSpans(src, spans)
+}
+
+// handlerCallOf rebuilds signature of service method called in handler generated by GenHttpHandlerImplWithImpl
+// from types of variables declared at the beginning and the call to receiver.svc.Method. It reports false if the
+// call is not found, such as handlers implemented by hand.
+func handlerCallOf(fn *ast.FuncDecl, svcField string) (astutils.MethodMeta, bool) {
+	ret := astutils.MethodMeta{
+		Name: fn.Name.Name,
+	}
+	if fn.Body == nil {
+		return ret, false
+	}
+	types := make(map[string]string)
+	for _, stmt := range fn.Body.List {
+		declStmt, ok := stmt.(*ast.DeclStmt)
+		if !ok {
+			continue
+		}
+		genDecl, ok := declStmt.Decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			valueSpec := spec.(*ast.ValueSpec)
+			if valueSpec.Type == nil {
+				continue
+			}
+			for _, name := range valueSpec.Names {
+				types[name.Name] = astutils.ExprString(valueSpec.Type)
+			}
+		}
+		break
+	}
+	isCall := func(expr ast.Expr) (*ast.CallExpr, bool) {
+		call, ok := expr.(*ast.CallExpr)
+		if !ok {
+			return nil, false
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != fn.Name.Name {
+			return nil, false
+		}
+		field, ok := sel.X.(*ast.SelectorExpr)
+		if !ok || field.Sel.Name != svcField {
+			return nil, false
+		}
+		return call, true
+	}
+	var (
+		call    *ast.CallExpr
+		results []ast.Expr
+	)
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if call != nil {
+			return false
+		}
+		switch stmt := n.(type) {
+		case *ast.AssignStmt:
+			if len(stmt.Rhs) == 1 {
+				if c, ok := isCall(stmt.Rhs[0]); ok {
+					call, results = c, stmt.Lhs
+				}
+			}
+		case *ast.ExprStmt:
+			if c, ok := isCall(stmt.X); ok {
+				call = c
+			}
+		}
+		return true
+	})
+	if call == nil {
+		return ret, false
+	}
+	fieldOf := func(expr ast.Expr) (astutils.FieldMeta, bool) {
+		ident, ok := expr.(*ast.Ident)
+		if !ok {
+			return astutils.FieldMeta{}, false
+		}
+		t, ok := types[ident.Name]
+		return astutils.FieldMeta{Name: ident.Name, Type: t}, ok
+	}
+	for _, arg := range call.Args {
+		field, ok := fieldOf(arg)
+		if !ok {
+			return ret, false
+		}
+		ret.Params = append(ret.Params, field)
+	}
+	for _, result := range results {
+		field, ok := fieldOf(result)
+		if !ok {
+			return ret, false
+		}
+		ret.Results = append(ret.Results, field)
+	}
+	return ret, true
+}
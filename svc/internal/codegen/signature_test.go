@@ -0,0 +1,118 @@
+package codegen
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func Test_sameSignature(t *testing.T) {
+	getUser := astutils.MethodMeta{
+		Name: "GetUser",
+		Params: []astutils.FieldMeta{
+			{Name: "ctx", Type: "context.Context"},
+			{Name: "userId", Type: "int"},
+		},
+		Results: []astutils.FieldMeta{
+			{Name: "data", Type: "string"},
+			{Name: "err", Type: "error"},
+		},
+	}
+	assert.Equal(t, "GetUser(ctx context.Context, userId int) (data string, err error)", signatureOf(getUser))
+	assert.True(t, sameSignature(getUser, getUser))
+
+	renamed := getUser
+	renamed.Params = []astutils.FieldMeta{
+		{Name: "ctx", Type: "context.Context"},
+		{Name: "id", Type: "int"},
+	}
+	assert.False(t, sameSignature(getUser, renamed))
+}
+
+const signatureSrc = `package httpsrv
+
+type UsersvcHandlerImpl struct{}
+
+// GetUser gets user
+func (receiver *UsersvcHandlerImpl) GetUser(_writer http.ResponseWriter, _req *http.Request) {
+	var (
+		ctx    context.Context
+		userId int
+		data   string
+		err    error
+	)
+	data, err = receiver.usersvc.GetUser(
+		ctx,
+		userId,
+	)
+}
+
+func (receiver *UsersvcHandlerImpl) SignUp(_writer http.ResponseWriter, _req *http.Request) {
+	custom(_writer, _req)
+}
+
+type UsersvcFallback interface {
+	GetUser(ctx context.Context, userId int, _err error) (data string, err error)
+	SignUp(ctx context.Context, _err error) (err error)
+}
+`
+
+func Test_handlerCallOf(t *testing.T) {
+	root, err := parser.ParseFile(token.NewFileSet(), "", signatureSrc, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	called, ok := handlerCallOf(root.Decls[1].(*ast.FuncDecl), "usersvc")
+	assert.True(t, ok)
+	assert.Equal(t, "GetUser(ctx context.Context, userId int) (data string, err error)", signatureOf(called))
+
+	_, ok = handlerCallOf(root.Decls[2].(*ast.FuncDecl), "usersvc")
+	assert.False(t, ok)
+}
+
+func Test_removeFuncs(t *testing.T) {
+	source := string(removeFuncs([]byte(signatureSrc), map[string]bool{
+		"UsersvcHandlerImpl.GetUser": true,
+	}))
+	assert.NotContains(t, source, "GetUser gets user")
+	assert.NotContains(t, source, "receiver.usersvc.GetUser(")
+	assert.Contains(t, source, "func (receiver *UsersvcHandlerImpl) SignUp(")
+}
+
+func Test_replaceInterfaceMethods(t *testing.T) {
+	source := string(replaceInterfaceMethods([]byte(signatureSrc), "UsersvcFallback", map[string]string{
+		"GetUser": "GetUser(ctx context.Context, userId string, _err error) (data string, err error)",
+		"Ping":    "Ping(_err error) (err error)",
+	}))
+	assert.Contains(t, source, "\tGetUser(ctx context.Context, userId string, _err error) (data string, err error)\n")
+	assert.Contains(t, source, "\tSignUp(ctx context.Context, _err error) (err error)\n")
+	assert.NotContains(t, source, "Ping")
+}
+
+func Test_appendInterfaceMethods(t *testing.T) {
+	source := string(appendInterfaceMethods([]byte(signatureSrc), "UsersvcFallback", []string{
+		"Ping(ctx context.Context, _err error) (pong string, err error)",
+	}))
+	assert.Contains(t, source, "\tPing(ctx context.Context, _err error) (pong string, err error)\n}")
+	assert.Contains(t, source, "\tSignUp(ctx context.Context, _err error) (err error)\n")
+}
+
+func Test_replaceSignatures(t *testing.T) {
+	source := string(replaceSignatures([]byte(signatureSrc), "UsersvcHandlerImpl", map[string]astutils.MethodMeta{
+		"GetUser": {
+			Name:    "GetUser",
+			Params:  []astutils.FieldMeta{{Name: "ctx", Type: "context.Context"}, {Name: "userId", Type: "string"}},
+			Results: []astutils.FieldMeta{{Name: "data", Type: "string"}, {Name: "err", Type: "error"}},
+		},
+		"SignUp": {
+			Name: "SignUp",
+		},
+	}))
+	assert.Contains(t, source, "// GetUser gets user\nfunc (receiver *UsersvcHandlerImpl) GetUser(ctx context.Context, userId string) (data string, err error) {\n\tvar (")
+	assert.Contains(t, source, "func (receiver *UsersvcHandlerImpl) SignUp() {\n\tcustom(_writer, _req)\n}")
+	// methods of other types are not changed
+	assert.Contains(t, source, "\tGetUser(ctx context.Context, userId int, _err error) (data string, err error)\n")
+}
@@ -7,16 +7,22 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/unionj-cloud/go-doudou/astutils"
 	"github.com/unionj-cloud/go-doudou/copier"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
 	"go/ast"
 	"go/parser"
 	"go/token"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"text/template"
 )
 
+const (
+	svcimplTmplName        = "svc/svcimpl.go.tmpl"
+	svcimplMethodsTmplName = "svc/svcimpl_methods.go.tmpl"
+)
+
 var appendPart = `{{- range $m := .Meta.Methods }}
 	func (receiver *{{$.Meta.Name}}Impl) {{$m.Name}}({{- range $i, $p := $m.Params}}
     {{- if $i}},{{end}}
@@ -51,7 +57,7 @@ type {{.Meta.Name}}Impl struct {
 	conf *config.Config
 }
 
-` + appendPart + `
+{{- template "svc/svcimpl_methods.go.tmpl" . }}
 
 func New{{.Meta.Name}}(conf *config.Config, db *sqlx.DB) {{.Meta.Name}} {
 	return &{{.Meta.Name}}Impl{
@@ -60,7 +66,22 @@ func New{{.Meta.Name}}(conf *config.Config, db *sqlx.DB) {{.Meta.Name}} {
 }
 `
 
-// GenSvcImpl generates service implementation
+func init() {
+	templateutils.Register(svcimplTmplName, svcimplTmpl, `ConfigPackage string: import path of config package
+VoPackage string: import path of vo package
+SvcPackage string: package name of service package
+Meta astutils.InterfaceMeta: service interface
+Functions: toCamel
+Calls svc/svcimpl_methods.go.tmpl to generate methods`)
+	templateutils.Register(svcimplMethodsTmplName, appendPart, `ConfigPackage string: import path of config package
+VoPackage string: import path of vo package
+SvcPackage string: package name of service package
+Meta astutils.InterfaceMeta: service interface, only with missing methods when appending to existing svcimpl.go
+Functions: toCamel`)
+}
+
+// GenSvcImpl generates service implementation. If svcimpl.go exists, only missing methods will be appended,
+// and params and results of methods whose signatures have changed are updated with their bodies kept
 func GenSvcImpl(dir string, ic astutils.InterfaceCollector) {
 	var (
 		err         error
@@ -68,11 +89,12 @@ func GenSvcImpl(dir string, ic astutils.InterfaceCollector) {
 		modName     string
 		svcimplfile string
 		firstLine   string
-		f           *os.File
+		f           fileutils.File
 		tpl         *template.Template
 		buf         bytes.Buffer
 		meta        astutils.InterfaceMeta
 		tmpl        string
+		original    []byte
 	)
 	svcimplfile = filepath.Join(dir, "svcimpl.go")
 	err = copier.DeepCopy(ic.Interfaces[0], &meta)
@@ -80,7 +102,7 @@ func GenSvcImpl(dir string, ic astutils.InterfaceCollector) {
 		panic(err)
 	}
 	modfile = filepath.Join(dir, "go.mod")
-	if f, err = os.Open(modfile); err != nil {
+	if f, err = fileutils.Open(modfile); err != nil {
 		panic(err)
 	}
 	reader := bufio.NewReader(f)
@@ -88,22 +110,21 @@ func GenSvcImpl(dir string, ic astutils.InterfaceCollector) {
 		panic(err)
 	}
 	modName = strings.TrimSpace(strings.TrimPrefix(firstLine, "module"))
-	if _, err = os.Stat(svcimplfile); os.IsNotExist(err) {
-		if f, err = os.Create(svcimplfile); err != nil {
+	if _, err = fileutils.Stat(svcimplfile); os.IsNotExist(err) {
+		if f, err = fileutils.Create(svcimplfile); err != nil {
 			panic(err)
 		}
 		defer f.Close()
-		tmpl = svcimplTmpl
+		tmpl = svcimplTmplName
 	} else {
 		logrus.Warningln("New content will be append to file svcimpl.go")
-		if f, err = os.OpenFile(svcimplfile, os.O_APPEND, os.ModePerm); err != nil {
+		if original, err = fileutils.ReadFile(svcimplfile); err != nil {
 			panic(err)
 		}
-		defer f.Close()
-		tmpl = appendPart
+		tmpl = svcimplMethodsTmplName
 
 		fset := token.NewFileSet()
-		root, err := parser.ParseFile(fset, svcimplfile, nil, parser.ParseComments)
+		root, err := parser.ParseFile(fset, svcimplfile, original, parser.ParseComments)
 		if err != nil {
 			panic(err)
 		}
@@ -111,9 +132,15 @@ func GenSvcImpl(dir string, ic astutils.InterfaceCollector) {
 		ast.Walk(sc, root)
 		if implementations, exists := sc.Methods[meta.Name+"Impl"]; exists {
 			var notimplemented []astutils.MethodMeta
+			changed := make(map[string]astutils.MethodMeta)
 			for _, item := range meta.Methods {
 				for _, implemented := range implementations {
 					if item.Name == implemented.Name {
+						if !sameSignature(item, implemented) {
+							logrus.Warnf("signature of %s has changed to %s, updated in %s, please check its body",
+								item.Name, signatureOf(item), svcimplfile)
+							changed[item.Name] = item
+						}
 						goto L
 					}
 				}
@@ -123,14 +150,20 @@ func GenSvcImpl(dir string, ic astutils.InterfaceCollector) {
 			}
 
 			meta.Methods = notimplemented
+			original = replaceSignatures(original, meta.Name+"Impl", changed)
 		}
 	}
 
 	funcMap := make(map[string]interface{})
 	funcMap["toCamel"] = strcase.ToCamel
-	if tpl, err = template.New("svcimpl.go.tmpl").Funcs(funcMap).Parse(tmpl); err != nil {
+	if tpl, err = template.New(tmpl).Funcs(funcMap).Parse(templateutils.Lookup(tmpl)); err != nil {
 		panic(err)
 	}
+	if tpl.Lookup(svcimplMethodsTmplName) == nil {
+		if _, err = tpl.New(svcimplMethodsTmplName).Parse(templateutils.Lookup(svcimplMethodsTmplName)); err != nil {
+			panic(err)
+		}
+	}
 	if err = tpl.Execute(&buf, struct {
 		ConfigPackage string
 		VoPackage     string
@@ -145,11 +178,6 @@ func GenSvcImpl(dir string, ic astutils.InterfaceCollector) {
 		panic(err)
 	}
 
-	original, err := ioutil.ReadAll(f)
-	if err != nil {
-		panic(err)
-	}
-
 	original = append(original, buf.Bytes()...)
 	//fmt.Println(string(original))
 	astutils.FixImport(original, svcimplfile)
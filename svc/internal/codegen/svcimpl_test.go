@@ -1,7 +1,14 @@
 package codegen
 
 import (
+	"github.com/stretchr/testify/assert"
 	"github.com/unionj-cloud/go-doudou/astutils"
+	"github.com/unionj-cloud/go-doudou/templateutils"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -147,3 +154,89 @@ func (receiver *SvcimplappendImpl) GetUser(ctx context.Context, userId string, p
 		t.Errorf("want %s, got %s\n", expect, string(content))
 	}
 }
+
+func TestGenSvcImplWithOverriddenTemplate(t *testing.T) {
+	dir := testDir + "svcimpltmpl"
+	InitSvc(dir)
+	defer os.RemoveAll(dir)
+	tmplDir := filepath.Join(dir, ".go-doudou", "templates")
+	file := filepath.Join(tmplDir, "svc", "svcimpl_methods.go.tmpl")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(file), os.ModePerm))
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`{{- range $m := .Meta.Methods }}
+// {{$m.Name}} is generated by company template
+func (receiver *{{$.Meta.Name}}Impl) {{$m.Name}}() {}
+{{- end }}`), os.ModePerm))
+	templateutils.SetDir(tmplDir)
+	defer templateutils.SetDir(templateutils.DefaultDir)
+	ic := astutils.BuildInterfaceCollector(filepath.Join(dir, "svc.go"), astutils.ExprString)
+	GenSvcImpl(dir, ic)
+	content, err := ioutil.ReadFile(filepath.Join(dir, "svcimpl.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "// PageUsers is generated by company template\nfunc (receiver *TestdatasvcimpltmplImpl) PageUsers() {}")
+	assert.Contains(t, string(content), "func NewTestdatasvcimpltmpl(conf *config.Config, db *sqlx.DB) Testdatasvcimpltmpl {")
+}
+
+func TestGenSvcImplChangedSignature(t *testing.T) {
+	dir, err := ioutil.TempDir("", "svcimplchanged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module usersvc\n"), os.ModePerm))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "svc.go"), []byte(`package service
+
+import "context"
+
+type Usersvc interface {
+	SignUp(ctx context.Context, username string, password string, nickname string) (data string, err error)
+}
+`), os.ModePerm))
+	file := filepath.Join(dir, "svcimpl.go")
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`package service
+
+import "context"
+
+type UsersvcImpl struct{}
+
+// SignUp signs up a user
+func (receiver *UsersvcImpl) SignUp(ctx context.Context, username, password string) (data string, err error) {
+	return username + password, nil
+}
+`), os.ModePerm))
+	ic := astutils.BuildInterfaceCollector(filepath.Join(dir, "svc.go"), astutils.ExprString)
+	GenSvcImpl(dir, ic)
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `package service
+
+import "context"
+
+type UsersvcImpl struct{}
+
+// SignUp signs up a user
+func (receiver *UsersvcImpl) SignUp(ctx context.Context, username string, password string, nickname string) (data string, err error) {
+	return username + password, nil
+}
+`, string(content))
+
+	// *UsersvcImpl implements Usersvc again
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range []string{"svc.go", "svcimpl.go"} {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check("usersvc", fset, files, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	impl := types.NewPointer(pkg.Scope().Lookup("UsersvcImpl").Type())
+	iface := pkg.Scope().Lookup("Usersvc").Type().Underlying().(*types.Interface)
+	assert.True(t, types.Implements(impl, iface))
+}
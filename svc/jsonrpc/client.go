@@ -0,0 +1,67 @@
+package ddjsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+	"strconv"
+	"sync/atomic"
+)
+
+var lastID uint64
+
+// Call sends request of method with params to url by client, and decodes result member of response into result if it is not nil.
+// Error object in response is returned as *Error
+func Call(ctx context.Context, client *resty.Client, url, method string, params, result interface{}) error {
+	var resp Response
+	id := strconv.FormatUint(atomic.AddUint64(&lastID, 1), 10)
+	body, err := post(ctx, client, url, method, params, json.RawMessage(id))
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(body, &resp); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	raw, _ := resp.Result.(json.RawMessage)
+	if result == nil || len(raw) == 0 {
+		return nil
+	}
+	return errors.Wrap(json.Unmarshal(raw, result), "")
+}
+
+// Notify sends notification of method with params to url by client, server doesn't respond to notifications
+func Notify(ctx context.Context, client *resty.Client, url, method string, params interface{}) error {
+	_, err := post(ctx, client, url, method, params, nil)
+	return err
+}
+
+func post(ctx context.Context, client *resty.Client, url, method string, params interface{}, id json.RawMessage) ([]byte, error) {
+	req := Request{
+		JSONRPC: Version,
+		Method:  method,
+		ID:      id,
+	}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+		req.Params = data
+	}
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(req).
+		Post(url)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	if resp.IsError() {
+		return nil, errors.New(resp.String())
+	}
+	return resp.Body(), nil
+}
@@ -0,0 +1,39 @@
+package ddjsonrpc
+
+import (
+	"context"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCall(t *testing.T) {
+	var notified []string
+	ts := httptest.NewServer(newTestServer(&notified))
+	defer ts.Close()
+	client := resty.New().SetHostURL(ts.URL)
+
+	var sum int
+	assert.NoError(t, Call(context.Background(), client, Path, "Usersvc.Add", map[string]int{"a": 1, "b": 2}, &sum))
+	assert.Equal(t, 3, sum)
+
+	assert.NoError(t, Call(context.Background(), client, Path, "Usersvc.Add", []int{4, 5}, &sum))
+	assert.Equal(t, 9, sum)
+
+	err := Call(context.Background(), client, Path, "Usersvc.Custom", nil, nil)
+	assert.Equal(t, &Error{Code: 1001, Message: "custom", Data: "detail"}, err)
+
+	err = Call(context.Background(), client, Path, "Usersvc.Nope", nil, nil)
+	assert.Equal(t, CodeMethodNotFound, err.(*Error).Code)
+}
+
+func TestNotify(t *testing.T) {
+	var notified []string
+	ts := httptest.NewServer(newTestServer(&notified))
+	defer ts.Close()
+	client := resty.New().SetHostURL(ts.URL)
+
+	assert.NoError(t, Notify(context.Background(), client, Path, "Usersvc.Log", []string{"hello"}))
+	assert.Equal(t, []string{"hello"}, notified)
+}
@@ -0,0 +1,161 @@
+package ddjsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Version is value of jsonrpc member of every request and response
+const Version = "2.0"
+
+// Path is the single http endpoint serving json-rpc requests
+const Path = "/jsonrpc"
+
+// Error codes defined by json-rpc 2.0 specification
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	// CodeServerError is for errors returned by service implementation
+	CodeServerError = -32000
+)
+
+// Error is json-rpc error object. Service implementation can return it to control code and data of the error object
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+// NewError creates an Error
+func NewError(code int, message string) *Error {
+	return &Error{
+		Code:    code,
+		Message: message,
+	}
+}
+
+// Request is json-rpc request object. Request without id is a notification
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  Params          `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// IsNotification returns true if id member is absent
+func (r Request) IsNotification() bool {
+	return len(r.ID) == 0
+}
+
+// Response is json-rpc response object
+type Response struct {
+	JSONRPC string
+	Result  interface{}
+	Error   *Error
+	ID      json.RawMessage
+}
+
+// MarshalJSON encodes result member only if there is no error, as it must not exist on error but must exist on success
+func (r Response) MarshalJSON() ([]byte, error) {
+	id := r.ID
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	if r.Error != nil {
+		return json.Marshal(struct {
+			JSONRPC string          `json:"jsonrpc"`
+			Error   *Error          `json:"error"`
+			ID      json.RawMessage `json:"id"`
+		}{r.JSONRPC, r.Error, id})
+	}
+	return json.Marshal(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Result  interface{}     `json:"result"`
+		ID      json.RawMessage `json:"id"`
+	}{r.JSONRPC, r.Result, id})
+}
+
+// UnmarshalJSON decodes response object, result is kept as json.RawMessage
+func (r *Response) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Result  json.RawMessage `json:"result"`
+		Error   *Error          `json:"error"`
+		ID      json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	r.JSONRPC = raw.JSONRPC
+	r.Result = raw.Result
+	r.Error = raw.Error
+	r.ID = raw.ID
+	return nil
+}
+
+// Params is params member of request, either an array by-position or an object by-name
+type Params json.RawMessage
+
+// MarshalJSON returns p as json
+func (p Params) MarshalJSON() ([]byte, error) {
+	if len(p) == 0 {
+		return []byte("null"), nil
+	}
+	return p, nil
+}
+
+// UnmarshalJSON sets *p to a copy of data
+func (p *Params) UnmarshalJSON(data []byte) error {
+	*p = append((*p)[0:0], data...)
+	return nil
+}
+
+// Bind decodes params into dest. By-position params are decoded into dest in order,
+// by-name params are decoded into dest whose name at the same index of names matches.
+// Missing params leave dest untouched. It returns Error with CodeInvalidParams on failure.
+func (p Params) Bind(names []string, dest ...interface{}) error {
+	data := bytes.TrimSpace(p)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	switch data[0] {
+	case '[':
+		var values []json.RawMessage
+		if err := json.Unmarshal(data, &values); err != nil {
+			return NewError(CodeInvalidParams, err.Error())
+		}
+		if len(values) > len(dest) {
+			return NewError(CodeInvalidParams, fmt.Sprintf("expect at most %d params, got %d", len(dest), len(values)))
+		}
+		for i, value := range values {
+			if err := json.Unmarshal(value, dest[i]); err != nil {
+				return NewError(CodeInvalidParams, fmt.Sprintf("param %d: %s", i, err))
+			}
+		}
+	case '{':
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(data, &values); err != nil {
+			return NewError(CodeInvalidParams, err.Error())
+		}
+		for i, name := range names {
+			value, ok := values[name]
+			if !ok {
+				continue
+			}
+			if err := json.Unmarshal(value, dest[i]); err != nil {
+				return NewError(CodeInvalidParams, fmt.Sprintf("param %s: %s", name, err))
+			}
+		}
+	default:
+		return NewError(CodeInvalidParams, "params must be an array or an object")
+	}
+	return nil
+}
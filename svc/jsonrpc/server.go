@@ -0,0 +1,142 @@
+package ddjsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/unionj-cloud/go-doudou/svc/logger"
+	"io/ioutil"
+	"net/http"
+)
+
+// Method handles a json-rpc method call. Returned result is encoded as result member of response
+type Method func(ctx context.Context, params Params) (interface{}, error)
+
+// Server dispatches json-rpc requests to registered methods
+type Server struct {
+	methods map[string]Method
+}
+
+// NewServer creates a Server
+func NewServer() *Server {
+	return &Server{
+		methods: make(map[string]Method),
+	}
+}
+
+// Register registers fn as method name, such as Usersvc.GetUser
+func (srv *Server) Register(name string, fn Method) {
+	srv.methods[name] = fn
+}
+
+// ServeHTTP serves single and batch requests from http post body.
+// It responds 204 No Content if there is nothing to return, such as all requests are notifications
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	var resp interface{}
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		resp = srv.handleBatch(r.Context(), body)
+	} else if ret := srv.handle(r.Context(), body); ret != nil {
+		resp = ret
+	}
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(resp); err != nil {
+		logger.Errorln(fmt.Sprintf("%+v", errors.Wrap(err, "failed to write json-rpc response")))
+	}
+}
+
+func (srv *Server) handleBatch(ctx context.Context, body []byte) interface{} {
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return errorResponse(nil, NewError(CodeParseError, err.Error()))
+	}
+	if len(items) == 0 {
+		return errorResponse(nil, NewError(CodeInvalidRequest, "empty batch"))
+	}
+	var responses []*Response
+	for _, item := range items {
+		if ret := srv.handle(ctx, item); ret != nil {
+			responses = append(responses, ret)
+		}
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+	return responses
+}
+
+// handle calls method of a single request, it returns nil for notification
+func (srv *Server) handle(ctx context.Context, data []byte) *Response {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return errorResponse(nil, NewError(CodeParseError, err.Error()))
+		}
+		return errorResponse(nil, NewError(CodeInvalidRequest, err.Error()))
+	}
+	if req.JSONRPC != Version || req.Method == "" {
+		return errorResponse(req.ID, NewError(CodeInvalidRequest, `jsonrpc must be "2.0" and method must not be empty`))
+	}
+	fn, ok := srv.methods[req.Method]
+	if !ok {
+		if req.IsNotification() {
+			return nil
+		}
+		return errorResponse(req.ID, NewError(CodeMethodNotFound, fmt.Sprintf("method %s not found", req.Method)))
+	}
+	result, err := call(ctx, fn, req.Params)
+	if req.IsNotification() {
+		if err != nil {
+			logger.Errorln(fmt.Sprintf("json-rpc notification %s failed: %+v", req.Method, err))
+		}
+		return nil
+	}
+	if err != nil {
+		return errorResponse(req.ID, toError(err))
+	}
+	return &Response{
+		JSONRPC: Version,
+		Result:  result,
+		ID:      req.ID,
+	}
+}
+
+// call recovers panic of fn as internal error, so one failed request doesn't break others in the same batch
+func call(ctx context.Context, fn Method, params Params) (result interface{}, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			logger.Errorln(fmt.Sprintf("json-rpc method panic: %+v", e))
+			result, err = nil, NewError(CodeInternalError, fmt.Sprint(e))
+		}
+	}()
+	return fn(ctx, params)
+}
+
+// toError maps err to json-rpc error object. *Error is kept, other errors get CodeServerError
+func toError(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	return NewError(CodeServerError, err.Error())
+}
+
+func errorResponse(id json.RawMessage, err *Error) *Response {
+	return &Response{
+		JSONRPC: Version,
+		Error:   err,
+		ID:      id,
+	}
+}
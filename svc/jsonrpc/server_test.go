@@ -0,0 +1,98 @@
+package ddjsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestServer(notified *[]string) *Server {
+	srv := NewServer()
+	srv.Register("Usersvc.Add", func(ctx context.Context, params Params) (interface{}, error) {
+		var a, b int
+		if err := params.Bind([]string{"a", "b"}, &a, &b); err != nil {
+			return nil, err
+		}
+		return a + b, nil
+	})
+	srv.Register("Usersvc.Fail", func(ctx context.Context, params Params) (interface{}, error) {
+		return nil, errors.New("user not found")
+	})
+	srv.Register("Usersvc.Custom", func(ctx context.Context, params Params) (interface{}, error) {
+		return nil, &Error{Code: 1001, Message: "custom", Data: "detail"}
+	})
+	srv.Register("Usersvc.Panic", func(ctx context.Context, params Params) (interface{}, error) {
+		panic("boom")
+	})
+	srv.Register("Usersvc.Log", func(ctx context.Context, params Params) (interface{}, error) {
+		var msg string
+		if err := params.Bind([]string{"msg"}, &msg); err != nil {
+			return nil, err
+		}
+		*notified = append(*notified, msg)
+		return nil, nil
+	})
+	return srv
+}
+
+func serve(srv *Server, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, Path, strings.NewReader(body)))
+	return w
+}
+
+func TestServer_ServeHTTP(t *testing.T) {
+	var notified []string
+	srv := newTestServer(&notified)
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"positional", `{"jsonrpc":"2.0","method":"Usersvc.Add","params":[1,2],"id":1}`, `{"jsonrpc":"2.0","result":3,"id":1}`},
+		{"named", `{"jsonrpc":"2.0","method":"Usersvc.Add","params":{"b":5,"a":1},"id":"x"}`, `{"jsonrpc":"2.0","result":6,"id":"x"}`},
+		{"null result", `{"jsonrpc":"2.0","method":"Usersvc.Log","params":["hi"],"id":2}`, `{"jsonrpc":"2.0","result":null,"id":2}`},
+		{"invalid params", `{"jsonrpc":"2.0","method":"Usersvc.Add","params":["1"],"id":3}`, `{"jsonrpc":"2.0","error":{"code":-32602,"message":"param 0: json: cannot unmarshal string into Go value of type int"},"id":3}`},
+		{"too many params", `{"jsonrpc":"2.0","method":"Usersvc.Add","params":[1,2,3],"id":3}`, `{"jsonrpc":"2.0","error":{"code":-32602,"message":"expect at most 2 params, got 3"},"id":3}`},
+		{"method not found", `{"jsonrpc":"2.0","method":"Usersvc.Nope","id":4}`, `{"jsonrpc":"2.0","error":{"code":-32601,"message":"method Usersvc.Nope not found"},"id":4}`},
+		{"service error", `{"jsonrpc":"2.0","method":"Usersvc.Fail","id":5}`, `{"jsonrpc":"2.0","error":{"code":-32000,"message":"user not found"},"id":5}`},
+		{"custom error", `{"jsonrpc":"2.0","method":"Usersvc.Custom","id":6}`, `{"jsonrpc":"2.0","error":{"code":1001,"message":"custom","data":"detail"},"id":6}`},
+		{"panic", `{"jsonrpc":"2.0","method":"Usersvc.Panic","id":7}`, `{"jsonrpc":"2.0","error":{"code":-32603,"message":"boom"},"id":7}`},
+		{"parse error", `{"jsonrpc":"2.0","method"`, `{"jsonrpc":"2.0","error":{"code":-32700,"message":"unexpected end of JSON input"},"id":null}`},
+		{"invalid request", `{"jsonrpc":"1.0","method":"Usersvc.Add","id":8}`, `{"jsonrpc":"2.0","error":{"code":-32600,"message":"jsonrpc must be \"2.0\" and method must not be empty"},"id":8}`},
+		{"empty batch", `[]`, `{"jsonrpc":"2.0","error":{"code":-32600,"message":"empty batch"},"id":null}`},
+		{"batch", `[{"jsonrpc":"2.0","method":"Usersvc.Add","params":[1,1],"id":1},{"jsonrpc":"2.0","method":"Usersvc.Log","params":{"msg":"batch"}},1]`,
+			`[{"jsonrpc":"2.0","result":2,"id":1},{"jsonrpc":"2.0","error":{"code":-32600,"message":"json: cannot unmarshal number into Go value of type ddjsonrpc.Request"},"id":null}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(srv, tt.body)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, tt.want, w.Body.String())
+		})
+	}
+	assert.Equal(t, []string{"hi", "batch"}, notified)
+}
+
+func TestServer_ServeHTTPNotification(t *testing.T) {
+	var notified []string
+	srv := newTestServer(&notified)
+	w := serve(srv, `{"jsonrpc":"2.0","method":"Usersvc.Log","params":{"msg":"single"}}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	w = serve(srv, `[{"jsonrpc":"2.0","method":"Usersvc.Log","params":["a"]},{"jsonrpc":"2.0","method":"Usersvc.Nope"}]`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, []string{"single", "a"}, notified)
+}
+
+func TestResponse_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(Response{JSONRPC: Version, ID: json.RawMessage("1")})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","result":null,"id":1}`, string(data))
+}
@@ -0,0 +1,67 @@
+package plugin
+
+import (
+	"encoding/json"
+	"github.com/unionj-cloud/go-doudou/astutils"
+	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
+	"io"
+	"os"
+)
+
+// Prefix is prefix of plugin executable name. Plugin named foo is executable go-doudou-gen-foo found in PATH
+const Prefix = "go-doudou-gen-"
+
+// Request is sent to plugin executable as json through stdin
+type Request struct {
+	// Module is module name in go.mod of the service
+	Module string `json:"module"`
+	// InterfaceCollector holds service interface parsed from svc.go
+	InterfaceCollector astutils.InterfaceCollector `json:"interfaceCollector"`
+	// Vos holds structs parsed from vo package
+	Vos []astutils.StructMeta `json:"vos"`
+	// API is OpenAPI 3.0 description of the service
+	API v3.API `json:"api"`
+}
+
+// File is a file to write
+type File struct {
+	// Path is relative to service root directory. Absolute path and path out of service root directory are not allowed
+	Path string `json:"path"`
+	// Content of the file. Existing file will be overwritten
+	Content string `json:"content"`
+}
+
+// Response is received from plugin executable as json through stdout
+type Response struct {
+	Files []File `json:"files"`
+	// Error fails code generation if not empty
+	Error string `json:"error,omitempty"`
+}
+
+// Generator generates files from request
+type Generator func(req Request) ([]File, error)
+
+// Serve reads Request from r, calls gen and writes Response to w. It helps writing plugins in go.
+func Serve(r io.Reader, w io.Writer, gen Generator) error {
+	var (
+		req  Request
+		resp Response
+		err  error
+	)
+	if err = json.NewDecoder(r).Decode(&req); err != nil {
+		return err
+	}
+	if resp.Files, err = gen(req); err != nil {
+		resp.Files = nil
+		resp.Error = err.Error()
+	}
+	return json.NewEncoder(w).Encode(resp)
+}
+
+// Main is for main function of plugin executable written in go. It serves request from stdin and exits with non-zero code on failure.
+func Main(gen Generator) {
+	if err := Serve(os.Stdin, os.Stdout, gen); err != nil {
+		_, _ = os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,50 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	in := strings.NewReader(`{"module":"usersvc","interfaceCollector":{"Interfaces":[{"Name":"Usersvc","Methods":[{"Name":"GetUser"}]}]}}`)
+	var out bytes.Buffer
+	err := Serve(in, &out, func(req Request) ([]File, error) {
+		var files []File
+		for _, m := range req.InterfaceCollector.Interfaces[0].Methods {
+			files = append(files, File{
+				Path:    "sdk/" + m.Name + ".txt",
+				Content: req.Module,
+			})
+		}
+		return files, nil
+	})
+	assert.NoError(t, err)
+	var resp Response
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &resp))
+	assert.Equal(t, []File{{Path: "sdk/GetUser.txt", Content: "usersvc"}}, resp.Files)
+	assert.Empty(t, resp.Error)
+}
+
+func TestServeError(t *testing.T) {
+	var out bytes.Buffer
+	err := Serve(strings.NewReader(`{}`), &out, func(req Request) ([]File, error) {
+		return []File{{Path: "a.txt"}}, errors.New("no service interface")
+	})
+	assert.NoError(t, err)
+	var resp Response
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &resp))
+	assert.Empty(t, resp.Files)
+	assert.Equal(t, "no service interface", resp.Error)
+}
+
+func TestServeInvalidRequest(t *testing.T) {
+	var out bytes.Buffer
+	err := Serve(strings.NewReader(`not json`), &out, func(req Request) ([]File, error) {
+		return nil, nil
+	})
+	assert.Error(t, err)
+}
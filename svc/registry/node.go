@@ -24,6 +24,7 @@ import (
 var mlist *memberlist.Memberlist
 var BroadcastQueue *memberlist.TransmitLimitedQueue
 var events = &eventDelegate{}
+var localDelegate *delegate
 
 type mergedMeta struct {
 	Meta nodeMeta               `json:"_meta,omitempty"`
@@ -77,12 +78,15 @@ type nodeMeta struct {
 	Service       string     `json:"service"`
 	RouteRootPath string     `json:"routeRootPath"`
 	Port          int        `json:"port"`
+	GrpcPort      int        `json:"grpcPort,omitempty"`
 	RegisterAt    *time.Time `json:"registerAt"`
 	GoVer         string     `json:"goVer"`
 	GddVer        string     `json:"gddVer"`
 	BuildUser     string     `json:"buildUser"`
 	BuildTime     string     `json:"buildTime"`
 	Weight        int        `json:"weight"`
+	Version       string     `json:"version,omitempty"`
+	Zone          string     `json:"zone,omitempty"`
 }
 
 func newMeta(node *memberlist.Node) (mergedMeta, error) {
@@ -281,12 +285,15 @@ func NewNode(data ...map[string]interface{}) error {
 			Service:       service,
 			RouteRootPath: config.GddRouteRootPath.Load(),
 			Port:          port,
+			GrpcPort:      cast.ToInt(config.GddGrpcPort.Load()),
 			RegisterAt:    &now,
 			GoVer:         runtime.Version(),
 			GddVer:        config.GddVer,
 			BuildUser:     config.BuildUser,
 			BuildTime:     buildTime,
 			Weight:        cast.ToInt(config.GddMemWeight.Load()),
+			Version:       config.GddServiceVersion.Load(),
+			Zone:          config.GddZone.Load(),
 		},
 		Data: make(map[string]interface{}),
 	}
@@ -303,10 +310,11 @@ func NewNode(data ...map[string]interface{}) error {
 		RetransmitMult: mconf.RetransmitMult,
 	}
 	BroadcastQueue = queue
-	mconf.Delegate = &delegate{
+	localDelegate = &delegate{
 		mmeta: mmeta,
 		queue: queue,
 	}
+	mconf.Delegate = localDelegate
 	mconf.Events = events
 	var err error
 	if mlist, err = memberlist.Create(mconf); err != nil {
@@ -332,6 +340,21 @@ func Shutdown() {
 	}
 }
 
+// SetGrpcPort records port which grpc server is listening on into GDD_GRPC_PORT, so nodes created later register it,
+// and updates meta of local node if it has been created already, so grpc server can be started before or after NewNode
+func SetGrpcPort(port int) error {
+	if err := config.GddGrpcPort.Write(fmt.Sprint(port)); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if mlist == nil || localDelegate == nil {
+		return nil
+	}
+	localDelegate.lock.Lock()
+	localDelegate.mmeta.Meta.GrpcPort = port
+	localDelegate.lock.Unlock()
+	return errors.Wrap(mlist.UpdateNode(10*time.Second), "SetGrpcPort() error: Failed to update node meta")
+}
+
 // NodeInfo wraps node information
 type NodeInfo struct {
 	SvcName   string                 `json:"svcName"`
@@ -343,9 +366,12 @@ type NodeInfo struct {
 	GddVer    string                 `json:"gddVer"`
 	BuildUser string                 `json:"buildUser"`
 	BuildTime string                 `json:"buildTime"`
+	Version   string                 `json:"version"`
+	Zone      string                 `json:"zone"`
 	Data      map[string]interface{} `json:"data"`
 	Host      string                 `json:"host"`
 	SvcPort   int                    `json:"svcPort"`
+	GrpcPort  int                    `json:"grpcPort,omitempty"`
 	MemPort   int                    `json:"memPort"`
 }
 
@@ -374,9 +400,12 @@ func Info(node *memberlist.Node) NodeInfo {
 		GddVer:    meta.Meta.GddVer,
 		BuildUser: meta.Meta.BuildUser,
 		BuildTime: meta.Meta.BuildTime,
+		Version:   meta.Meta.Version,
+		Zone:      meta.Meta.Zone,
 		Data:      meta.Data,
 		Host:      node.Addr,
 		SvcPort:   meta.Meta.Port,
+		GrpcPort:  meta.Meta.GrpcPort,
 		MemPort:   int(node.Port),
 	}
 }
@@ -392,6 +421,21 @@ func BaseUrl(node *memberlist.Node) (string, error) {
 	return fmt.Sprintf("http://%s:%d%s", node.Addr, mm.Meta.Port, mm.Meta.RouteRootPath), nil
 }
 
+// GrpcAddr returns host:port address of grpc server of the node. It returns error if the node doesn't serve grpc
+func GrpcAddr(node *memberlist.Node) (string, error) {
+	var (
+		mm  mergedMeta
+		err error
+	)
+	if mm, err = newMeta(node); err != nil {
+		return "", err
+	}
+	if mm.Meta.GrpcPort == 0 {
+		return "", errors.Errorf("node %s doesn't serve grpc", node.Name)
+	}
+	return fmt.Sprintf("%s:%d", node.Addr, mm.Meta.GrpcPort), nil
+}
+
 func MetaWeight(node *memberlist.Node) (int, error) {
 	var (
 		mm  mergedMeta
@@ -4,6 +4,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/unionj-cloud/go-doudou/svc/config"
+	"github.com/unionj-cloud/memberlist"
 	"reflect"
 	"testing"
 )
@@ -147,6 +148,40 @@ func TestInfo(t *testing.T) {
 	assert.NotZero(t, info)
 }
 
+func TestGrpcAddr(t *testing.T) {
+	setup()
+	_, err := GrpcAddr(&memberlist.Node{Name: "test00", Meta: []byte(`{"_meta":{"port":6060}}`)})
+	assert.Error(t, err)
+	_ = config.GddGrpcPort.Write("50051")
+	defer config.GddGrpcPort.Write("")
+	err = NewNode()
+	if err != nil {
+		panic(err)
+	}
+	defer mlist.Shutdown()
+	addr, err := GrpcAddr(LocalNode())
+	assert.NoError(t, err)
+	assert.Equal(t, LocalNode().Addr+":50051", addr)
+	assert.Equal(t, 50051, Info(LocalNode()).GrpcPort)
+}
+
+func TestSetGrpcPort(t *testing.T) {
+	setup()
+	defer config.GddGrpcPort.Write("")
+	err := NewNode()
+	if err != nil {
+		panic(err)
+	}
+	defer mlist.Shutdown()
+	_, err = GrpcAddr(LocalNode())
+	assert.Error(t, err)
+	assert.NoError(t, SetGrpcPort(50052))
+	assert.Equal(t, "50052", config.GddGrpcPort.Load())
+	addr, err := GrpcAddr(LocalNode())
+	assert.NoError(t, err)
+	assert.Equal(t, LocalNode().Addr+":50052", addr)
+}
+
 func TestMetaWeight(t *testing.T) {
 	setup()
 	err := NewNode()
@@ -2,6 +2,7 @@ package registry
 
 import (
 	"github.com/unionj-cloud/memberlist"
+	"time"
 )
 
 // IServiceProvider defines service provider interface for server discovery
@@ -11,3 +12,22 @@ type IServiceProvider interface {
 	UpdateWeight(node *memberlist.Node)
 	RemoveNode(node *memberlist.Node)
 }
+
+// RequestOutcome is outcome of a request sent to a server selected by service provider
+type RequestOutcome struct {
+	// URL is the full url of the request
+	URL string
+	// Latency is the duration from sending request to receiving response header or error
+	Latency time.Duration
+	// StatusCode is zero if Err is not nil
+	StatusCode int
+	Err        error
+}
+
+// IFeedbackServiceProvider defines service provider interface which receives outcome of requests
+// sent to the selected servers, so that it can select server by latency, load or failures
+type IFeedbackServiceProvider interface {
+	IServiceProvider
+	RequestStarted(url string)
+	RequestDone(outcome RequestOutcome)
+}
@@ -9,13 +9,21 @@ import (
 	"github.com/unionj-cloud/go-doudou/astutils"
 	"github.com/unionj-cloud/go-doudou/constants"
 	"github.com/unionj-cloud/go-doudou/executils"
+	"github.com/unionj-cloud/go-doudou/fileutils"
 	v3 "github.com/unionj-cloud/go-doudou/openapi/v3"
 	"github.com/unionj-cloud/go-doudou/openapi/v3/codegen/client"
+	"github.com/unionj-cloud/go-doudou/openapi/v3/codegen/doc"
+	"github.com/unionj-cloud/go-doudou/openapi/v3/mock"
 	"github.com/unionj-cloud/go-doudou/stringutils"
+	"github.com/unionj-cloud/go-doudou/svc/config"
+	ddhttp "github.com/unionj-cloud/go-doudou/svc/http"
 	"github.com/unionj-cloud/go-doudou/svc/internal/codegen"
+	"github.com/unionj-cloud/go-doudou/svc/registry"
+	"net/url"
 	"os"
 	"os/exec"
 	"os/user"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -35,7 +43,13 @@ type Svc struct {
 	Client       string
 	Omitempty    bool
 	Doc          bool
+	Postman      bool
+	GenMock      bool
+	GenTest      bool
+	Force        bool
+	Jsonrpc      bool
 	Jsonattrcase string
+	Plugins      []string
 
 	DocPath string
 
@@ -78,6 +92,9 @@ func (receiver Svc) Http() {
 	codegen.GenHttpMiddleware(dir)
 
 	codegen.GenMain(dir, ic)
+	if receiver.Force {
+		receiver.removeGenerated(dir)
+	}
 	codegen.GenHttpHandler(dir, ic, receiver.RoutePatternStrategy)
 	if receiver.Handler {
 		var caseconvertor func(string) string
@@ -102,6 +119,85 @@ func (receiver Svc) Http() {
 	if receiver.Doc {
 		codegen.GenDoc(dir, ic, receiver.RoutePatternStrategy)
 	}
+	if receiver.Postman {
+		codegen.GenPostman(dir, ic, receiver.RoutePatternStrategy)
+	}
+	if receiver.GenMock {
+		codegen.GenGoMock(dir, ic)
+	}
+	if receiver.GenTest {
+		codegen.GenHttpHandlerTest(dir, ic, receiver.RoutePatternStrategy)
+	}
+	if receiver.Jsonrpc {
+		codegen.GenJsonrpc(dir, ic, receiver.Env)
+		logrus.Infoln("please mount json-rpc endpoint in cmd/main.go by srv.AddRoute(jsonrpc.Routes(svc)...)")
+	}
+	for _, name := range receiver.Plugins {
+		codegen.GenPlugin(dir, ic, name, receiver.RoutePatternStrategy)
+	}
+}
+
+// Grpc generates .proto file from svc.go and vo package, then go code of messages and grpc service by protoc if it
+// is found in PATH, and grpc server adapter calling the same service implementation as http handlers into transport/grpcsrv.
+// It returns error if any type used by service methods is not supported by grpc
+func (receiver Svc) Grpc() error {
+	dir := receiver.dir
+	ic := astutils.BuildInterfaceCollector(filepath.Join(dir, "svc.go"), astutils.ExprString)
+	if len(ic.Interfaces) == 0 {
+		return errors.New("no service interface found")
+	}
+	protofile, err := codegen.GenGrpcProto(dir, ic)
+	if err != nil {
+		return err
+	}
+	if err = codegen.GenGrpcSrv(dir, ic); err != nil {
+		return err
+	}
+	codegen.GenGrpcEnv(dir)
+
+	data, err := fileutils.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	modName := strings.TrimSpace(strings.TrimPrefix(strings.SplitN(string(data), "\n", 2)[0], "module"))
+	out := dir
+	if stringutils.IsEmpty(out) {
+		out = "."
+	}
+	args := []string{"--proto_path=" + filepath.Dir(protofile),
+		"--go_out=" + out, "--go_opt=module=" + modName,
+		"--go-grpc_out=" + out, "--go-grpc_opt=module=" + modName,
+		protofile}
+	if _, err = exec.LookPath("protoc"); err != nil || fileutils.IsDryRun() {
+		logrus.Warnln("protoc is not run, please run below command to generate go code of messages and grpc service")
+		logrus.Warnln("protoc " + strings.Join(args, " "))
+	} else if err = receiver.runner.Run("protoc", args...); err != nil {
+		return errors.WithStack(err)
+	}
+	logrus.Infoln("please register grpc server adapter in cmd/main.go by grpcsrv.Register(grpcSrv.Server, svc) " +
+		"where grpcSrv := ddgrpc.NewGrpcSrv(), then call grpcSrv.Run() and defer grpcSrv.GracefulStop()")
+	return nil
+}
+
+// removeGenerated removes generated files which are appended to rather than overwritten by default, so that they
+// will be regenerated from scratch. svcimpl.go is never removed because it holds user code.
+func (receiver Svc) removeGenerated(dir string) {
+	files := []string{filepath.Join(dir, "transport", "httpsrv", "handlerimpl.go")}
+	if receiver.Client == "go" {
+		files = append(files, filepath.Join(dir, "client", "clientproxy.go"))
+	}
+	if receiver.GenTest {
+		files = append(files, filepath.Join(dir, "transport", "httpsrv", "handler_test.go"))
+	}
+	for _, file := range files {
+		if _, err := fileutils.Stat(file); err != nil {
+			continue
+		}
+		logrus.Warningf("file %s will be regenerated from scratch", file)
+		if err := fileutils.Remove(file); err != nil {
+			panic(err)
+		}
+	}
 }
 
 // validateRestApi is checking whether parameter types in each of service interface methods valid or not
@@ -291,8 +387,8 @@ func (receiver Svc) Shutdown(k8sfile string) {
 	}
 }
 
-// GenClient generates http client code from OpenAPI3.0 description json file, only support Golang currently.
-func (receiver Svc) GenClient() {
+// docPath returns DocPath property if set, otherwise the first *_openapi3.json file found in project root
+func (receiver Svc) docPath() string {
 	docpath := receiver.DocPath
 	if stringutils.IsEmpty(docpath) {
 		matches, _ := filepath.Glob(filepath.Join(receiver.dir, "*_openapi3.json"))
@@ -303,11 +399,40 @@ func (receiver Svc) GenClient() {
 	if stringutils.IsEmpty(docpath) {
 		panic("openapi 3.0 spec json file path is empty")
 	}
+	return docpath
+}
+
+// GenClient generates http client code from OpenAPI3.0 description json file, only support Golang currently.
+func (receiver Svc) GenClient() {
+	docpath := receiver.docPath()
 	if receiver.Client == "go" {
 		client.GenGoClient(receiver.dir, docpath, receiver.Omitempty, receiver.Env, receiver.ClientPkg)
 	}
 }
 
+// GenStaticDoc renders OpenAPI3.0 description json file as static documentation in html or markdown format.
+// If output is empty, the documentation will be written to the same directory as the json file with .html or .md extension.
+// If the json file is a download link, the documentation will be written to the service directory named after the last
+// element of url path, e.g. openapi.html for https://petstore3.swagger.io/api/v3/openapi.json.
+func (receiver Svc) GenStaticDoc(format, output string) {
+	docpath := receiver.docPath()
+	if stringutils.IsEmpty(output) {
+		ext := ".md"
+		if doc.Format(format) == doc.HTML {
+			ext = ".html"
+		}
+		dir, base := filepath.Dir(docpath), filepath.Base(docpath)
+		if strings.HasPrefix(docpath, "http") {
+			dir, base = receiver.dir, "openapi3"
+			if u, err := url.Parse(docpath); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+				base = path.Base(u.Path)
+			}
+		}
+		output = filepath.Join(dir, strings.TrimSuffix(base, filepath.Ext(base))+ext)
+	}
+	doc.GenDoc(docpath, doc.Format(format), output)
+}
+
 func (receiver Svc) run() *exec.Cmd {
 	err := receiver.runner.Run("go", "build", filepath.FromSlash("cmd/main.go"))
 	if err != nil {
@@ -430,3 +555,23 @@ func (receiver Svc) Run(watch bool) {
 		}
 	}
 }
+
+// Mock starts a mock server from OpenAPI3.0 description json file. Every path in the document is served
+// with example values or data synthesized from schemas, and requests are validated against parameters and request bodies.
+// If register is true, the mock server will join memberlist cluster under the service name, so real clients can discover it.
+func (receiver Svc) Mock(register bool) {
+	api := v3.LoadAPI(receiver.docPath())
+	if register {
+		if stringutils.IsEmpty(config.GddServiceName.Load()) {
+			_ = config.GddServiceName.Write(mock.ServiceName(api))
+		}
+		if err := registry.NewNode(); err != nil {
+			logrus.Panicln(fmt.Sprintf("%+v", err))
+		}
+		defer registry.Shutdown()
+	}
+	srv := ddhttp.NewDefaultHttpSrv()
+	srv.AddMiddleware(ddhttp.Metrics, ddhttp.Logger, ddhttp.Rest, ddhttp.Recover)
+	srv.AddRoute(mock.Routes(api)...)
+	srv.Run()
+}
@@ -7,6 +7,9 @@ import (
 	"github.com/unionj-cloud/go-doudou/astutils"
 	"github.com/unionj-cloud/go-doudou/executils"
 	"github.com/unionj-cloud/go-doudou/pathutils"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -53,6 +56,7 @@ func TestSvc_Http(t *testing.T) {
 		Client       string
 		Omitempty    bool
 		Doc          bool
+		Jsonrpc      bool
 		Jsonattrcase string
 	}
 	tests := []struct {
@@ -78,6 +82,7 @@ func TestSvc_Http(t *testing.T) {
 				Client:    "go",
 				Omitempty: false,
 				Doc:       false,
+				Jsonrpc:   true,
 			},
 		},
 		{
@@ -99,6 +104,7 @@ func TestSvc_Http(t *testing.T) {
 				Client:       tt.fields.Client,
 				Omitempty:    tt.fields.Omitempty,
 				Doc:          tt.fields.Doc,
+				Jsonrpc:      tt.fields.Jsonrpc,
 				Jsonattrcase: tt.fields.Jsonattrcase,
 			}
 			assert.NotPanics(t, func() {
@@ -284,6 +290,16 @@ func TestSvc_Shutdown(t *testing.T) {
 	receiver.Shutdown("")
 }
 
+func TestSvc_Grpc(t *testing.T) {
+	dir := testDir + "grpc"
+	receiver := NewMockSvc(dir)
+	receiver.Init()
+	defer os.RemoveAll(dir)
+	assert.NoError(t, receiver.Grpc())
+	assert.FileExists(t, filepath.Join(dir, "transport", "grpcsrv", "testdatagrpc.proto"))
+	assert.FileExists(t, filepath.Join(dir, "transport", "grpcsrv", "handler.go"))
+}
+
 func Test_validateDataType(t *testing.T) {
 	assert.NotPanics(t, func() {
 		validateDataType(testDir)
@@ -296,6 +312,22 @@ func Test_validateDataType_shouldpanic(t *testing.T) {
 	})
 }
 
+func TestSvc_removeGenerated(t *testing.T) {
+	dir := testDir + "removegenerated"
+	defer os.RemoveAll(dir)
+	handlerimpl := filepath.Join(dir, "transport", "httpsrv", "handlerimpl.go")
+	clientproxy := filepath.Join(dir, "client", "clientproxy.go")
+	svcimpl := filepath.Join(dir, "svcimpl.go")
+	for _, file := range []string{handlerimpl, clientproxy, svcimpl} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(file), os.ModePerm))
+		assert.NoError(t, ioutil.WriteFile(file, []byte("package test"), os.ModePerm))
+	}
+	Svc{}.removeGenerated(dir)
+	assert.NoFileExists(t, handlerimpl)
+	assert.FileExists(t, clientproxy)
+	assert.FileExists(t, svcimpl)
+}
+
 func Test_GenClient(t *testing.T) {
 	defer os.RemoveAll(filepath.Join(testDir, "client"))
 	s := Svc{
@@ -441,6 +473,50 @@ func TestSvc_GenClient_DocPathEmpty1(t *testing.T) {
 	}
 }
 
+func TestSvc_GenStaticDoc(t *testing.T) {
+	receiver := Svc{
+		dir: filepath.Join("testdata", "openapi"),
+	}
+	output := filepath.Join("testdata", "openapi", "testfilesdoc1_openapi3.html")
+	defer os.Remove(output)
+	assert.NotPanics(t, func() {
+		receiver.GenStaticDoc("html", "")
+	})
+	_, err := os.Stat(output)
+	assert.NoError(t, err)
+}
+
+func TestSvc_GenStaticDoc_URL(t *testing.T) {
+	ts := httptest.NewServer(http.FileServer(http.Dir(filepath.Join("testdata", "openapi"))))
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "staticdoc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		name    string
+		docPath string
+		want    string
+	}{
+		{"url", ts.URL + "/testfilesdoc1_openapi3.json", "testfilesdoc1_openapi3.md"},
+		{"url with query", ts.URL + "/testfilesdoc1_openapi3.json?version=1", "testfilesdoc1_openapi3.md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := Svc{
+				dir:     dir,
+				DocPath: tt.docPath,
+			}
+			assert.NotPanics(t, func() {
+				receiver.GenStaticDoc("markdown", "")
+			})
+			_, err := os.Stat(filepath.Join(dir, tt.want))
+			assert.NoError(t, err)
+		})
+	}
+}
+
 func TestNewSvc(t *testing.T) {
 	assert.NotPanics(t, func() {
 		NewSvc("")
@@ -0,0 +1,136 @@
+package templateutils
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/unionj-cloud/go-doudou/fileutils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultDir is the default directory relative to project root where overriding templates are looked up
+var DefaultDir = filepath.Join(".go-doudou", "templates")
+
+// Template is a default template used by code generators
+type Template struct {
+	// Name is also path of the overriding template file relative to template directory, such as svc/main.go.tmpl
+	Name string
+	// Text is the default content
+	Text string
+	// Data describes data passed to the template and functions available in it
+	Data string
+}
+
+var (
+	lock      sync.RWMutex
+	templates = make(map[string]Template)
+	dir       = DefaultDir
+)
+
+// Register registers default text of template name with description of its data. It panics if name is registered
+// twice because template names are used as file paths for overriding.
+func Register(name, text, data string) {
+	lock.Lock()
+	defer lock.Unlock()
+	if _, exists := templates[name]; exists {
+		panic(fmt.Sprintf("template %s registered twice", name))
+	}
+	templates[name] = Template{
+		Name: name,
+		Text: text,
+		Data: data,
+	}
+}
+
+// SetDir sets directory where overriding templates are looked up
+func SetDir(d string) {
+	lock.Lock()
+	defer lock.Unlock()
+	dir = d
+}
+
+// Dir returns directory where overriding templates are looked up
+func Dir() string {
+	lock.RLock()
+	defer lock.RUnlock()
+	return dir
+}
+
+// Lookup returns content of file with the same name as template name in template directory if it exists,
+// otherwise default text of the template. It panics if name is not registered.
+func Lookup(name string) string {
+	lock.RLock()
+	t, exists := templates[name]
+	d := dir
+	lock.RUnlock()
+	if !exists {
+		panic(fmt.Sprintf("template %s not registered", name))
+	}
+	file := filepath.Join(d, filepath.FromSlash(name))
+	data, err := fileutils.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			panic(err)
+		}
+		return t.Text
+	}
+	logrus.Infof("template %s is overridden by %s", name, file)
+	return string(data)
+}
+
+// Templates returns all registered templates sorted by name
+func Templates() []Template {
+	lock.RLock()
+	defer lock.RUnlock()
+	var ret []Template
+	for _, t := range templates {
+		ret = append(ret, t)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+const exportReadme = `# Templates
+
+Templates in this directory override default templates of go-doudou code generators with the same relative path.
+They are parsed by text/template package except doc/doc.html.tmpl parsed by html/template package.
+Delete templates you don't want to change so that improvements of default templates can be picked up after upgrade.
+
+Data of many templates has field Meta of type astutils.InterfaceMeta built from svc.go:
+
+- astutils.InterfaceMeta: Name string, Methods []MethodMeta, Comments []string
+- astutils.MethodMeta: Name string, Params []FieldMeta, Results []FieldMeta, Comments []string. Methods built from
+  openapi 3.0 spec also have Path, PathVars, HeaderVars, QueryParams, BodyParams, BodyJSON and Files
+- astutils.FieldMeta: Name string, Type string, Tag string, Comments []string, IsExport bool, DocName string
+`
+
+// Export writes default text of all registered templates into dir with README.md describing their data.
+// Existing files are skipped so that overridden templates are kept.
+func Export(d string) error {
+	var readme bytes.Buffer
+	readme.WriteString(exportReadme)
+	for _, t := range Templates() {
+		readme.WriteString(fmt.Sprintf("\n## %s\n\n", t.Name))
+		for _, line := range strings.Split(t.Data, "\n") {
+			readme.WriteString(fmt.Sprintf("- %s\n", line))
+		}
+		file := filepath.Join(d, filepath.FromSlash(t.Name))
+		if _, err := fileutils.Stat(file); err == nil {
+			logrus.Warnf("file %s already exists", file)
+			continue
+		}
+		if err := fileutils.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+			return err
+		}
+		if err := fileutils.WriteFile(file, []byte(t.Text), os.ModePerm); err != nil {
+			return err
+		}
+	}
+	return fileutils.WriteFile(filepath.Join(d, "README.md"), readme.Bytes(), os.ModePerm)
+}
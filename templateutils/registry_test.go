@@ -0,0 +1,57 @@
+package templateutils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/unionj-cloud/go-doudou/pathutils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func init() {
+	Register("test/hello.tmpl", "hello {{.Name}}", "Name string: name to greet")
+}
+
+func TestLookup(t *testing.T) {
+	dir := pathutils.Abs("testlookup")
+	defer os.RemoveAll(dir)
+	SetDir(dir)
+	defer SetDir(DefaultDir)
+	assert.Equal(t, "hello {{.Name}}", Lookup("test/hello.tmpl"))
+
+	file := filepath.Join(dir, "test", "hello.tmpl")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(file), os.ModePerm))
+	assert.NoError(t, ioutil.WriteFile(file, []byte("hi {{.Name}}"), os.ModePerm))
+	assert.Equal(t, "hi {{.Name}}", Lookup("test/hello.tmpl"))
+}
+
+func TestLookupNotRegistered(t *testing.T) {
+	assert.Panics(t, func() {
+		Lookup("test/notregistered.tmpl")
+	})
+}
+
+func TestRegisterTwice(t *testing.T) {
+	assert.Panics(t, func() {
+		Register("test/hello.tmpl", "", "")
+	})
+}
+
+func TestExport(t *testing.T) {
+	dir := pathutils.Abs("testexport")
+	defer os.RemoveAll(dir)
+	assert.NoError(t, Export(dir))
+	data, err := ioutil.ReadFile(filepath.Join(dir, "test", "hello.tmpl"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello {{.Name}}", string(data))
+	readme, err := ioutil.ReadFile(filepath.Join(dir, "README.md"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(readme), "## test/hello.tmpl\n\n- Name string: name to greet\n")
+
+	file := filepath.Join(dir, "test", "hello.tmpl")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("hi {{.Name}}"), os.ModePerm))
+	assert.NoError(t, Export(dir))
+	data, _ = ioutil.ReadFile(file)
+	assert.Equal(t, "hi {{.Name}}", string(data))
+}